@@ -22,6 +22,9 @@ import (
 	"os"
 
 	"github.com/etcd-io/auger/pkg/client"
+	"github.com/etcd-io/auger/pkg/crd"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -32,6 +35,11 @@ type getFlagpole struct {
 	ChunkSize int64
 	Prefix    string
 	Limit     int64
+
+	EncryptionConfig string
+	CRDSchemas       bool
+	Redact           bool
+	RedactPaths      []string
 }
 
 var getExample = `
@@ -55,6 +63,15 @@ var getExample = `
   # Nearly equivalent
   kubectl get apiservices.apiregistration.k8s.io v1.apps -o yaml
 
+  # List all secrets encrypted at rest, decrypting them with the kube-apiserver encryption config
+  augerctl get secrets --encryption-config /etc/kubernetes/encryption-config.yaml
+
+  # List all secrets with their data masked, e.g. to share them in a bug report
+  augerctl get secrets --redact
+
+  # List all crontabs, warning about those that do not match the schema of their CustomResourceDefinition
+  augerctl get crontabs.stable.example.com --crd-schemas
+
   # List all resources
   augerctl get
   # Nearly equivalent
@@ -82,11 +99,15 @@ func newCtlGetCommand(f *flagpole) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&flags.Output, "output", "o", "yaml", "output format. One of: (yaml, json).")
+	cmd.Flags().StringVarP(&flags.Output, "output", "o", "yaml", "output format. One of: (yaml, json, cbor).")
 	cmd.Flags().StringVarP(&flags.Namespace, "namespace", "n", "", "namespace of resource")
 	cmd.Flags().Int64Var(&flags.ChunkSize, "chunk-size", 500, "chunk size of the list pager")
 	cmd.Flags().StringVar(&flags.Prefix, "prefix", "/registry", "prefix to prepend to the resource")
 	cmd.Flags().Int64Var(&flags.Limit, "limit", 0, "max total number of results returned (0 means no limit)")
+	cmd.Flags().StringVar(&flags.EncryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+	cmd.Flags().BoolVar(&flags.Redact, "redact", false, "mask the data of Secrets and kubeconfigs of ConfigMaps, keeping their keys and sizes")
+	cmd.Flags().StringArrayVar(&flags.RedactPaths, "redact-path", nil, "also mask the values at this dot separated path of all objects, * matches any field or item, implies --redact")
+	cmd.Flags().BoolVar(&flags.CRDSchemas, "crd-schemas", false, "check custom resources against the schemas of the CustomResourceDefinitions in etcd, problems are written to stderr")
 
 	return cmd
 }
@@ -111,17 +132,68 @@ func getCommand(ctx context.Context, etcdclient client.Client, flags *getFlagpol
 		}
 	}
 
-	printer := NewPrinter(os.Stdout, flags.Output)
+	var redactor *redact.Redactor
+	if flags.Redact || len(flags.RedactPaths) > 0 {
+		if flags.Output != "yaml" && flags.Output != "json" {
+			return fmt.Errorf("--redact only supports yaml and json output")
+		}
+		var err error
+		redactor, err = redact.New(flags.RedactPaths)
+		if err != nil {
+			return err
+		}
+	}
+
+	printer := NewPrinter(os.Stdout, flags.Output, redactor)
 	if printer == nil {
 		return fmt.Errorf("invalid output format: %q", flags.Output)
 	}
 
+	var config *encryption.Config
+	if flags.EncryptionConfig != "" {
+		var err error
+		config, err = encryption.LoadConfig(flags.EncryptionConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	response := printer.Print
+	if flags.CRDSchemas {
+		crds, err := crd.LoadFromClient(ctx, etcdclient, flags.Prefix, config)
+		if err != nil {
+			return fmt.Errorf("failed to load CustomResourceDefinitions: %w", err)
+		}
+		next := response
+		response = func(kv *client.KeyValue) error {
+			result, err := crds.Check(kv.Value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warn: %s: unable to check against the CustomResourceDefinitions: %v\n", kv.Key, err)
+			} else if result != nil {
+				for _, warning := range result.Warnings() {
+					fmt.Fprintf(os.Stderr, "warn: %s: %s\n", kv.Key, warning)
+				}
+			}
+			return next(kv)
+		}
+	}
+	if config != nil {
+		next := response
+		response = func(kv *client.KeyValue) error {
+			value, err := config.Decrypt(string(kv.Key), kv.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", kv.Key, err)
+			}
+			return next(&client.KeyValue{Key: kv.Key, Value: value})
+		}
+	}
+
 	opOpts := []client.OpOption{
 		client.WithName(targetName, targetNamespace),
 		client.WithGroupResource(targetGr),
 		client.WithChunkSize(flags.ChunkSize),
 		client.WithLimit(flags.Limit),
-		client.WithResponse(printer.Print),
+		client.WithResponse(response),
 	}
 
 	// TODO: Support watch
@@ -20,18 +20,24 @@ import (
 	"io"
 
 	"github.com/etcd-io/auger/pkg/client"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/redact"
+	"github.com/etcd-io/auger/pkg/scheme"
 )
 
 type Printer interface {
 	Print(kv *client.KeyValue) error
 }
 
-func NewPrinter(w io.Writer, printerType string) Printer {
+func NewPrinter(w io.Writer, printerType string, redactor *redact.Redactor) Printer {
+	decoder := encoding.NewDecoder(scheme.Codecs)
 	switch printerType {
 	case "yaml":
-		return &yamlPrinter{w: w}
+		return &yamlPrinter{w: w, decoder: decoder, redactor: redactor}
 	case "json":
-		return &jsonPrinter{w: w}
+		return &jsonPrinter{w: w, decoder: decoder, redactor: redactor}
+	case "cbor":
+		return &cborPrinter{w: w, decoder: decoder}
 	}
 	return nil
 }
@@ -0,0 +1,43 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io"
+
+	"github.com/etcd-io/auger/pkg/client"
+	"github.com/etcd-io/auger/pkg/encoding"
+)
+
+// cborPrinter writes each value as a self-described CBOR data item, the output is a CBOR sequence.
+type cborPrinter struct {
+	w       io.Writer
+	decoder *encoding.Decoder
+}
+
+func (p *cborPrinter) Print(kv *client.KeyValue) error {
+	inMediaType, value, err := encoding.DetectAndExtract(kv.Value)
+	if err != nil {
+		return err
+	}
+	data, _, err := p.decoder.Convert(inMediaType, encoding.CborMediaType, value)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	return err
+}
@@ -17,15 +17,18 @@ limitations under the License.
 package command
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/etcd-io/auger/pkg/client"
 	"github.com/etcd-io/auger/pkg/encoding"
-	"github.com/etcd-io/auger/pkg/scheme"
+	"github.com/etcd-io/auger/pkg/redact"
 )
 
 type jsonPrinter struct {
-	w io.Writer
+	w        io.Writer
+	decoder  *encoding.Decoder
+	redactor *redact.Redactor
 }
 
 func (p *jsonPrinter) Print(kv *client.KeyValue) error {
@@ -34,10 +37,14 @@ func (p *jsonPrinter) Print(kv *client.KeyValue) error {
 	if err != nil {
 		return err
 	}
-	data, _, err := encoding.Convert(scheme.Codecs, inMediaType, encoding.JsonMediaType, value)
+	data, _, err := p.decoder.Convert(inMediaType, encoding.JsonMediaType, value)
 	if err != nil {
 		return err
 	}
+	data, err = p.redactor.Redact(encoding.JsonMediaType, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", kv.Key, err)
+	}
 	_, err = p.w.Write(data)
 	if err != nil {
 		return err
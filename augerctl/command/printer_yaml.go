@@ -23,30 +23,27 @@ import (
 
 	"github.com/etcd-io/auger/pkg/client"
 	"github.com/etcd-io/auger/pkg/encoding"
-	"github.com/etcd-io/auger/pkg/scheme"
+	"github.com/etcd-io/auger/pkg/redact"
 )
 
 type yamlPrinter struct {
-	w io.Writer
+	w        io.Writer
+	decoder  *encoding.Decoder
+	redactor *redact.Redactor
 }
 
 func (p *yamlPrinter) Print(kv *client.KeyValue) error {
 	value := kv.Value
 	inMediaType, _, err := encoding.DetectAndExtract(value)
 	if err != nil {
-		_, err0 := fmt.Fprintf(p.w, "---\n# %s | raw | %v\n# %s\n", kv.Key, err, value)
-		if err0 != nil {
-			return errors.Join(err, err0)
-		}
-		return nil
+		return p.printRaw(kv, err)
+	}
+	data, _, err := p.decoder.Convert(inMediaType, encoding.YamlMediaType, value)
+	if err == nil {
+		data, err = p.redactor.Redact(encoding.YamlMediaType, data)
 	}
-	data, _, err := encoding.Convert(scheme.Codecs, inMediaType, encoding.YamlMediaType, value)
 	if err != nil {
-		_, err0 := fmt.Fprintf(p.w, "---\n# %s | raw | %v\n# %s\n", kv.Key, err, value)
-		if err0 != nil {
-			return errors.Join(err, err0)
-		}
-		return nil
+		return p.printRaw(kv, err)
 	}
 	_, err = fmt.Fprintf(p.w, "---\n# %s | %s\n%s\n", kv.Key, inMediaType, data)
 	if err != nil {
@@ -54,3 +51,17 @@ func (p *yamlPrinter) Print(kv *client.KeyValue) error {
 	}
 	return nil
 }
+
+// printRaw prints a value that can't be decoded as a comment, unless values are redacted.
+func (p *yamlPrinter) printRaw(kv *client.KeyValue, err error) error {
+	var err0 error
+	if p.redactor != nil {
+		_, err0 = fmt.Fprintf(p.w, "---\n# %s | raw | %v\n", kv.Key, err)
+	} else {
+		_, err0 = fmt.Fprintf(p.w, "---\n# %s | raw | %v\n# %s\n", kv.Key, err, kv.Value)
+	}
+	if err0 != nil {
+		return errors.Join(err, err0)
+	}
+	return nil
+}
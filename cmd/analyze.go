@@ -18,6 +18,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"runtime"
 	"sort"
 
 	"github.com/etcd-io/auger/pkg/data"
@@ -34,7 +37,8 @@ var analyzeCmd = &cobra.Command{
 }
 
 type analyzeOptions struct {
-	filename string
+	filename    string
+	parallelism int
 }
 
 var analyzeOpts = &analyzeOptions{}
@@ -42,19 +46,47 @@ var analyzeOpts = &analyzeOptions{}
 func init() {
 	RootCmd.AddCommand(analyzeCmd)
 	analyzeCmd.Flags().StringVarP(&analyzeOpts.filename, "file", "f", "", "Bolt DB '.db' filename")
+	analyzeCmd.Flags().IntVar(&analyzeOpts.parallelism, "parallelism", runtime.NumCPU(), "Number of workers decoding values")
 }
 
+// analyzeTopCount is the number of most common types and of largest objects that are written.
+const analyzeTopCount = 12
+
 func analyzeValidateAndRun() error {
-	summaries, err := data.ListKeySummaries(scheme.Codecs, analyzeOpts.filename, []data.Filter{}, &data.KeySummaryProjection{HasKey: true, HasValue: false}, 0)
+	return analyze(analyzeOpts.filename, analyzeOpts.parallelism, os.Stdout)
+}
+
+// analyze writes the storage used by the kubernetes objects of the db file, their most common types and
+// the largest objects. Only the statistics of one key at a time are held in memory, besides the largest
+// objects. Values are decoded by parallelism workers.
+func analyze(filename string, parallelism int, out io.Writer) error {
+	store, err := openStore(filename, parallelism)
 	if err != nil {
 		return err
 	}
+	defer store.Close()
 
+	var objectCount int
+	var totalKeySize int
+	var totalValueSize int
+	var totalAllVersionsKeySize int
+	var totalAllVersionsValueSize int
 	objectCounts := map[string]uint{}
-	for _, s := range summaries {
+	var largest []*data.KeySummary
+	err = store.WalkKeySummaries(scheme.Codecs, []data.Filter{}, &data.KeySummaryProjection{HasKey: true, HasValue: false}, 0, nil, func(s *data.KeySummary) error {
+		objectCount++
+		totalKeySize += s.Stats.KeySize
+		totalValueSize += s.Stats.ValueSize
+		totalAllVersionsKeySize += s.Stats.AllVersionsKeySize
+		totalAllVersionsValueSize += s.Stats.AllVersionsValueSize
 		if s.TypeMeta != nil {
 			objectCounts[fmt.Sprintf("%s/%s", s.TypeMeta.APIVersion, s.TypeMeta.Kind)]++
 		}
+		largest = insertLargest(largest, s)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	type entry struct {
@@ -68,43 +100,51 @@ func analyzeValidateAndRun() error {
 	}
 
 	sort.Slice(entries[:], func(i, j int) bool {
-		return entries[i].count > entries[j].count
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].gvk < entries[j].gvk
 	})
 
-	var totalKeySize int
-	var totalValueSize int
-	var totalAllVersionsKeySize int
-	var totalAllVersionsValueSize int
-	for _, summary := range summaries {
-		totalKeySize += summary.Stats.KeySize
-		totalValueSize += summary.Stats.ValueSize
-		totalAllVersionsKeySize += summary.Stats.AllVersionsKeySize
-		totalAllVersionsValueSize += summary.Stats.AllVersionsValueSize
-	}
-
-	fmt.Printf("Total kubernetes objects: %d\n", len(summaries))
-	fmt.Printf("Total (all revisions) storage used by kubernetes objects: %d\n", totalAllVersionsKeySize+totalAllVersionsValueSize)
-	fmt.Printf("Current (latest revision) storage used by kubernetes objects: %d\n", totalKeySize+totalValueSize)
-	fmt.Printf("\n")
-	fmt.Printf("Most common kubernetes types:\n")
+	fmt.Fprintf(out, "Total kubernetes objects: %d\n", objectCount)
+	fmt.Fprintf(out, "Total (all revisions) storage used by kubernetes objects: %d\n", totalAllVersionsKeySize+totalAllVersionsValueSize)
+	fmt.Fprintf(out, "Current (latest revision) storage used by kubernetes objects: %d\n", totalKeySize+totalValueSize)
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "Most common kubernetes types:\n")
 	for i, entry := range entries {
-		fmt.Printf("\t%d\t%s\n", entry.count, entry.gvk)
-		if i > 10 {
+		if i == analyzeTopCount {
 			break
 		}
+		fmt.Fprintf(out, "\t%d\t%s\n", entry.count, entry.gvk)
 	}
 
-	sort.Slice(summaries[:], func(i, j int) bool {
-		return summaries[i].Stats.AllVersionsValueSize > summaries[j].Stats.AllVersionsValueSize
-	})
-	fmt.Printf("\n")
-	fmt.Printf("Largest objects (byte size sum of all revisions):\n")
-	for i, summary := range summaries {
-		fmt.Printf("\t%d\t%s (%s/%s)\n", summary.Stats.AllVersionsValueSize, summary.Key, summary.TypeMeta.APIVersion, summary.TypeMeta.Kind)
-		if i > 10 {
-			break
+	fmt.Fprintf(out, "\n")
+	fmt.Fprintf(out, "Largest objects (byte size sum of all revisions):\n")
+	for _, summary := range largest {
+		if summary.TypeMeta == nil {
+			fmt.Fprintf(out, "\t%d\t%s\n", summary.Stats.AllVersionsValueSize, summary.Key)
+			continue
 		}
+		fmt.Fprintf(out, "\t%d\t%s (%s/%s)\n", summary.Stats.AllVersionsValueSize, summary.Key, summary.TypeMeta.APIVersion, summary.TypeMeta.Kind)
 	}
 
 	return nil
 }
+
+// insertLargest inserts s into the summaries sorted by the byte size sum of all revisions, largest first,
+// keeping at most analyzeTopCount of them. Summaries of the same size are kept in the order they are inserted.
+func insertLargest(largest []*data.KeySummary, s *data.KeySummary) []*data.KeySummary {
+	i := sort.Search(len(largest), func(i int) bool {
+		return largest[i].Stats.AllVersionsValueSize < s.Stats.AllVersionsValueSize
+	})
+	if i == analyzeTopCount {
+		return largest
+	}
+	largest = append(largest, nil)
+	copy(largest[i+1:], largest[i:])
+	largest[i] = s
+	if len(largest) > analyzeTopCount {
+		largest = largest[:analyzeTopCount]
+	}
+	return largest
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/etcd-io/auger/pkg/anonymize"
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	anonymizeLong = `
+Writes a copy of a boltdb '.db' file in which the kubernetes objects are
+anonymized, so that it can be shared, e.g. to report a bug.
+
+Names and namespaces, in etcd keys and in objects, the values of labels
+and annotations, images, messages, and the data of Secrets and ConfigMaps
+are replaced with pseudonyms. Each value is always replaced with the same
+pseudonym, so objects still refer to each other, e.g. by owner references,
+volumes or label selectors. The namespaces and names kubernetes creates
+itself, such as kube-system, are kept. Payloads keep their size.
+
+Revisions, deletions, versions and leases are kept as is, as are all
+other buckets of the file, so that problems of etcd and kube-apiserver
+can still be reproduced with the copy.
+
+Pseudonyms are derived from --seed, which is random by default. Pass the
+same seed to anonymize the files of several members consistently.
+
+Values encrypted at rest are decrypted with --encryption-config and
+written unencrypted. Values that can't be decrypted or decoded, e.g. of
+kinds that are not known to this tool or encrypted without
+--encryption-config, can't be anonymized: the first one fails the
+command and no copy is written. With --keep-undecodable, they are
+instead kept as is with a warning for each revision, and the namespaces
+and names in their keys are still replaced. Review such copies before
+sharing them.`
+
+	anonymizeExample = `
+        # Anonymize a boltdb file:
+        auger anonymize -f <boltdb-file> -o anonymized.db
+
+        # Anonymize the files of two members consistently:
+        auger anonymize -f member-1/snap/db -o member-1.db --seed <seed>
+        auger anonymize -f member-2/snap/db -o member-2.db --seed <seed>
+`
+)
+
+var anonymizeCmd = &cobra.Command{
+	Use:     "anonymize",
+	Short:   "Writes a copy of a boltdb '.db' file with the kubernetes objects anonymized.",
+	Long:    anonymizeLong,
+	Example: anonymizeExample,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return anonymizeValidateAndRun()
+	},
+}
+
+type anonymizeOptions struct {
+	filename         string
+	out              string
+	seed             string
+	encryptionConfig string
+	keepUndecodable  bool
+}
+
+var anonymizeOpts = &anonymizeOptions{}
+
+func init() {
+	RootCmd.AddCommand(anonymizeCmd)
+	anonymizeCmd.Flags().StringVarP(&anonymizeOpts.filename, "file", "f", "", "Bolt DB '.db' filename")
+	anonymizeCmd.Flags().StringVarP(&anonymizeOpts.out, "output", "o", "", "Bolt DB '.db' filename to write the anonymized copy to, must not exist")
+	anonymizeCmd.Flags().StringVar(&anonymizeOpts.seed, "seed", "", "Seed of the pseudonyms, random if not set")
+	anonymizeCmd.Flags().StringVar(&anonymizeOpts.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+	anonymizeCmd.Flags().BoolVar(&anonymizeOpts.keepUndecodable, "keep-undecodable", false, "Keep values that can't be decrypted or decoded as is, instead of failing")
+}
+
+func anonymizeValidateAndRun() error {
+	if anonymizeOpts.filename == "" || anonymizeOpts.out == "" {
+		return errors.New("--file and --output are required")
+	}
+	config, err := loadEncryptionConfig(anonymizeOpts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	seed := []byte(anonymizeOpts.seed)
+	if len(seed) == 0 {
+		seed = make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			return err
+		}
+	}
+	return anonymizeDB(anonymizeOpts.filename, anonymizeOpts.out, seed, config, anonymizeOpts.keepUndecodable, os.Stdout, os.Stderr)
+}
+
+// compactRevKey is the key etcd writes the revision of the last scheduled compaction to, among the keys of
+// kubernetes objects. Its value is kept as is.
+const compactRevKey = "compact_rev_key"
+
+// anonymizeDB writes an anonymized copy of the boltdb file in to out. The names and namespaces of all
+// objects are collected first, so that references to them are replaced in all objects. Values that can't
+// be anonymized fail the copy, unless keepUndecodable is set: they are then kept, with a warning written
+// to errOut for each.
+func anonymizeDB(in, out string, seed []byte, config *encryption.Config, keepUndecodable bool, stdout, errOut io.Writer) error {
+	a := anonymize.New(seed)
+	err := data.WalkKeyValues(in, func(kv *mvccpb.KeyValue) error {
+		if obj, _, err := decodeAnonymizeValue(config, kv); err == nil {
+			a.Collect(string(kv.Key), obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var revisions, anonymized, kept int
+	err = data.RewriteKeyValues(in, out, func(kv *mvccpb.KeyValue) (*mvccpb.KeyValue, error) {
+		revisions++
+		rewritten := *kv
+		rewritten.Key = []byte(a.Key(string(kv.Key)))
+		if len(kv.Value) == 0 || string(kv.Key) == compactRevKey {
+			return &rewritten, nil
+		}
+		value, err := anonymizeValue(a, config, kv)
+		if err != nil {
+			if !keepUndecodable {
+				return nil, fmt.Errorf("revision %d can't be anonymized, pass --keep-undecodable to keep it as is: %w", kv.ModRevision, err)
+			}
+			fmt.Fprintf(errOut, "warn: %s at revision %d: %v, the value is kept as is\n", kv.Key, kv.ModRevision, err)
+			kept++
+			return &rewritten, nil
+		}
+		rewritten.Value = value
+		anonymized++
+		return &rewritten, nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s: %d revisions, %d objects anonymized, %d values kept as is\n", out, revisions, anonymized, kept)
+	return nil
+}
+
+// anonymizeValue anonymizes the object of a key-value and encodes it to the media type it was stored as,
+// or to JSON if its kind can't be encoded to that media type.
+func anonymizeValue(a *anonymize.Anonymizer, config *encryption.Config, kv *mvccpb.KeyValue) ([]byte, error) {
+	obj, inMediaType, err := decodeAnonymizeValue(config, kv)
+	if err != nil {
+		return nil, err
+	}
+	a.Object(obj)
+	value, err := encoder.Encode(&unstructured.Unstructured{Object: obj}, inMediaType)
+	if err != nil {
+		// e.g. kinds that were removed from kubernetes, decoded with the embedded protobuf descriptors.
+		return json.Marshal(obj)
+	}
+	return value, nil
+}
+
+// decodeAnonymizeValue decrypts and decodes the value of a key-value to an object, and returns the media
+// type it is stored as.
+func decodeAnonymizeValue(config *encryption.Config, kv *mvccpb.KeyValue) (map[string]interface{}, string, error) {
+	value, err := config.Decrypt(string(kv.Key), kv.Value)
+	if err != nil {
+		return nil, "", err
+	}
+	inMediaType, in, err := encoding.DetectAndExtract(value)
+	if err != nil {
+		return nil, "", err
+	}
+	js, _, err := decoder.Convert(inMediaType, encoding.JsonMediaType, in)
+	if err != nil {
+		return nil, "", err
+	}
+	// Numbers are kept as is, rather than rounded to float64.
+	obj := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, "", err
+	}
+	return obj, inMediaType, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAnonymize(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "anonymized.db")
+	stdout, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	if err := anonymizeDB(dbFile, out, []byte("seed"), nil, false, stdout, errOut); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("wrote %s: 4 revisions, 3 objects anonymized, 0 values kept as is\n", out); stdout.String() != want || errOut.Len() != 0 {
+		t.Errorf("got %q and warnings %q, want %q", stdout, errOut, want)
+	}
+
+	revisions := func(filename string) []string {
+		var got []string
+		err := data.WalkKeyValues(filename, func(kv *mvccpb.KeyValue) error {
+			got = append(got, fmt.Sprintf("%d %d %d %d", kv.CreateRevision, kv.ModRevision, kv.Version, kv.Lease))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got, want := revisions(out), revisions(dbFile); !reflect.DeepEqual(got, want) {
+		t.Errorf("got revisions %q, want %q", got, want)
+	}
+
+	kvs, err := data.ListValues(out, "/registry/pods/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 {
+		t.Fatalf("got %d pods, want 1", len(kvs))
+	}
+	pod := new(bytes.Buffer)
+	if err := decryptAndRun("", encoding.JsonMediaType, schema.GroupVersion{}, nil, nil, "", kvs[0].Value, pod); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pod.Bytes(), []byte(`"image":"registry.invalid/anon-`)) {
+		t.Errorf("got pod %s, want an anonymized image", pod)
+	}
+	written, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pi-dqtsw", "kubernetes-minion-group-vlql"} {
+		if bytes.Contains(written, []byte(name)) {
+			t.Errorf("anonymized file still contains %q", name)
+		}
+	}
+
+	// The same seed gives the same pseudonyms.
+	again := filepath.Join(dir, "again.db")
+	if err := anonymizeDB(dbFile, again, []byte("seed"), nil, false, new(bytes.Buffer), new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	againKvs, err := data.ListValues(again, "/registry/pods/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(againKvs[0].Key, kvs[0].Key) || !bytes.Equal(againKvs[0].Value, kvs[0].Value) {
+		t.Errorf("got %s, want %s for the same seed", againKvs[0].Key, kvs[0].Key)
+	}
+}
+
+func TestAnonymizeEncrypted(t *testing.T) {
+	config, err := loadEncryptionConfig("testdata/encryption/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "/registry/pods/prod/db-0"
+	encrypted := new(bytes.Buffer)
+	pod := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"db-0","namespace":"prod"}}`)
+	if err := encodeAndEncrypt(encoding.JsonMediaType, encoding.StorageBinaryMediaType, config, key, pod, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	in := createTestDB(t, []testKeyValue{{key: key, value: encrypted.Bytes()}})
+
+	// Without the encryption config, the value can't be anonymized and no copy is written.
+	out := filepath.Join(t.TempDir(), "anonymized.db")
+	stdout, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	err = anonymizeDB(in, out, []byte("seed"), nil, false, stdout, errOut)
+	if want := "error rewriting key " + key + ": revision 1 can't be anonymized, pass --keep-undecodable to keep it as is: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("got %v, want no copy written", err)
+	}
+
+	// With --keep-undecodable, the value is kept with a warning, but its key is still anonymized.
+	if err := anonymizeDB(in, out, []byte("seed"), nil, true, stdout, errOut); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("wrote %s: 1 revisions, 0 objects anonymized, 1 values kept as is\n", out); stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+	if want := "warn: " + key + " at revision 1: "; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("got warnings %q, want %q", errOut, want)
+	}
+	kvs, err := data.ListValues(out, "/registry/pods/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || !bytes.Equal(kvs[0].Value, encrypted.Bytes()) {
+		t.Fatalf("got %v, want the encrypted value kept as is", kvs)
+	}
+	for _, name := range []string{"prod", "db-0"} {
+		if strings.Contains(string(kvs[0].Key), name) {
+			t.Errorf("got key %s, want %q replaced", kvs[0].Key, name)
+		}
+	}
+
+	// With it, the value is anonymized, and its key is replaced the same way.
+	decrypted := filepath.Join(t.TempDir(), "decrypted.db")
+	stdout.Reset()
+	if err := anonymizeDB(in, decrypted, []byte("seed"), config, false, stdout, errOut); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("wrote %s: 1 revisions, 1 objects anonymized, 0 values kept as is\n", decrypted); stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+	decryptedKvs, err := data.ListValues(decrypted, "/registry/pods/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decryptedKvs) != 1 || !bytes.Equal(decryptedKvs[0].Key, kvs[0].Key) {
+		t.Errorf("got %v, want key %s", decryptedKvs, kvs[0].Key)
+	}
+}
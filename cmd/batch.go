@@ -0,0 +1,256 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// batchFormatText is the format of etcd-dump-logs: hex lines in, 'OK|<data>' or 'ERROR:<error>|' lines out.
+	batchFormatText = "text"
+	// batchFormatJSONL is the JSON Lines format: hex or batchInput lines in, batchResult lines out.
+	batchFormatJSONL = "jsonl"
+)
+
+// batchInput is a line of JSON Lines batch input, the value may be preceded by its etcd key, which is
+// used to decrypt it.
+type batchInput struct {
+	Key   string `json:"key,omitempty"`
+	Value []byte `json:"value"`
+}
+
+// batchResult is a line of JSON Lines batch output.
+type batchResult struct {
+	Line      int               `json:"line"`
+	Key       string            `json:"key,omitempty"`
+	Status    string            `json:"status"`
+	MediaType string            `json:"mediaType,omitempty"`
+	TypeMeta  *runtime.TypeMeta `json:"typeMeta,omitempty"`
+	Summary   *encoding.Summary `json:"summary,omitempty"`
+	Object    json.RawMessage   `json:"object,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// batchLine is a line of batch input, decoded by a worker to its output.
+type batchLine struct {
+	num    int
+	input  []byte
+	output chan batchOutput
+}
+
+type batchOutput struct {
+	buf []byte
+	err error
+}
+
+// runInBatchMode runs when batchProcess is set to true.
+// Original requirement is from etcd WAL log analysis tool etcd-dump-logs,
+// (etcd-dump-logs: add decoder support #9790 https://github.com/coreos/etcd/pull/9790)
+// to serve as a decoder to decode k8s objects from each entry of the etcd WAL log.
+// Read data from in, decode object line by line, and print out to out.
+//
+// In etcd-dump-logs, when batchProcess mode is on, two columns are listed for decoder:
+// "decoder_status" and "decoded_data". In this case, Auger has two possible output:
+// 1. "OK" for decoder_status, actual decoded data for decoded_data
+// 2. "ERROR:Auger error" for decoder_status, decoded_data column would be empty in this case.
+//
+// With the JSON Lines format, each line is written as a batchResult instead. Lines are decoded by
+// parallelism workers, and written in the order they are read.
+func runInBatchMode(metaOnly bool, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, format string, parallelism int, in io.Reader, out io.Writer) error {
+	var decodeLine func(num int, input []byte) ([]byte, error)
+	switch format {
+	case batchFormatText:
+		decodeLine = func(num int, input []byte) ([]byte, error) {
+			return decodeTextBatchLine(metaOnly, outMediaType, outVersion, config, redactor, num, input)
+		}
+	case batchFormatJSONL:
+		decodeLine = func(num int, input []byte) ([]byte, error) {
+			return decodeJSONLBatchLine(metaOnly, outVersion, config, redactor, num, input)
+		}
+	default:
+		return fmt.Errorf("unrecognized 'batch-format' flag value: %v", format)
+	}
+	if parallelism < 1 {
+		return fmt.Errorf("--parallelism must be at least 1, got %d", parallelism)
+	}
+
+	lines := make(chan *batchLine, parallelism)
+	ordered := make(chan *batchLine, 4*parallelism)
+	done := make(chan struct{})
+	defer close(done)
+
+	var readErr error
+	go func() {
+		defer close(ordered)
+		defer close(lines)
+		readErr = readBatchLines(in, func(line *batchLine) bool {
+			select {
+			case ordered <- line:
+			case <-done:
+				return false
+			}
+			select {
+			case lines <- line:
+			case <-done:
+				return false
+			}
+			return true
+		})
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for line := range lines {
+				buf, err := decodeLine(line.num, line.input)
+				line.output <- batchOutput{buf: buf, err: err}
+			}
+		}()
+	}
+
+	for line := range ordered {
+		output := <-line.output
+		if output.err != nil {
+			return output.err
+		}
+		if _, err := out.Write(output.buf); err != nil {
+			return err
+		}
+	}
+	workers.Wait()
+	return readErr
+}
+
+// readBatchLines reads the lines of batch input and calls f with each, numbered from 1, until f returns
+// false.
+func readBatchLines(in io.Reader, f func(line *batchLine) bool) error {
+	inputReader := bufio.NewReader(in)
+	for lineNum := 1; ; lineNum++ {
+		input, err := inputReader.ReadBytes(byte('\n'))
+		if len(input) > 0 {
+			line := &batchLine{num: lineNum, input: stripNewline(input), output: make(chan batchOutput, 1)}
+			if !f(line) {
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading --batch-process input: %w", err)
+		}
+	}
+}
+
+// decodeTextBatchLine decodes a hex line of batch input to an 'OK|<data>' or 'ERROR:<error>|' line.
+func decodeTextBatchLine(metaOnly bool, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, lineNum int, input []byte) ([]byte, error) {
+	decodedinput, err := hex.DecodeString(string(input))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding input on line %d of --batch-process input: %w", lineNum, err)
+	}
+	decodedinput, err = config.Decrypt("", decodedinput)
+	if err != nil {
+		return []byte(fmt.Sprintf("ERROR:%v|\n", err)), nil
+	}
+	inMediaType, decodedinput, err := encoding.DetectAndExtract(decodedinput)
+	if err != nil {
+		return []byte(fmt.Sprintf("ERROR:%v|\n", err)), nil
+	}
+
+	if metaOnly {
+		buf := bytes.NewBufferString("")
+		err = encoding.DecodeSummary(inMediaType, decodedinput, buf)
+		if err != nil {
+			return []byte(fmt.Sprintf("ERROR:%v|\n", err)), nil
+		}
+		return []byte(fmt.Sprintf("OK|%s\n", buf.String())), nil
+	}
+
+	buf, err := convert(inMediaType, outMediaType, outVersion, redactor, decodedinput, os.Stderr)
+	if err != nil {
+		return []byte(fmt.Sprintf("ERROR:%v|\n", err)), nil
+	}
+	return []byte(fmt.Sprintf("OK|%s\n", string(buf))), nil
+}
+
+// decodeJSONLBatchLine decodes a line of batch input, either hex or a batchInput, to a batchResult line.
+// The object is decoded to JSON, converted to outVersion unless it is empty. If metaOnly is set, its summary
+// is written instead.
+func decodeJSONLBatchLine(metaOnly bool, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, lineNum int, input []byte) ([]byte, error) {
+	result := batchResult{Line: lineNum, Status: "OK"}
+	if err := decodeBatchResult(metaOnly, outVersion, config, redactor, input, &result); err != nil {
+		result.Status = "ERROR"
+		result.Error = err.Error()
+	}
+	buf, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+func decodeBatchResult(metaOnly bool, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, input []byte, result *batchResult) error {
+	var line batchInput
+	if bytes.HasPrefix(input, []byte("{")) {
+		if err := json.Unmarshal(input, &line); err != nil {
+			return fmt.Errorf("error parsing input: %w", err)
+		}
+	} else {
+		value, err := hex.DecodeString(string(input))
+		if err != nil {
+			return fmt.Errorf("error decoding hex input: %w", err)
+		}
+		line.Value = value
+	}
+	result.Key = line.Key
+
+	value, err := config.Decrypt(line.Key, line.Value)
+	if err != nil {
+		return err
+	}
+	inMediaType, value, err := encoding.DetectAndExtract(value)
+	if err != nil {
+		return err
+	}
+	result.MediaType = inMediaType
+	if result.TypeMeta, err = encoding.DecodeTypeMeta(inMediaType, value); err != nil {
+		return err
+	}
+	if metaOnly {
+		result.Summary, err = encoding.Summarize(inMediaType, value)
+		return err
+	}
+	result.Object, err = convertToJSON(inMediaType, outVersion, redactor, value)
+	return err
+}
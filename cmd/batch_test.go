@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRunInBatchMode(t *testing.T) {
+	config, err := loadEncryptionConfig("testdata/encryption/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := readTestFile(t, "testdata/storage/pod.bin")
+	pod = pod[:len(pod)-1]
+	encrypted := readTestFile(t, "testdata/storage/pod-aesgcm.bin")
+	encrypted = encrypted[:len(encrypted)-1]
+
+	podHex := hex.EncodeToString(pod)
+	summary := string(readTestFile(t, "testdata/meta/pod.txt"))
+	summaryJSON := strings.TrimSuffix(string(readTestFile(t, "testdata/meta/pod.json")), "\n")
+	in := strings.Join([]string{
+		podHex,
+		hex.EncodeToString([]byte("\x00\x01")),
+		fmt.Sprintf(`{"key":"/registry/pods/default/pi-dqtsw","value":%q}`, base64.StdEncoding.EncodeToString(encrypted)),
+		podHex,
+	}, "\n")
+
+	cases := []struct {
+		name   string
+		format string
+		in     string
+		want   string
+	}{
+		{
+			name:   "text",
+			format: batchFormatText,
+			in:     podHex + "\n" + hex.EncodeToString([]byte("\x00\x01")) + "\n" + podHex + "\n",
+			want: "OK|" + summary + "\n" +
+				"ERROR:error reading input, does not appear to contain valid JSON, CBOR or binary data|\n" +
+				"OK|" + summary + "\n",
+		},
+		{
+			name:   "jsonl",
+			format: batchFormatJSONL,
+			in:     in + "\nzz",
+			want: `{"line":1,"status":"OK","mediaType":"application/vnd.kubernetes.storagebinary","typeMeta":{"apiVersion":"v1","kind":"Pod"},"summary":` + summaryJSON + `}
+{"line":2,"status":"ERROR","error":"error reading input, does not appear to contain valid JSON, CBOR or binary data"}
+{"line":3,"key":"/registry/pods/default/pi-dqtsw","status":"OK","mediaType":"application/vnd.kubernetes.storagebinary","typeMeta":{"apiVersion":"v1","kind":"Pod"},"summary":` + summaryJSON + `}
+{"line":4,"status":"OK","mediaType":"application/vnd.kubernetes.storagebinary","typeMeta":{"apiVersion":"v1","kind":"Pod"},"summary":` + summaryJSON + `}
+{"line":5,"status":"ERROR","error":"error decoding hex input: encoding/hex: invalid byte: U+007A 'z'"}
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			if err := runInBatchMode(true, encoding.YamlMediaType, schema.GroupVersion{}, config, nil, tt.format, 2, strings.NewReader(tt.in), out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+
+	if err := runInBatchMode(false, encoding.YamlMediaType, schema.GroupVersion{}, config, nil, batchFormatText, 2, strings.NewReader(podHex+"\nzz\n"), new(bytes.Buffer)); err == nil || err.Error() != "error decoding input on line 2 of --batch-process input: encoding/hex: invalid byte: U+007A 'z'" {
+		t.Errorf("got error %v, want an error for line 2", err)
+	}
+}
+
+func TestRunInBatchModeOrder(t *testing.T) {
+	pod := readTestFile(t, "testdata/storage/pod.bin")
+	podHex := hex.EncodeToString(pod[:len(pod)-1])
+	var lines []string
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			lines = append(lines, "00")
+		} else {
+			lines = append(lines, podHex)
+		}
+	}
+	out := new(bytes.Buffer)
+	if err := runInBatchMode(false, encoding.JsonMediaType, schema.GroupVersion{}, nil, nil, batchFormatJSONL, 8, strings.NewReader(strings.Join(lines, "\n")), out); err != nil {
+		t.Fatal(err)
+	}
+	want := readTestFile(t, "testdata/json/pod.json")
+	results := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(results) != len(lines) {
+		t.Fatalf("got %d lines of output, want %d", len(results), len(lines))
+	}
+	for i, line := range results {
+		var result batchResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Line != i+1 {
+			t.Fatalf("got line %d at output line %d", result.Line, i+1)
+		}
+		if wantStatus := map[bool]string{true: "ERROR", false: "OK"}[i%3 == 0]; result.Status != wantStatus {
+			t.Errorf("got status %s for line %d, want %s", result.Status, result.Line, wantStatus)
+		}
+		if result.Status == "OK" && !bytes.Equal(result.Object, mustCompactJSON(t, want)) {
+			t.Errorf("got object %s for line %d, want %s", result.Object, result.Line, want)
+		}
+	}
+}
+
+func mustCompactJSON(t *testing.T, in []byte) []byte {
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, in); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
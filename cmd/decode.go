@@ -17,17 +17,21 @@ limitations under the License.
 package cmd
 
 import (
-	"bufio"
 	"bytes"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 
+	"github.com/etcd-io/auger/pkg/conversion"
 	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
 	"github.com/etcd-io/auger/pkg/scheme"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var (
@@ -35,7 +39,8 @@ var (
 Decodes kubernetes objects from the binary key-value store encoding used
 with etcd 3+.
 
-Outputs data in a variety of formats including YAML, JSON and Protobuf.
+Outputs data in a variety of formats including YAML, JSON, Protobuf
+and CBOR.
 
 YAML and JSON output conversion is performed using api-machinery
 serializers and type declarations from the version of kubernetes
@@ -45,16 +50,103 @@ version that stored the data and the version of this tool are not
 identical. If a type declaration has an incompatible change, or if a
 type has been removed entirely, conversion may fail.
 
+With --output-version, objects are converted to another version of
+their kind, e.g. extensions/v1beta1 Deployments to apps/v1. The types
+of all versions must be known to this tool. Fields are converted one
+by one, adjusted for the versions whose fields differ, e.g. the
+backends of Ingresses, the empty selectors of PodDisruptionBudgets or
+the metrics of HorizontalPodAutoscalers. Fields that don't exist in the
+output version are dropped with a warning. Converting to a version the
+kind is not served at, or between versions whose fields differ without
+a known conversion, fails.
+
 The binary format contains an "encoding prefix", an envelope (defined
 by runtime.Unknown), and a protobuf payload.  The envelope contains
 'meta' fields identifying the payload type.
 
 Protobuf output requires no conversions and returns the exact bytes
-of the protobuf payload.`
+of the protobuf payload.
+
+Proto-raw output prints the envelope, followed by the protobuf payload
+decoded without type declarations as a tree of field numbers, wire
+types and values, like 'protoc --decode_raw'. Objects whose type is
+not known to this tool, not even from the protobuf descriptors of past
+kubernetes releases, are printed this way instead of failing.
+
+Objects stored as CBOR by newer kubernetes releases start with the
+self-described CBOR tag (0xd9d9f7) and are detected automatically.
+Custom resources, whose types are not known to this tool, are
+converted between CBOR, JSON and YAML without type declarations.
+
+Values encrypted at rest by kube-apiserver, which start with a
+'k8s:enc:<provider>:v1:<keyname>:' prefix, are decrypted using the
+providers of the EncryptionConfiguration file given by
+--encryption-config. The aesgcm and kms providers authenticate the
+etcd key of the value, which must be provided with --key. The kms
+provider unwraps the data encryption key by calling the KMS plugin
+listening on the endpoint configured for it.
+
+With --meta-only, only the envelope and metadata of objects are
+decoded: the apiVersion, kind, content type and encoding, payload
+size, name, namespace, uid, resourceVersion, generation, creation and
+deletion timestamps, finalizers, owner references, and the sizes of
+the managedFields and annotations. The provider and key name of
+encrypted values, and for KMS v2 the key ID and annotations, are
+printed as well. --meta-format selects text, yaml or json output.
+
+With --delimiter or --length-prefixed, the input is a stream of values,
+separated by the delimiter or each prefixed with its length as a 4 byte
+big-endian integer. The delimiter must not occur in the values, which
+for protobuf values rules out newlines. With --with-keys, each value is
+preceded by its etcd key, which is used to decrypt it, as written by
+'auger encode'. Objects decoded to YAML are written as separate
+documents, and objects decoded to protobuf or CBOR with the framing of
+the input.
+
+With --batch-process, hex encoded values are read line by line from
+stdin, as written by etcd-dump-logs, and an 'OK|<data>' or
+'ERROR:<error>|' line is written for each. With --batch-format jsonl,
+each input line is either hex or a JSON object with the etcd "key" and
+the base64 encoded "value", and each output line is a JSON object with
+the "line" number, "key", "status", "mediaType", "typeMeta", decoded
+"object" and "error". Lines are decoded by --parallelism workers and
+written in input order.
+
+Values copied as text, e.g. from logs, bug reports or the etcd gRPC
+gateway, are decoded from hex, standard or URL base64, or a Go quoted
+string, whichever yields a stored value, and the detected encoding is
+reported on stderr. --input-encoding sets the encoding instead.
+
+With --redact, the data of Secrets, including service account and
+bootstrap tokens, and kubeconfigs held by ConfigMaps are replaced by
+their size in YAML and JSON output, keeping their keys. --redact-path
+masks the values of more fields, e.g. spec.containers.*.env.*.value,
+where * matches all fields of an object or items of a list. Objects of
+kinds unknown to auger can't be redacted, and are an error rather than
+dumped as raw protobuf.
+
+With --from etcdctl-json or --from etcdctl-protobuf, the input is the
+output of 'etcdctl get -w json' or 'etcdctl get -w protobuf', e.g. of
+all keys with a prefix. Each value is decoded and printed with its key,
+create_revision, mod_revision, version and lease, as a YAML document or
+a line of JSON. Values that can't be decoded are printed with an error
+instead.`
 
 	decodeExample = `
         ETCDCTL_API=3 etcdctl get /registry/pods/default/<pod-name> \
-        --print-value-only | auger decode`
+        --print-value-only | auger decode
+
+        # Decrypt and decode a secret encrypted at rest
+        ETCDCTL_API=3 etcdctl get /registry/secrets/default/<secret-name> \
+        --print-value-only | auger decode --encryption-config <config-file> \
+        --key /registry/secrets/default/<secret-name>
+
+        # Decode all pods of a namespace
+        ETCDCTL_API=3 etcdctl get /registry/pods/default/ --prefix -w json | \
+        auger decode --from etcdctl-json
+
+        # Decode a stream written by 'auger encode --length-prefixed'
+        auger decode --file objects.bin --length-prefixed --with-keys`
 )
 
 var decodeCmd = &cobra.Command{
@@ -68,20 +160,52 @@ var decodeCmd = &cobra.Command{
 }
 
 type decodeOptions struct {
-	out           string
-	metaOnly      bool
-	inputFilename string
-	batchProcess  bool // special flag to handle incoming etcd-dump-logs output
+	out              string
+	outVersion       string
+	metaOnly         bool
+	metaFormat       string
+	inputEncoding    string
+	inputFilename    string
+	batchProcess     bool // special flag to handle incoming etcd-dump-logs output
+	encryptionConfig string
+	key              string
+	delimiter        string
+	lengthPrefixed   bool
+	withKeys         bool
+	from             string
+	batchFormat      string
+	parallelism      int
+	redact           bool
+	redactPaths      []string
 }
 
 var options = &decodeOptions{}
 
+// decoder and encoder convert objects between media types for all commands, caching the codecs they need.
+var (
+	decoder = encoding.NewDecoder(scheme.Codecs)
+	encoder = encoding.NewEncoder(scheme.Codecs)
+)
+
 func init() {
 	RootCmd.AddCommand(decodeCmd)
-	decodeCmd.Flags().StringVarP(&options.out, "output", "o", "yaml", "Output format. One of: json|yaml|proto")
+	decodeCmd.Flags().StringVarP(&options.out, "output", "o", "yaml", "Output format. One of: json|yaml|proto|proto-raw|cbor")
+	decodeCmd.Flags().StringVar(&options.outVersion, "output-version", "", "Convert objects to this <group>/<version> of their kind, e.g. apps/v1, instead of printing the stored version")
 	decodeCmd.Flags().BoolVar(&options.metaOnly, "meta-only", false, "Output only content type and metadata fields")
+	decodeCmd.Flags().StringVar(&options.metaFormat, "meta-format", metaFormatText, "Format of --meta-only output. One of: text|yaml|json")
 	decodeCmd.Flags().StringVar(&options.inputFilename, "file", "", "Filename to read storage encoded data from")
+	decodeCmd.Flags().StringVar(&options.inputEncoding, "input-encoding", inputEncodingAuto, "Encoding of the input value. One of: auto|raw|hex|base64|base64url|quoted")
 	decodeCmd.Flags().BoolVar(&options.batchProcess, "batch-process", false, "If set, deccode batch of objects from os.Stdin")
+	decodeCmd.Flags().StringVar(&options.batchFormat, "batch-format", batchFormatText, "Format of --batch-process input and output. One of: text|jsonl")
+	decodeCmd.Flags().IntVar(&options.parallelism, "parallelism", runtime.NumCPU(), "Number of workers decoding --batch-process input, output is written in input order")
+	decodeCmd.Flags().StringVar(&options.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+	decodeCmd.Flags().StringVar(&options.key, "key", "", "Etcd key of the input data, used as authenticated data when decrypting")
+	decodeCmd.Flags().StringVar(&options.delimiter, "delimiter", "", "Decode a stream of values separated by this delimiter, e.g. '\\n'")
+	decodeCmd.Flags().BoolVar(&options.lengthPrefixed, "length-prefixed", false, "Decode a stream of values each prefixed with its length as a 4 byte big-endian integer")
+	decodeCmd.Flags().StringVar(&options.from, "from", fromValue, "Input format. One of: value|etcdctl-json|etcdctl-protobuf")
+	decodeCmd.Flags().BoolVar(&options.withKeys, "with-keys", false, "Each value of the stream is preceded by its etcd key, used as authenticated data when decrypting")
+	decodeCmd.Flags().BoolVar(&options.redact, "redact", false, "Mask the data of Secrets and kubeconfigs of ConfigMaps, keeping their keys and sizes")
+	decodeCmd.Flags().StringArrayVar(&options.redactPaths, "redact-path", nil, "Also mask the values at this dot separated path of all objects, * matches any field or item, implies --redact")
 }
 
 // Validate the command line flags and run the command.
@@ -90,9 +214,65 @@ func validateAndRun() error {
 	if err != nil {
 		return err
 	}
+	outVersion, err := parseOutputVersion(options.outVersion)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadEncryptionConfig(options.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactor(options.redact, options.redactPaths, outMediaType)
+	if err != nil {
+		return err
+	}
+
+	// metaFormat is only set with --meta-only.
+	metaFormat := ""
+	if options.metaOnly {
+		if err := validateMetaFormat(options.metaFormat); err != nil {
+			return err
+		}
+		metaFormat = options.metaFormat
+	}
+
+	if options.inputEncoding != inputEncodingAuto && (options.from != fromValue || options.batchProcess || options.delimiter != "" || options.lengthPrefixed) {
+		return errors.New("--input-encoding can't be combined with --from, --batch-process, --delimiter or --length-prefixed")
+	}
+
+	if options.from != fromValue {
+		if options.batchProcess || options.delimiter != "" || options.lengthPrefixed || options.metaOnly {
+			return fmt.Errorf("--from %s can't be combined with --batch-process, --delimiter, --length-prefixed or --meta-only", options.from)
+		}
+		in, err := readInput(options.inputFilename)
+		if len(in) == 0 {
+			return errors.New("no input data")
+		}
+		if err != nil {
+			return err
+		}
+		return decodeRangeResponse(options.from, outMediaType, outVersion, config, redactor, in, os.Stdout)
+	}
 
 	if options.batchProcess {
-		return runInBatchMode(options.metaOnly, outMediaType, os.Stdout)
+		return runInBatchMode(options.metaOnly, outMediaType, outVersion, config, redactor, options.batchFormat, options.parallelism, os.Stdin, os.Stdout)
+	}
+
+	frames, err := newFraming(options.delimiter, options.lengthPrefixed)
+	if err != nil {
+		return err
+	}
+	if options.withKeys && frames == nil {
+		return errors.New("--with-keys requires --delimiter or --length-prefixed")
+	}
+	if frames != nil {
+		in, err := openInput(options.inputFilename)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		return decodeStream(metaFormat, outMediaType, outVersion, config, redactor, options.key, options.withKeys, frames, in, os.Stdout)
 	}
 
 	in, err := readInput(options.inputFilename)
@@ -102,77 +282,85 @@ func validateAndRun() error {
 	if err != nil {
 		return err
 	}
+	in, inputEncoding, err := unwrapInput(options.inputEncoding, in)
+	if err != nil {
+		return err
+	}
+	if options.inputEncoding == inputEncodingAuto && inputEncoding != inputEncodingRaw {
+		fmt.Fprintf(os.Stderr, "detected %s input encoding\n", inputEncoding)
+	}
 
-	return run(options.metaOnly, outMediaType, in, os.Stdout)
+	return decryptAndRun(metaFormat, outMediaType, outVersion, config, redactor, options.key, in, os.Stdout)
 }
 
-// runInBatchMode runs when batchProcess is set to true.
-// Original requirement is from etcd WAL log analysis tool etcd-dump-logs,
-// (etcd-dump-logs: add decoder support #9790 https://github.com/coreos/etcd/pull/9790)
-// to serve as a decoder to decode k8s objects from each entry of the etcd WAL log.
-// Read data from os.Stdin, decode object line by line, and print out to os.Stdout.
-//
-// In etcd-dump-logs, when batchProcess mode is on, two columns are listed for decoder:
-// "decoder_status" and "decoded_data". In this case, Auger has two possible output:
-// 1. "OK" for decoder_status, actual decoded data for decoded_data
-// 2. "ERROR:Auger error" for decoder_status, decoded_data column would be empty in this case.
-func runInBatchMode(metaOnly bool, outMediaType string, out io.Writer) (err error) {
-	inputReader := bufio.NewReader(os.Stdin)
-	lineNum := 0
-	for {
-		input, err := inputReader.ReadBytes(byte('\n'))
-		if errors.Is(err, io.EOF) {
-			return nil
-		}
+// decryptAndRun decrypts input that was encrypted at rest and runs the decode command line. If
+// metaFormat is set, only the summary of the input is written in that format, including the encryption
+// envelope, e.g. the KMS key ID. The envelope is written even if no encryption config is available to
+// decrypt the input. Unless redactor is nil, the sensitive values of the object are masked.
+func decryptAndRun(metaFormat string, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, key string, in []byte, out io.Writer) error {
+	if metaFormat != "" {
+		summary, err := summarizeValue(config, key, in)
 		if err != nil {
-			return fmt.Errorf("error reading --batch-process input: %w", err)
+			return err
 		}
+		return writeSummary(metaFormat, summary, out)
+	}
+	in, err := config.Decrypt(key, in)
+	if err != nil {
+		return err
+	}
+	return run(outMediaType, outVersion, redactor, in, out)
+}
 
-		input = stripNewline(input)
-		decodedinput, err := hex.DecodeString(string(input))
-		if err != nil {
-			return fmt.Errorf("error decoding input on line %d of --batch-process input: %w", lineNum, err)
+// decodeStream decodes each value of the input stream. If withKeys is set, each value is preceded by
+// its etcd key, which replaces the given key. Objects decoded to YAML are written as separate YAML
+// documents, and objects decoded to protobuf or CBOR are written as a stream of the same framing as the
+// input.
+func decodeStream(metaFormat string, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, key string, withKeys bool, frames *framing, in io.Reader, out io.Writer) error {
+	i := 0
+	var valueKey []byte
+	err := frames.readFrames(in, func(value []byte) error {
+		if withKeys && valueKey == nil {
+			valueKey = bytes.Clone(value)
+			return nil
 		}
-		inMediaType, decodedinput, err := encoding.DetectAndExtract(decodedinput)
-		if err != nil {
-			fmt.Fprintf(out, "ERROR:%v|\n", err)
-			continue
+		errKey := fmt.Sprintf("value %d", i)
+		if withKeys {
+			key, errKey = string(valueKey), string(valueKey)
+			valueKey = nil
 		}
-
-		if metaOnly {
-			buf := bytes.NewBufferString("")
-			err = encoding.DecodeSummary(inMediaType, decodedinput, buf)
-			if err != nil {
-				fmt.Fprintf(out, "ERROR:%v|\n", err)
-			} else {
-				fmt.Fprintf(out, "OK|%s\n", buf.String())
-			}
-			continue
+		buf := new(bytes.Buffer)
+		if err := decryptAndRun(metaFormat, outMediaType, outVersion, config, redactor, key, value, buf); err != nil {
+			return fmt.Errorf("error decoding %s of the stream: %w", errKey, err)
 		}
-
-		buf, _, err := encoding.Convert(scheme.Codecs, inMediaType, outMediaType, decodedinput)
-		if err != nil {
-			fmt.Fprintf(out, "ERROR:%v|\n", err)
-		} else {
-			fmt.Fprintf(out, "OK|%s\n", string(buf))
+		i++
+		isYAML := metaFormat == metaFormatYAML || metaFormat == "" && outMediaType == encoding.YamlMediaType
+		switch {
+		case isYAML && i > 1:
+			if _, err := io.WriteString(out, "---\n"); err != nil {
+				return err
+			}
+		case metaFormat != "":
+		case outMediaType == encoding.ProtobufMediaType || outMediaType == encoding.CborMediaType:
+			return frames.writeFrame(out, buf.Bytes())
 		}
-
-		lineNum++
+		_, err := out.Write(buf.Bytes())
+		return err
+	})
+	if err == nil && valueKey != nil {
+		return fmt.Errorf("no value for key %s at the end of the stream", valueKey)
 	}
+	return err
 }
 
 // Run the decode command line.
-func run(metaOnly bool, outMediaType string, in []byte, out io.Writer) error {
+func run(outMediaType string, outVersion schema.GroupVersion, redactor *redact.Redactor, in []byte, out io.Writer) error {
 	inMediaType, in, err := encoding.DetectAndExtract(in)
 	if err != nil {
 		return err
 	}
 
-	if metaOnly {
-		return encoding.DecodeSummary(inMediaType, in, out)
-	}
-
-	buf, _, err := encoding.Convert(scheme.Codecs, inMediaType, outMediaType, in)
+	buf, err := convert(inMediaType, outMediaType, outVersion, redactor, in, os.Stderr)
 	if err != nil {
 		return err
 	}
@@ -181,6 +369,76 @@ func run(metaOnly bool, outMediaType string, in []byte, out io.Writer) error {
 	return err
 }
 
+// convert converts the input to the desired media type, and to the desired version of its kind unless
+// outVersion is empty, and masks its sensitive values unless redactor is nil. Objects whose kind is unknown
+// to this tool are dumped as raw protobuf instead, with a warning written to errOut, unless they are to be
+// redacted: their sensitive values can't be found, so they are an error.
+func convert(inMediaType, outMediaType string, outVersion schema.GroupVersion, redactor *redact.Redactor, in []byte, errOut io.Writer) ([]byte, error) {
+	if !outVersion.Empty() {
+		buf, err := convertToVersion(inMediaType, outMediaType, outVersion, in, errOut)
+		if err != nil {
+			return nil, err
+		}
+		return redactor.Redact(outMediaType, buf)
+	}
+	buf, _, err := decoder.Convert(inMediaType, outMediaType, in)
+	if errors.Is(err, encoding.ErrUnknownKind) {
+		if redactor != nil {
+			return nil, fmt.Errorf("%w, it can't be redacted", err)
+		}
+		fmt.Fprintf(errOut, "warn: %v, dumping the raw protobuf instead\n", err)
+		buf, _, err = decoder.Convert(inMediaType, encoding.ProtoRawMediaType, in)
+		return buf, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return redactor.Redact(outMediaType, buf)
+}
+
+// convertToVersion converts the input to the desired version of its kind and media type. The fields
+// dropped because they don't exist in the desired version are written to errOut.
+func convertToVersion(inMediaType, outMediaType string, outVersion schema.GroupVersion, in []byte, errOut io.Writer) ([]byte, error) {
+	js, typeMeta, err := decoder.Convert(inMediaType, encoding.JsonMediaType, in)
+	if err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == outVersion.String() {
+		return convert(inMediaType, outMediaType, schema.GroupVersion{}, nil, in, errOut)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(js); err != nil {
+		return nil, err
+	}
+	converted, dropped, err := conversion.Convert(scheme.Scheme, obj, outVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range dropped {
+		fmt.Fprintf(errOut, "warn: %s of %s %s does not exist in %s and is dropped\n", field, typeMeta.Kind, typeMeta.APIVersion, outVersion)
+	}
+
+	// Encode to the storage representation first, which all media types are converted from.
+	stored, err := encoder.Encode(converted, encoding.StorageBinaryMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s: %w", outVersion.WithKind(typeMeta.Kind), err)
+	}
+	buf, _, err := decoder.Convert(encoding.StorageBinaryMediaType, outMediaType, stored)
+	return buf, err
+}
+
+// parseOutputVersion parses the --output-version flag, an empty flag is parsed as an empty version.
+func parseOutputVersion(outVersion string) (schema.GroupVersion, error) {
+	if outVersion == "" {
+		return schema.GroupVersion{}, nil
+	}
+	gv, err := schema.ParseGroupVersion(outVersion)
+	if err != nil {
+		return schema.GroupVersion{}, fmt.Errorf("invalid --output-version %s: %w", outVersion, err)
+	}
+	return gv, nil
+}
+
 // Readinput reads command line input, either from a provided input file or from stdin.
 func readInput(inputFilename string) ([]byte, error) {
 	if inputFilename != "" {
@@ -192,15 +450,11 @@ func readInput(inputFilename string) ([]byte, error) {
 		return data, nil
 	}
 
-	stat, err := os.Stdin.Stat()
+	in, err := openInput("")
 	if err != nil {
-		return nil, fmt.Errorf("stdin error: %w", err)
+		return nil, err
 	}
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		fmt.Fprintln(os.Stderr, "warn: waiting on stdin from tty")
-	}
-
-	stdin, err := io.ReadAll(os.Stdin)
+	stdin, err := io.ReadAll(in)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read data from stdin: %w", err)
 	}
@@ -211,6 +465,47 @@ func readInput(inputFilename string) ([]byte, error) {
 	return stdin, nil
 }
 
+// openInput opens the provided input file, or stdin if no file is provided.
+func openInput(inputFilename string) (io.ReadCloser, error) {
+	if inputFilename != "" {
+		f, err := os.Open(inputFilename)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input file %s: %w", inputFilename, err)
+		}
+		return f, nil
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stdin error: %w", err)
+	}
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		fmt.Fprintln(os.Stderr, "warn: waiting on stdin from tty")
+	}
+	return io.NopCloser(os.Stdin), nil
+}
+
+// loadEncryptionConfig loads the EncryptionConfiguration file at the given path. A nil config is
+// returned if no path is given, it may still be used to pass through values that are not encrypted.
+func loadEncryptionConfig(filename string) (*encryption.Config, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	return encryption.LoadConfig(filename)
+}
+
+// newRedactor returns the redactor of the --redact and --redact-path flags. A nil redactor is returned if
+// redaction is not enabled, it may still be used to pass through objects as is.
+func newRedactor(enabled bool, paths []string, outMediaType string) (*redact.Redactor, error) {
+	if !enabled && len(paths) == 0 {
+		return nil, nil
+	}
+	if !redact.Supported(outMediaType) {
+		return nil, errors.New("--redact only supports yaml and json output")
+	}
+	return redact.New(paths)
+}
+
 func stripNewline(d []byte) []byte {
 	if len(d) > 0 && d[len(d)-1] == '\n' {
 		return d[:len(d)-1]
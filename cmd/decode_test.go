@@ -18,42 +18,66 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/etcd-io/auger/pkg/conversion"
 	"github.com/etcd-io/auger/pkg/encoding"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var decodeTests = []struct {
 	fileIn       string
 	fileExpected string
 
-	metaOnly     bool
+	metaFormat   string
 	outMediaType string
 }{
 	// Pod is in the 'core' group
-	//	{"testdata/storage/pod.bin", "testdata/yaml/pod.yaml", false, encoding.YamlMediaType},
-	{"testdata/storage/pod.bin", "testdata/json/pod.json", false, encoding.JsonMediaType},
-	{"testdata/storage/pod.bin", "testdata/proto/pod.bin", false, encoding.ProtobufMediaType},
-	{"testdata/storage/pod.bin", "testdata/meta/pod.txt", true, encoding.YamlMediaType},
+	//	{"testdata/storage/pod.bin", "testdata/yaml/pod.yaml", "", encoding.YamlMediaType},
+	{"testdata/storage/pod.bin", "testdata/json/pod.json", "", encoding.JsonMediaType},
+	{"testdata/storage/pod.bin", "testdata/proto/pod.bin", "", encoding.ProtobufMediaType},
+	{"testdata/storage/pod.bin", "testdata/meta/pod.txt", metaFormatText, encoding.YamlMediaType},
+	{"testdata/storage/pod.bin", "testdata/meta/pod.yaml", metaFormatYAML, encoding.YamlMediaType},
+	{"testdata/storage/pod.bin", "testdata/meta/pod.json", metaFormatJSON, encoding.YamlMediaType},
 
 	// Job is in the 'batch' group
-	//	{"testdata/storage/job.bin", "testdata/yaml/job.yaml", false, encoding.YamlMediaType},
-	{"testdata/storage/job.bin", "testdata/json/job.json", false, encoding.JsonMediaType},
-	{"testdata/storage/job.bin", "testdata/proto/job.bin", false, encoding.ProtobufMediaType},
-	{"testdata/storage/job.bin", "testdata/meta/job.txt", true, encoding.YamlMediaType},
+	//	{"testdata/storage/job.bin", "testdata/yaml/job.yaml", "", encoding.YamlMediaType},
+	{"testdata/storage/job.bin", "testdata/json/job.json", "", encoding.JsonMediaType},
+	{"testdata/storage/job.bin", "testdata/proto/job.bin", "", encoding.ProtobufMediaType},
+	{"testdata/storage/job.bin", "testdata/meta/job.txt", metaFormatText, encoding.YamlMediaType},
 
 	// JSON
-	{"testdata/json/pod.json", "testdata/json/pod.json", false, encoding.JsonMediaType},
+	{"testdata/json/pod.json", "testdata/json/pod.json", "", encoding.JsonMediaType},
+
+	// CBOR
+	{"testdata/cbor/pod.cbor", "testdata/json/pod.json", "", encoding.JsonMediaType},
+	{"testdata/cbor/pod.cbor", "testdata/meta/pod-cbor.txt", metaFormatText, encoding.YamlMediaType},
+	{"testdata/cbor/job.cbor", "testdata/json/job.json", "", encoding.JsonMediaType},
+
+	// CBOR custom resource, its kind is not registered in the scheme
+	{"testdata/cbor/widget.cbor", "testdata/json/widget.json", "", encoding.JsonMediaType},
+
+	// CronJob in 'batch/v2alpha1', which was removed from k8s.io/api and is decoded with the embedded
+	// protobuf descriptors
+	{"testdata/storage/cronjob-v2alpha1.bin", "testdata/yaml/cronjob-v2alpha1.yaml", "", encoding.YamlMediaType},
+	{"testdata/storage/cronjob-v2alpha1.bin", "testdata/json/cronjob-v2alpha1.json", "", encoding.JsonMediaType},
+	{"testdata/storage/cronjob-v2alpha1.bin", "testdata/meta/cronjob-v2alpha1.txt", metaFormatText, encoding.YamlMediaType},
+
+	// Proto-raw, also used for kinds that are not known at all
+	{"testdata/storage/job.bin", "testdata/proto-raw/job.txt", "", encoding.ProtoRawMediaType},
+	{"testdata/storage/widget.bin", "testdata/proto-raw/widget.txt", "", encoding.ProtoRawMediaType},
+	{"testdata/storage/widget.bin", "testdata/proto-raw/widget.txt", "", encoding.YamlMediaType},
 
 	// With etcd key
-	//	{"testdata/storage/pod-with-key.bin", "testdata/yaml/pod.yaml", false, encoding.YamlMediaType},
-	{"testdata/json/pod-with-key.txt", "testdata/json/pod.json", false, encoding.JsonMediaType},
+	//	{"testdata/storage/pod-with-key.bin", "testdata/yaml/pod.yaml", "", encoding.YamlMediaType},
+	{"testdata/json/pod-with-key.txt", "testdata/json/pod.json", "", encoding.JsonMediaType},
 }
 
 func TestDecode(t *testing.T) {
@@ -61,7 +85,117 @@ func TestDecode(t *testing.T) {
 		in := readTestFile(t, test.fileIn)
 		in = in[:len(in)-1]
 		out := new(bytes.Buffer)
-		if err := run(test.metaOnly, test.outMediaType, in, out); err != nil {
+		if err := decryptAndRun(test.metaFormat, test.outMediaType, schema.GroupVersion{}, nil, nil, "", in, out); err != nil {
+			t.Fatalf("%v for %+v", err, test)
+		}
+		assertMatchesFile(t, out, test.fileExpected)
+	}
+}
+
+func TestDecodeOutputVersion(t *testing.T) {
+	in := readTestFile(t, "testdata/storage/deployment-extensions-v1beta1.bin")
+	in = in[:len(in)-1]
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOut := new(bytes.Buffer)
+	buf, err := convert(inMediaType, encoding.YamlMediaType, schema.GroupVersion{Group: "apps", Version: "v1"}, nil, in, errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFile(t, bytes.NewBuffer(buf), "testdata/yaml/deployment-apps-v1.yaml")
+	if want := "warn: spec.rollbackTo of Deployment extensions/v1beta1 does not exist in apps/v1 and is dropped\n"; errOut.String() != want {
+		t.Errorf("got warnings %q, want %q", errOut, want)
+	}
+
+	_, err = convert(inMediaType, encoding.YamlMediaType, schema.GroupVersion{Group: "batch", Version: "v1"}, nil, in, errOut)
+	if !errors.Is(err, conversion.ErrNoConversionPath) {
+		t.Errorf("got error %v, want %v", err, conversion.ErrNoConversionPath)
+	}
+}
+
+var decodeRedactTests = []struct {
+	fileIn       string
+	fileExpected string
+	paths        []string
+
+	outMediaType string
+}{
+	{"testdata/storage/secret.bin", "testdata/redact/secret.yaml", nil, encoding.YamlMediaType},
+	{"testdata/storage/secret.bin", "testdata/redact/secret.json", nil, encoding.JsonMediaType},
+	{"testdata/storage/secret.bin", "testdata/redact/secret-paths.json", []string{"metadata.uid", "metadata.annotations.*"}, encoding.JsonMediaType},
+	{"testdata/storage/pod.bin", "testdata/json/pod.json", nil, encoding.JsonMediaType},
+}
+
+func TestDecodeRedact(t *testing.T) {
+	for _, test := range decodeRedactTests {
+		redactor, err := newRedactor(true, test.paths, test.outMediaType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := readTestFile(t, test.fileIn)
+		in = in[:len(in)-1]
+		out := new(bytes.Buffer)
+		if err := decryptAndRun("", test.outMediaType, schema.GroupVersion{}, nil, redactor, "", in, out); err != nil {
+			t.Fatalf("%v for %+v", err, test)
+		}
+		assertMatchesFile(t, out, test.fileExpected)
+	}
+
+	// Objects of unknown kinds are not dumped as raw protobuf, their sensitive values can't be masked.
+	redactor, err := newRedactor(true, nil, encoding.JsonMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := readTestFile(t, "testdata/storage/widget.bin")
+	in = in[:len(in)-1]
+	errOut := new(bytes.Buffer)
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := convert(inMediaType, encoding.JsonMediaType, schema.GroupVersion{}, redactor, in, errOut); !errors.Is(err, encoding.ErrUnknownKind) {
+		t.Errorf("got %q, %v, want an unknown kind error", buf, err)
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("got warning %q, want none", errOut)
+	}
+
+	if _, err := newRedactor(true, nil, encoding.ProtobufMediaType); err == nil {
+		t.Error("expected an error for protobuf output")
+	}
+	if redactor, err := newRedactor(false, nil, encoding.ProtobufMediaType); redactor != nil || err != nil {
+		t.Errorf("got %v, %v, want no redactor if redaction is not enabled", redactor, err)
+	}
+}
+
+var decodeEncryptedTests = []struct {
+	fileIn       string
+	fileExpected string
+	key          string
+
+	encryptionConfig string
+	metaFormat       string
+}{
+	{"testdata/storage/pod-aesgcm.bin", "testdata/json/pod.json", "/registry/pods/default/pi-dqtsw", "testdata/encryption/config.yaml", ""},
+	{"testdata/storage/pod-aescbc.bin", "testdata/json/pod.json", "", "testdata/encryption/config.yaml", ""},
+	{"testdata/storage/pod.bin", "testdata/json/pod.json", "", "testdata/encryption/config.yaml", ""},
+	{"testdata/storage/pod-aescbc.bin", "testdata/meta/pod-aescbc.txt", "", "testdata/encryption/config.yaml", metaFormatText},
+	{"testdata/storage/pod-aescbc.bin", "testdata/meta/pod-aescbc-no-config.txt", "", "", metaFormatText},
+	{"testdata/storage/pod-aescbc.bin", "testdata/meta/pod-aescbc.json", "", "testdata/encryption/config.yaml", metaFormatJSON},
+}
+
+func TestDecodeEncrypted(t *testing.T) {
+	for _, test := range decodeEncryptedTests {
+		config, err := loadEncryptionConfig(test.encryptionConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := readTestFile(t, test.fileIn)
+		in = in[:len(in)-1]
+		out := new(bytes.Buffer)
+		if err := decryptAndRun(test.metaFormat, encoding.JsonMediaType, schema.GroupVersion{}, config, nil, test.key, in, out); err != nil {
 			t.Fatalf("%v for %+v", err, test)
 		}
 		assertMatchesFile(t, out, test.fileExpected)
@@ -109,3 +243,35 @@ func readTestFileAsKv(t *testing.T, filename string) *mvccpb.KeyValue {
 	}
 	return kv
 }
+
+func TestDecodeRangeResponse(t *testing.T) {
+	in := readTestFile(t, "testdata/etcdctl/range.json")
+	resp, err := parseRangeResponse(fromEtcdctlJSON, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inProtobuf, err := resp.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		from         string
+		in           []byte
+		outMediaType string
+		fileExpected string
+	}{
+		{fromEtcdctlJSON, in, encoding.YamlMediaType, "testdata/etcdctl/range.yaml"},
+		{fromEtcdctlJSON, in, encoding.JsonMediaType, "testdata/etcdctl/range.jsonl"},
+		{fromEtcdctlProtobuf, inProtobuf, encoding.YamlMediaType, "testdata/etcdctl/range.yaml"},
+	} {
+		out := new(bytes.Buffer)
+		if err := decodeRangeResponse(test.from, test.outMediaType, schema.GroupVersion{}, nil, nil, test.in, out); err != nil {
+			t.Fatalf("%v for --from %s to %s", err, test.from, test.outMediaType)
+		}
+		assertMatchesFile(t, out, test.fileExpected)
+	}
+
+	if err := decodeRangeResponse(fromEtcdctlJSON, encoding.ProtobufMediaType, schema.GroupVersion{}, nil, nil, in, new(bytes.Buffer)); err == nil {
+		t.Error("expected an error for protobuf output")
+	}
+}
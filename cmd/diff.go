@@ -0,0 +1,353 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/etcd-io/auger/pkg/diff"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	diffLong = `
+Compares two stored kubernetes objects field by field, e.g. the same key
+on two etcd members, in two backups or at two revisions.
+
+Each input is one of:
+
+  <file>                         a file holding a value, as written by
+                                 'etcdctl get --print-value-only', or
+                                 hex or base64 encoded
+  <boltdb-file>#<key>[@<rev>]    a key in a boltdb '.db' file, at the
+                                 latest revision or at the etcd revision
+                                 <rev>, i.e. as of that mod revision
+  <boltdb-file>#<key>@v<version> a version of a key in a boltdb '.db'
+                                 file, as listed by 'auger extract
+                                 --list-versions', i.e. the number of
+                                 times the key was written
+  etcd:<key>[@<rev>]             a key in a live etcd cluster, reached
+                                 with --endpoints, at the latest revision
+                                 or at the etcd revision <rev>
+
+Keys may contain '@', e.g. /registry/clusterrolebindings/alice@example.com:
+only a suffix after the last '@' that is a number, or v and a number, is
+taken as a revision or version.
+
+Both objects are decoded, decrypted with --encryption-config if needed,
+and the fields that were added, removed or changed are printed by their
+path. Items of lists whose items all have a distinct name, such as
+containers, are compared by name rather than by index.
+
+With --redact, the data of Secrets and kubeconfigs of ConfigMaps are
+masked with their size before they are compared, like by 'auger decode
+--redact', so that their values are not printed. Masked values are only
+reported as changed if their size changed. --redact-path masks the
+values of more fields.
+
+Exits with a non-zero status if the objects differ.`
+
+	diffExample = `
+        # Compare a pod on two members:
+        auger diff member-1/snap/db#/registry/pods/default/<pod-name> member-2/snap/db#/registry/pods/default/<pod-name>
+
+        # Compare a deployment at an older revision with the live one, ignoring its status:
+        auger diff backup.db#/registry/deployments/default/<name>@1234 etcd:/registry/deployments/default/<name> --ignore-status
+
+        # Compare the first and the third version of a pod in a boltdb file:
+        auger diff db#/registry/pods/default/<pod-name>@v1 db#/registry/pods/default/<pod-name>@v3`
+)
+
+var diffCmd = &cobra.Command{
+	Use:     "diff <input> <input>",
+	Short:   "Compares two stored kubernetes objects field by field.",
+	Long:    diffLong,
+	Example: diffExample,
+	Args:    cobra.ExactArgs(2),
+	// A difference is an error, the usage would only hide it.
+	SilenceUsage: true,
+	RunE: func(_ *cobra.Command, args []string) error {
+		return diffValidateAndRun(args[0], args[1])
+	},
+}
+
+type diffOptions struct {
+	out                   string
+	ignoreResourceVersion bool
+	ignoreManagedFields   bool
+	ignoreStatus          bool
+	encryptionConfig      string
+	redact                bool
+	redactPaths           []string
+
+	endpoints          []string
+	tls                transport.TLSInfo
+	insecureSkipVerify bool
+	user               string
+	password           string
+}
+
+var diffOpts = &diffOptions{}
+
+const (
+	diffOutputText = "text"
+	diffOutputJSON = "json"
+
+	// etcdInputPrefix marks diff inputs read from a live etcd cluster.
+	etcdInputPrefix = "etcd:"
+)
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffOpts.out, "output", "o", diffOutputText, "Output format. One of: text|json")
+	diffCmd.Flags().BoolVar(&diffOpts.ignoreResourceVersion, "ignore-resource-version", false, "Ignore metadata.resourceVersion")
+	diffCmd.Flags().BoolVar(&diffOpts.ignoreManagedFields, "ignore-managed-fields", false, "Ignore metadata.managedFields")
+	diffCmd.Flags().BoolVar(&diffOpts.ignoreStatus, "ignore-status", false, "Ignore the status of the objects")
+	diffCmd.Flags().StringVar(&diffOpts.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+	diffCmd.Flags().BoolVar(&diffOpts.redact, "redact", false, "Mask the data of Secrets and kubeconfigs of ConfigMaps, keeping their keys and sizes")
+	diffCmd.Flags().StringArrayVar(&diffOpts.redactPaths, "redact-path", nil, "Also mask the values at this dot separated path of all objects, * matches any field or item, implies --redact")
+	diffCmd.Flags().StringSliceVar(&diffOpts.endpoints, "endpoints", []string{"127.0.0.1:2379"}, "gRPC endpoints of the etcd cluster of etcd: inputs")
+	diffCmd.Flags().StringVar(&diffOpts.tls.CertFile, "tls-cert", "", "path to the etcd client TLS cert file")
+	diffCmd.Flags().StringVar(&diffOpts.tls.KeyFile, "tls-key", "", "path to the etcd client TLS key file")
+	diffCmd.Flags().StringVar(&diffOpts.tls.TrustedCAFile, "tls-cacert", "", "path to the etcd client TLS CA cert file")
+	diffCmd.Flags().BoolVar(&diffOpts.insecureSkipVerify, "insecure-skip-tls-verify", false, "skip server certificate verification")
+	diffCmd.Flags().StringVar(&diffOpts.user, "user", "", "username for authentication, provide username[:password]")
+	diffCmd.Flags().StringVar(&diffOpts.password, "password", "", "password for authentication, only available if --user has no password")
+}
+
+func diffValidateAndRun(oldInput, newInput string) error {
+	if diffOpts.out != diffOutputText && diffOpts.out != diffOutputJSON {
+		return fmt.Errorf("unrecognized 'output' flag value: %v", diffOpts.out)
+	}
+	config, err := loadEncryptionConfig(diffOpts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactor(diffOpts.redact, diffOpts.redactPaths, encoding.JsonMediaType)
+	if err != nil {
+		return err
+	}
+	var ignored [][]string
+	if diffOpts.ignoreResourceVersion {
+		ignored = append(ignored, []string{"metadata", "resourceVersion"})
+	}
+	if diffOpts.ignoreManagedFields {
+		ignored = append(ignored, []string{"metadata", "managedFields"})
+	}
+	if diffOpts.ignoreStatus {
+		ignored = append(ignored, []string{"status"})
+	}
+
+	var cli *clientv3.Client
+	if strings.HasPrefix(oldInput, etcdInputPrefix) || strings.HasPrefix(newInput, etcdInputPrefix) {
+		cli, err = newEtcdClient(diffOpts)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+	}
+	var objects [2]map[string]interface{}
+	for i, input := range []string{oldInput, newInput} {
+		key, value, err := readDiffInput(cli, input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		if objects[i], err = decodeDiffInput(config, redactor, key, value, ignored); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+	}
+	return diffObjects(diffOpts.out, oldInput, newInput, objects[0], objects[1], os.Stdout)
+}
+
+// diffObjects writes the fields that differ between two objects, as a unified diff style header followed
+// by a line for each field, or as a JSON array of diff.Change. It returns an error if the objects differ.
+func diffObjects(format string, oldName, newName string, old, new map[string]interface{}, out io.Writer) error {
+	changes := diff.Objects(old, new)
+	if format == diffOutputJSON {
+		if changes == nil {
+			changes = []diff.Change{}
+		}
+		buf, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", buf); err != nil {
+			return err
+		}
+	} else if len(changes) > 0 {
+		fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+		for _, c := range changes {
+			fmt.Fprintln(out, c)
+		}
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%d fields differ", len(changes))
+	}
+	return nil
+}
+
+// readDiffInput reads the value of a diff input, and its etcd key if known.
+func readDiffInput(cli *clientv3.Client, input string) (key string, value []byte, err error) {
+	if key, ok := strings.CutPrefix(input, etcdInputPrefix); ok {
+		key, revision, version, err := parseKeyRevision(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if version > 0 {
+			return "", nil, errors.New("versions of keys are only supported for boltdb files, use @<rev>")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		resp, err := cli.Get(ctx, key, clientv3.WithRev(revision))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(resp.Kvs) == 0 {
+			return "", nil, fmt.Errorf("key %s not found", key)
+		}
+		return key, resp.Kvs[0].Value, nil
+	}
+
+	if filename, key, ok := strings.Cut(input, "#"); ok {
+		key, revision, version, err := parseKeyRevision(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if version > 0 {
+			value, err := data.GetValue(filename, key, version)
+			if err != nil {
+				return "", nil, err
+			}
+			return key, value, nil
+		}
+		kvs, err := data.ListValues(filename, key, revision)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, kv := range kvs {
+			if string(kv.Key) == key {
+				return key, kv.Value, nil
+			}
+		}
+		return "", nil, fmt.Errorf("key %s not found", key)
+	}
+
+	in, err := readInput(input)
+	if err != nil {
+		return "", nil, err
+	}
+	in, _, err = unwrapInput(inputEncodingAuto, in)
+	return "", in, err
+}
+
+// parseKeyRevision parses a '<key>[@<revision>]' or '<key>@v<version>' diff input. Keys may contain '@',
+// e.g. the names of ClusterRoleBindings of users, so only a suffix after the last '@' that is a number, or
+// 'v' and a number, is a revision or a version. The revision and the version are 0 if not set.
+func parseKeyRevision(s string) (key string, revision, version int64, err error) {
+	key = s
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		suffix := s[i+1:]
+		v, isVersion := strings.CutPrefix(suffix, "v")
+		if isDigits(v) {
+			key = s[:i]
+			n, err := strconv.ParseInt(v, 10, 64)
+			switch {
+			case isVersion && (err != nil || n <= 0):
+				return "", 0, 0, fmt.Errorf("invalid version %q", v)
+			case isVersion:
+				version = n
+			case err != nil || n <= 0:
+				return "", 0, 0, fmt.Errorf("invalid revision %q", suffix)
+			default:
+				revision = n
+			}
+		}
+	}
+	if key == "" {
+		return "", 0, 0, errors.New("no key given")
+	}
+	return key, revision, version, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeDiffInput decrypts and decodes a value to an object, without the ignored fields. Its sensitive values
+// are masked unless redactor is nil.
+func decodeDiffInput(config *encryption.Config, redactor *redact.Redactor, key string, value []byte, ignored [][]string) (map[string]interface{}, error) {
+	js, err := decodeToJSON(schema.GroupVersion{}, config, redactor, key, value)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, err
+	}
+	for _, fields := range ignored {
+		unstructured.RemoveNestedField(obj, fields...)
+	}
+	return obj, nil
+}
+
+// newEtcdClient connects to the etcd cluster of etcd: inputs.
+func newEtcdClient(o *diffOptions) (*clientv3.Client, error) {
+	cfg := clientv3.Config{Endpoints: o.endpoints, DialTimeout: 5 * time.Second}
+	if !o.tls.Empty() || o.insecureSkipVerify {
+		tlsConfig, err := o.tls.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.InsecureSkipVerify = o.insecureSkipVerify
+		cfg.TLS = tlsConfig
+	}
+	if o.user != "" {
+		cfg.Username, cfg.Password = o.user, o.password
+		if o.password == "" {
+			user, password, ok := strings.Cut(o.user, ":")
+			if !ok {
+				return nil, errors.New("password is missing")
+			}
+			cfg.Username, cfg.Password = user, password
+		}
+	}
+	return clientv3.New(cfg)
+}
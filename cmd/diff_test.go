@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	pod := string(readTestFile(t, "testdata/json/pod.json"))
+	changed := strings.NewReplacer(`"image":"perl"`, `"image":"perl:5"`, `"job-name":"pi"`, `"job-name":"pi-2"`, `"phase":"Succeeded"`, `"phase":"Failed"`).Replace(pod)
+	changedFile := filepath.Join(dir, "changed.json")
+	if err := os.WriteFile(changedFile, []byte(changed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// base64 encoded values are detected like by decode.
+	encodedFile := filepath.Join(dir, "pod.b64")
+	if err := os.WriteFile(encodedFile, []byte(base64.StdEncoding.EncodeToString([]byte(pod))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The password is changed to one of the same size.
+	secretFile := filepath.Join(dir, "secret.json")
+	secret := `{"kind":"Secret","apiVersion":"v1","metadata":{"name":"db","namespace":"default"},"data":{"password":"aHVudGVyMw==","username":"YWRtaW4="},"type":"Opaque"}`
+	if err := os.WriteFile(secretFile, []byte(secret), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		old     string
+		new     string
+		ignored [][]string
+		redact  bool
+		want    string
+	}{
+		{
+			name: "db and file",
+			old:  "testdata/boltdb/db#/registry/pods/default/pi-dqtsw",
+			new:  "testdata/storage/pod.bin",
+		},
+		{
+			name: "db version and file",
+			old:  "testdata/boltdb/db#/registry/pods/default/pi-dqtsw@v5",
+			new:  "testdata/storage/pod.bin",
+		},
+		{
+			name: "encoded",
+			old:  "testdata/json/pod.json",
+			new:  encodedFile,
+		},
+		{
+			name: "changed",
+			old:  "testdata/json/pod.json",
+			new:  changedFile,
+			want: `--- testdata/json/pod.json
++++ ` + changedFile + `
+~ metadata.labels.job-name: "pi" -> "pi-2"
+~ spec.containers[name=pi].image: "perl" -> "perl:5"
+~ status.phase: "Succeeded" -> "Failed"
+`,
+		},
+		{
+			name:    "ignore status",
+			old:     "testdata/json/pod.json",
+			new:     changedFile,
+			ignored: [][]string{{"status"}},
+			want: `--- testdata/json/pod.json
++++ ` + changedFile + `
+~ metadata.labels.job-name: "pi" -> "pi-2"
+~ spec.containers[name=pi].image: "perl" -> "perl:5"
+`,
+		},
+		{
+			name:    "secret",
+			old:     "testdata/storage/secret.bin",
+			new:     secretFile,
+			ignored: [][]string{{"metadata"}},
+			want: `--- testdata/storage/secret.bin
++++ ` + secretFile + `
+~ data.password: "aHVudGVyMg==" -> "aHVudGVyMw=="
+`,
+		},
+		{
+			name:    "redacted secret",
+			old:     "testdata/storage/secret.bin",
+			new:     secretFile,
+			ignored: [][]string{{"metadata"}},
+			redact:  true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := newRedactor(tt.redact, nil, encoding.JsonMediaType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var objects [2]map[string]interface{}
+			for i, input := range []string{tt.old, tt.new} {
+				key, value, err := readDiffInput(nil, input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if objects[i], err = decodeDiffInput(nil, redactor, key, value, tt.ignored); err != nil {
+					t.Fatal(err)
+				}
+			}
+			out := new(bytes.Buffer)
+			err = diffObjects(diffOutputText, tt.old, tt.new, objects[0], objects[1], out)
+			if (err != nil) != (tt.want != "") {
+				t.Errorf("got error %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffInputErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{input: "testdata/boltdb/db#/registry/pods/default/missing", want: "key /registry/pods/default/missing not found"},
+		{input: "testdata/boltdb/db#/registry/pods/default/pi-dqtsw@0", want: `invalid revision "0"`},
+		{input: "testdata/boltdb/db#/registry/pods/default/pi-dqtsw@x", want: "key /registry/pods/default/pi-dqtsw@x not found"},
+		{input: "testdata/boltdb/db#", want: "no key given"},
+		{input: "testdata/boltdb/db#/registry/pods/default/pi-dqtsw@v0", want: `invalid version "0"`},
+		{input: "testdata/boltdb/db#/registry/pods/default/pi-dqtsw@v1", want: "key not found: /registry/pods/default/pi-dqtsw"},
+		{input: "etcd:/registry/pods/default/pi-dqtsw@v1", want: "versions of keys are only supported for boltdb files, use @<rev>"},
+	}
+	for _, tt := range cases {
+		if _, _, err := readDiffInput(nil, tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("got error %v for %s, want %s", err, tt.input, tt.want)
+		}
+	}
+}
+
+func TestParseKeyRevision(t *testing.T) {
+	cases := []struct {
+		input    string
+		key      string
+		revision int64
+		version  int64
+	}{
+		{input: "/registry/pods/default/web", key: "/registry/pods/default/web"},
+		{input: "/registry/pods/default/web@12", key: "/registry/pods/default/web", revision: 12},
+		{input: "/registry/pods/default/web@v3", key: "/registry/pods/default/web", version: 3},
+		{input: "/registry/clusterrolebindings/alice@example.com", key: "/registry/clusterrolebindings/alice@example.com"},
+		{input: "/registry/clusterrolebindings/alice@example.com@12", key: "/registry/clusterrolebindings/alice@example.com", revision: 12},
+		{input: "/registry/clusterrolebindings/alice@v2", key: "/registry/clusterrolebindings/alice", version: 2},
+	}
+	for _, tt := range cases {
+		key, revision, version, err := parseKeyRevision(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if key != tt.key || revision != tt.revision || version != tt.version {
+			t.Errorf("got key %s, revision %d and version %d for %s, want %s, %d and %d", key, revision, version, tt.input, tt.key, tt.revision, tt.version)
+		}
+	}
+	if _, _, _, err := parseKeyRevision("@12"); err == nil || err.Error() != "no key given" {
+		t.Errorf("got error %v, want no key given", err)
+	}
+}
@@ -17,24 +17,83 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/etcd-io/auger/pkg/client"
+	"github.com/etcd-io/auger/pkg/conversion"
 	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/roundtrip"
 	"github.com/etcd-io/auger/pkg/scheme"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
 var (
 	encodeLong = `
 Encodes kubernetes objects to the binary key-value store encoding used
 with etcd 3+.
+
+By default objects are encoded as protobuf, in the runtime.Unknown
+envelope kube-apiserver writes to etcd. With '-o cbor' they are
+encoded as self-described CBOR instead, which newer kubernetes releases
+may use to store objects.
+
+When --encryption-config is set, the encoded object is encrypted with the
+first provider configured for its resource, the same way kube-apiserver
+encrypts data at rest. --key must then be set to the etcd key the object
+will be written to, since some providers bind the encrypted value to it.
+
+With --strict, the input is checked before it is encoded so that hand
+edits can't write bad data to etcd: unknown and duplicate fields are
+errors, the apiVersion and kind must be registered, and the apiVersion
+must be the latest version of the kind known to this tool. That is the
+version kube-apiserver usually stores the kind at, but it depends on the
+release and configuration of kube-apiserver. Objects of other versions
+can be converted with 'auger decode --output-version' first.
+
+YAML and JSON input may hold more than one object: multiple YAML
+documents, concatenated JSON objects, JSON arrays and lists, e.g. of
+kind List, are split into their objects. Each object is written as its
+etcd key followed by its encoded value, separated by --delimiter or
+prefixed with their length if --length-prefixed is set. Protobuf values
+may contain any byte, use --length-prefixed to encode them. Keys are
+computed from the kind, namespace and name of the object and --prefix,
+and are used as authenticated data when encrypting. Objects of
+namespaced kinds must have their namespace set, the namespace of objects
+of cluster-scoped kinds is ignored. Kinds that are not registered, such
+as custom resources, are taken to be namespaced.
 `
 
 	encodeExample = `
 	    cat pod.yaml | auger encode | \
-	    ETCDCTL_API=3 etcdctl put /registry/pods/default/<pod-name>`
+	    ETCDCTL_API=3 etcdctl put /registry/pods/default/<pod-name>
+
+	    # Store a custom resource as CBOR
+	    cat widget.yaml | auger encode -o cbor | \
+	    ETCDCTL_API=3 etcdctl put /registry/example.com/widgets/default/<widget-name>
+
+	    # Refuse to encode objects with unknown fields or not at the latest version of their kind
+	    cat pod.yaml | auger encode --strict | \
+	    ETCDCTL_API=3 etcdctl put /registry/pods/default/<pod-name>
+
+	    # Encode all objects of a file to a stream of their keys and values
+	    auger encode --file objects.yaml --length-prefixed > objects.bin
+
+	    # Encrypt the encoded secret the way kube-apiserver would
+	    cat secret.yaml | auger encode --encryption-config encryption-config.yaml \
+	    --key /registry/secrets/default/<secret-name> | \
+	    ETCDCTL_API=3 etcdctl put /registry/secrets/default/<secret-name>`
 )
 
 var encodeCmd = &cobra.Command{
@@ -48,16 +107,30 @@ var encodeCmd = &cobra.Command{
 }
 
 type encodeOptions struct {
-	in            string
-	inputFilename string
+	in               string
+	out              string
+	inputFilename    string
+	encryptionConfig string
+	key              string
+	strict           bool
+	prefix           string
+	delimiter        string
+	lengthPrefixed   bool
 }
 
 var encodeOpts = &encodeOptions{}
 
 func init() {
 	RootCmd.AddCommand(encodeCmd)
-	encodeCmd.Flags().StringVarP(&encodeOpts.in, "format", "f", "yaml", "Input format. One of: json|yaml|proto")
+	encodeCmd.Flags().StringVarP(&encodeOpts.in, "format", "f", "yaml", "Input format. One of: json|yaml|proto|cbor")
+	encodeCmd.Flags().StringVarP(&encodeOpts.out, "output", "o", encoding.ProtobufShortname, "Storage encoding to output. One of: proto|cbor")
 	encodeCmd.Flags().StringVar(&encodeOpts.inputFilename, "file", "", "Filename to read input data from")
+	encodeCmd.Flags().StringVar(&encodeOpts.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to encrypt data at rest, the first provider matching the object's resource is used")
+	encodeCmd.Flags().StringVar(&encodeOpts.key, "key", "", "Etcd key the encoded object is written to, used as authenticated data when encrypting")
+	encodeCmd.Flags().StringVar(&encodeOpts.prefix, "prefix", "/registry", "Etcd key prefix of kube-apiserver, used to compute the keys of the encoded objects")
+	encodeCmd.Flags().StringVar(&encodeOpts.delimiter, "delimiter", "", "Write the key and value of each object followed by this delimiter, e.g. '\\n'")
+	encodeCmd.Flags().BoolVar(&encodeOpts.lengthPrefixed, "length-prefixed", false, "Write the key and value of each object prefixed with its length as a 4 byte big-endian integer")
+	encodeCmd.Flags().BoolVar(&encodeOpts.strict, "strict", false, "Fail on unknown or duplicate fields, kinds that are not registered and versions that are not the latest version of the kind")
 }
 
 // encodeValidateAndRun validates the command line flags and runs the command.
@@ -67,6 +140,14 @@ func encodeValidateAndRun() error {
 	if err != nil {
 		return err
 	}
+	if inMediaType == encoding.ProtoRawMediaType {
+		return fmt.Errorf("unrecognized 'format' flag value: %v", encodeOpts.in)
+	}
+
+	outMediaType, err := toStorageMediaType(encodeOpts.out)
+	if err != nil {
+		return err
+	}
 
 	in, err := readInput(encodeOpts.inputFilename)
 	if len(in) == 0 {
@@ -76,12 +157,274 @@ func encodeValidateAndRun() error {
 		return err
 	}
 
-	return encodeRun(inMediaType, in, os.Stdout)
+	frames, err := newFraming(encodeOpts.delimiter, encodeOpts.lengthPrefixed)
+	if err != nil {
+		return err
+	}
+	if inMediaType == encoding.YamlMediaType || inMediaType == encoding.JsonMediaType {
+		objects, multiple, err := splitObjects(in)
+		if err != nil {
+			return err
+		}
+		if multiple || frames != nil {
+			return encodeObjectsAndRun(objects, outMediaType, frames)
+		}
+	} else if frames != nil {
+		return errors.New("--delimiter and --length-prefixed require yaml or json input")
+	}
+
+	if encodeOpts.strict {
+		if err := validateStrict(inMediaType, in); err != nil {
+			return err
+		}
+	}
+
+	if encodeOpts.encryptionConfig == "" {
+		return encodeRun(inMediaType, outMediaType, in, os.Stdout)
+	}
+	if encodeOpts.key == "" {
+		return errors.New("--key must be set when --encryption-config is set")
+	}
+	config, err := loadEncryptionConfig(encodeOpts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	return encodeAndEncrypt(inMediaType, outMediaType, config, encodeOpts.key, in, os.Stdout)
+}
+
+// encodeObjectsAndRun validates the command line flags for input holding any number of objects and
+// encodes them.
+func encodeObjectsAndRun(objects [][]byte, outMediaType string, frames *framing) error {
+	if frames == nil {
+		return fmt.Errorf("input holds %d objects, --delimiter or --length-prefixed must be set to separate them", len(objects))
+	}
+	if encodeOpts.key != "" {
+		return errors.New("--key can't be set when encoding a stream of objects, the key of each object is computed")
+	}
+	config, err := loadEncryptionConfig(encodeOpts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	return encodeObjects(objects, outMediaType, encodeOpts.strict, config, encodeOpts.prefix, frames, os.Stdout)
+}
+
+// toStorageMediaType maps 'output' flag values of the encode command to the media types
+// kubernetes objects are stored as.
+func toStorageMediaType(out string) (string, error) {
+	switch out {
+	case encoding.ProtobufShortname:
+		return encoding.StorageBinaryMediaType, nil
+	case encoding.CborShortname:
+		return encoding.CborMediaType, nil
+	default:
+		return "", fmt.Errorf("unrecognized 'output' flag value: %v", out)
+	}
+}
+
+// validateStrict checks that the input decodes without unknown or duplicate fields to a kind registered
+// in the scheme, at the version kube-apiserver stores the kind at.
+func validateStrict(inMediaType string, in []byte) error {
+	typeMetaMediaType := inMediaType
+	if inMediaType == encoding.ProtobufMediaType {
+		// Protobuf input is in the same 'Unknown' envelope as the storage representation.
+		typeMetaMediaType = encoding.StorageBinaryMediaType
+	}
+	typeMeta, err := encoding.DecodeTypeMeta(typeMetaMediaType, in)
+	if err != nil {
+		return err
+	}
+	if typeMeta.APIVersion == "" || typeMeta.Kind == "" {
+		return errors.New("apiVersion and kind must be set")
+	}
+	gvk := schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind)
+	if !scheme.Scheme.Recognizes(gvk) {
+		return fmt.Errorf("%s is not registered in the scheme", gvk)
+	}
+	latest, err := conversion.LatestVersion(scheme.Scheme, gvk.GroupKind())
+	if err != nil {
+		return err
+	}
+	if latest != gvk {
+		return fmt.Errorf("%s is not the latest version of the kind, %s, convert it with 'auger decode --output-version %s' first", gvk, latest, latest.GroupVersion())
+	}
+
+	switch inMediaType {
+	case encoding.ProtobufMediaType, encoding.StorageBinaryMediaType:
+		result, err := roundtrip.Verify(scheme.Codecs, in)
+		if err != nil {
+			return err
+		}
+		var unknown []error
+		for _, f := range result.Findings {
+			if f.Reason == roundtrip.Unknown {
+				unknown = append(unknown, fmt.Errorf("unknown field %s at offset %d", f.Path, f.Offset))
+			}
+		}
+		if len(unknown) > 0 {
+			return runtime.NewStrictDecodingError(unknown)
+		}
+	default:
+		info, ok := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), inMediaType)
+		if !ok || info.StrictSerializer == nil {
+			return fmt.Errorf("no strict decoder for %s", inMediaType)
+		}
+		// Strict decoding errors list all unknown and duplicate fields.
+		if _, _, err := info.StrictSerializer.Decode(in, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitObjects splits YAML or JSON input into the JSON of the objects it holds. Multiple YAML documents,
+// concatenated JSON objects, JSON arrays and lists are split, and multiple is set unless the input holds a
+// single object.
+func splitObjects(in []byte) (objects [][]byte, multiple bool, err error) {
+	documents := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(in), 4096)
+	count := 0
+	for {
+		var doc json.RawMessage
+		if err := documents.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, false, fmt.Errorf("error splitting input into objects: %w", err)
+		}
+		count++
+		items, isList, err := listItems(doc)
+		if err != nil {
+			return nil, false, err
+		}
+		multiple = multiple || isList
+		objects = append(objects, items...)
+	}
+	return objects, multiple || count > 1, nil
+}
+
+// listItems returns the items of a JSON array or list, e.g. of kind List, or the document itself if it
+// is a single object. Empty documents have no items.
+func listItems(doc json.RawMessage) ([][]byte, bool, error) {
+	doc = bytes.TrimSpace(doc)
+	var items []json.RawMessage
+	switch {
+	case len(doc) == 0 || bytes.Equal(doc, []byte("null")):
+		return nil, false, nil
+	case doc[0] == '[':
+		if err := json.Unmarshal(doc, &items); err != nil {
+			return nil, false, err
+		}
+	default:
+		var list struct {
+			Kind  string            `json:"kind"`
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(doc, &list); err != nil {
+			return nil, false, err
+		}
+		if !strings.HasSuffix(list.Kind, "List") {
+			return [][]byte{doc}, false, nil
+		}
+		items = list.Items
+	}
+	objects := make([][]byte, len(items))
+	for i, item := range items {
+		objects[i] = item
+	}
+	return objects, true, nil
+}
+
+// encodeObjects encodes JSON objects and writes the etcd key and the encoded value of each as frames.
+// Objects are encrypted for their key if an encryption config is given.
+func encodeObjects(objects [][]byte, outMediaType string, strict bool, config *encryption.Config, prefix string, frames *framing, out io.Writer) error {
+	for i, obj := range objects {
+		if strict {
+			if err := validateStrict(encoding.JsonMediaType, obj); err != nil {
+				return fmt.Errorf("object %d: %w", i, err)
+			}
+		}
+		key, err := objectKey(prefix, obj)
+		if err != nil {
+			return fmt.Errorf("object %d: %w", i, err)
+		}
+		buf := new(bytes.Buffer)
+		if config == nil {
+			err = encodeRun(encoding.JsonMediaType, outMediaType, obj, buf)
+		} else {
+			err = encodeAndEncrypt(encoding.JsonMediaType, outMediaType, config, key, obj, buf)
+		}
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", key, err)
+		}
+		if err := frames.writeFrame(out, []byte(key)); err != nil {
+			return err
+		}
+		if err := frames.writeFrame(out, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectKey returns the etcd key kube-apiserver stores the JSON object at. Objects of namespaced kinds must
+// have a namespace, the namespace of objects of cluster-scoped kinds is ignored like by kube-apiserver.
+func objectKey(prefix string, obj []byte) (string, error) {
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(obj); err != nil {
+		return "", err
+	}
+	gr, namespaced, err := objectResource(u.GroupVersionKind())
+	if err != nil {
+		return "", err
+	}
+	namespace := u.GetNamespace()
+	if !namespaced {
+		namespace = ""
+	} else if namespace == "" {
+		return "", fmt.Errorf("error computing the key of %s %s: namespace is empty", u.GetKind(), u.GetName())
+	}
+	key, err := client.Key(prefix, gr, u.GetName(), namespace)
+	if err != nil {
+		return "", fmt.Errorf("error computing the key of %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return key, nil
+}
+
+// objectResource returns the resource of a kind and whether it is namespaced, from scheme.RESTMapper. The
+// resource of other kinds, such as custom resources, is guessed from their kind, and they are namespaced.
+func objectResource(gvk schema.GroupVersionKind) (schema.GroupResource, bool, error) {
+	mapping, err := scheme.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		return gvr.GroupResource(), true, nil
+	}
+	if err != nil {
+		return schema.GroupResource{}, false, err
+	}
+	return mapping.Resource.GroupResource(), mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
 }
 
 // encodeRun runs the encode command.
-func encodeRun(inMediaType string, in []byte, out io.Writer) error {
-	buf, _, err := encoding.Convert(scheme.Codecs, inMediaType, encoding.StorageBinaryMediaType, in)
+func encodeRun(inMediaType, outMediaType string, in []byte, out io.Writer) error {
+	buf, _, err := decoder.Convert(inMediaType, outMediaType, in)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(buf)
+	return err
+}
+
+// encodeAndEncrypt encodes the object and encrypts it for the given etcd key with the write provider
+// the encryption config selects for the object's resource.
+func encodeAndEncrypt(inMediaType, outMediaType string, config *encryption.Config, key string, in []byte, out io.Writer) error {
+	buf, typeMeta, err := decoder.Convert(inMediaType, outMediaType, in)
+	if err != nil {
+		return err
+	}
+	gr, _, err := objectResource(schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind))
+	if err != nil {
+		return err
+	}
+	buf, err = config.Encrypt(gr, key, buf)
 	if err != nil {
 		return err
 	}
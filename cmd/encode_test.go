@@ -18,32 +18,38 @@ package cmd
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/etcd-io/auger/pkg/encoding"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var encodeTests = []struct {
 	fileIn       string
 	fileExpected string
 	inMediaType  string
+	outMediaType string
 }{
-	{"testdata/yaml/pod.yaml", "testdata/storage/pod.bin", encoding.YamlMediaType},
-	{"testdata/json/pod.json", "testdata/storage/pod.bin", encoding.JsonMediaType},
-	{"testdata/yaml/job.yaml", "testdata/storage/job.bin", encoding.YamlMediaType},
-	{"testdata/json/job.json", "testdata/storage/job.bin", encoding.JsonMediaType},
+	{"testdata/yaml/pod.yaml", "testdata/storage/pod.bin", encoding.YamlMediaType, encoding.StorageBinaryMediaType},
+	{"testdata/json/pod.json", "testdata/storage/pod.bin", encoding.JsonMediaType, encoding.StorageBinaryMediaType},
+	{"testdata/yaml/job.yaml", "testdata/storage/job.bin", encoding.YamlMediaType, encoding.StorageBinaryMediaType},
+	{"testdata/json/job.json", "testdata/storage/job.bin", encoding.JsonMediaType, encoding.StorageBinaryMediaType},
+	{"testdata/yaml/pod.yaml", "testdata/cbor/pod.cbor", encoding.YamlMediaType, encoding.CborMediaType},
+	{"testdata/json/job.json", "testdata/cbor/job.cbor", encoding.JsonMediaType, encoding.CborMediaType},
+	{"testdata/json/widget.json", "testdata/cbor/widget.cbor", encoding.JsonMediaType, encoding.CborMediaType},
 }
 
 func TestEncode(t *testing.T) {
 	for _, test := range encodeTests {
 		in := readTestFile(t, test.fileIn)
 		out := new(bytes.Buffer)
-		if err := encodeRun(test.inMediaType, in, out); err != nil {
+		if err := encodeRun(test.inMediaType, test.outMediaType, in, out); err != nil {
 			t.Errorf("%v for %+v", err, test)
 			continue
 		}
 		rt := new(bytes.Buffer)
-		if err := run(false, test.inMediaType, out.Bytes(), rt); err != nil {
+		if err := run(test.inMediaType, schema.GroupVersion{}, nil, out.Bytes(), rt); err != nil {
 			t.Errorf("%v for round trip of %+v", err, test)
 			continue
 		}
@@ -53,3 +59,238 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeEncrypted(t *testing.T) {
+	config, err := loadEncryptionConfig("testdata/encryption/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "/registry/pods/default/pi-dqtsw"
+	in := readTestFile(t, "testdata/json/pod.json")
+	out := new(bytes.Buffer)
+	if err := encodeAndEncrypt(encoding.JsonMediaType, encoding.StorageBinaryMediaType, config, key, in, out); err != nil {
+		t.Fatal(err)
+	}
+	if prefix := []byte("k8s:enc:aesgcm:v1:key1:"); !bytes.HasPrefix(out.Bytes(), prefix) {
+		t.Fatalf("got %q, expected prefix %q", out.Bytes(), prefix)
+	}
+	rt := new(bytes.Buffer)
+	if err := decryptAndRun("", encoding.JsonMediaType, schema.GroupVersion{}, config, nil, key, out.Bytes(), rt); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rt.Bytes(), in) {
+		t.Errorf("for round trip, got:\n%s\nwanted:\n%s\n", rt.Bytes(), in)
+	}
+	if err := decryptAndRun("", encoding.JsonMediaType, schema.GroupVersion{}, config, nil, "/registry/pods/default/other", out.Bytes(), rt); err == nil {
+		t.Error("expected decrypting with a different key to fail")
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	pod := readTestFile(t, "testdata/storage/pod.bin")
+	cases := []struct {
+		name        string
+		inMediaType string
+		in          string
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			inMediaType: encoding.YamlMediaType,
+			in:          string(readTestFile(t, "testdata/yaml/pod.yaml")),
+		},
+		{
+			name:        "valid storage",
+			inMediaType: encoding.ProtobufMediaType,
+			in:          string(pod[:len(pod)-1]),
+		},
+		{
+			name:        "unknown and duplicate fields",
+			inMediaType: encoding.YamlMediaType,
+			in:          "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod\n  name: pod\nspec:\n  containers:\n  - name: web\n    imagee: nginx\n",
+			wantErr: `strict decoding error: yaml: unmarshal errors:
+  line 5: key "name" already set in map, unknown field "spec.containers[0].imagee"`,
+		},
+		{
+			name:        "unknown field in json",
+			inMediaType: encoding.JsonMediaType,
+			in:          `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod"},"spec":{"nodeNmae":"node"}}`,
+			wantErr:     `strict decoding error: unknown field "spec.nodeNmae"`,
+		},
+		{
+			name:        "not registered",
+			inMediaType: encoding.JsonMediaType,
+			in:          `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"widget"}}`,
+			wantErr:     "example.com/v1, Kind=Widget is not registered in the scheme",
+		},
+		{
+			name:        "not the latest version",
+			inMediaType: encoding.JsonMediaType,
+			in:          `{"apiVersion":"extensions/v1beta1","kind":"Deployment","metadata":{"name":"web"}}`,
+			wantErr:     "extensions/v1beta1, Kind=Deployment is not the latest version of the kind, apps/v1, Kind=Deployment, convert it with 'auger decode --output-version apps/v1' first",
+		},
+		{
+			name:        "no kind",
+			inMediaType: encoding.JsonMediaType,
+			in:          `{"metadata":{"name":"pod"}}`,
+			wantErr:     "apiVersion and kind must be set",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStrict(tt.inMediaType, []byte(tt.in))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("got error %v, want none", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("got error %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitObjects(t *testing.T) {
+	pod := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"a"}}`
+	cases := []struct {
+		name     string
+		in       string
+		want     []string
+		multiple bool
+	}{
+		{
+			name: "single object",
+			in:   "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\n",
+			want: []string{pod},
+		},
+		{
+			name:     "yaml documents",
+			in:       "---\napiVersion: v1\nkind: Pod\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: Pod\nmetadata:\n  name: b\n",
+			want:     []string{pod, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"b"}}`},
+			multiple: true,
+		},
+		{
+			name:     "concatenated json",
+			in:       pod + "\n" + pod,
+			want:     []string{pod, pod},
+			multiple: true,
+		},
+		{
+			name:     "json array",
+			in:       "[" + pod + "," + pod + "]",
+			want:     []string{pod, pod},
+			multiple: true,
+		},
+		{
+			name:     "list",
+			in:       `{"apiVersion":"v1","kind":"List","items":[` + pod + `]}`,
+			want:     []string{pod},
+			multiple: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, multiple, err := splitObjects([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, obj := range objects {
+				got = append(got, string(obj))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got objects %v, want %v", got, tt.want)
+			}
+			if multiple != tt.multiple {
+				t.Errorf("got multiple %v, want %v", multiple, tt.multiple)
+			}
+		})
+	}
+}
+
+func TestEncodeObjects(t *testing.T) {
+	pod := readTestFile(t, "testdata/yaml/pod.yaml")
+	job := readTestFile(t, "testdata/yaml/job.yaml")
+	objects, _, err := splitObjects(append(append(bytes.Clone(pod), "---\n"...), job...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := &framing{lengthPrefixed: true}
+	out := new(bytes.Buffer)
+	if err := encodeObjects(objects, encoding.StorageBinaryMediaType, false, nil, "/registry", frames, out); err != nil {
+		t.Fatal(err)
+	}
+	stream := bytes.Clone(out.Bytes())
+
+	var keys []string
+	i := 0
+	if err := frames.readFrames(out, func(value []byte) error {
+		if i%2 == 0 {
+			keys = append(keys, string(value))
+		}
+		i++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/registry/pods/default/pi-dqtsw", "/registry/jobs/default/pi"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+
+	rt := new(bytes.Buffer)
+	if err := decodeStream("", encoding.YamlMediaType, schema.GroupVersion{}, nil, nil, "", true, frames, bytes.NewReader(stream), rt); err != nil {
+		t.Fatal(err)
+	}
+	if wantYaml := string(pod) + "---\n" + string(job); rt.String() != wantYaml {
+		t.Errorf("for round trip, got:\n%s\nwanted:\n%s\n", rt.String(), wantYaml)
+	}
+}
+
+func TestEncodeObjectsEncrypted(t *testing.T) {
+	config, err := loadEncryptionConfig("testdata/encryption/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := readTestFile(t, "testdata/json/pod.json")
+	frames := &framing{delimiter: []byte("\n---\n")}
+	out := new(bytes.Buffer)
+	if err := encodeObjects([][]byte{pod}, encoding.StorageBinaryMediaType, false, config, "/registry", frames, out); err != nil {
+		t.Fatal(err)
+	}
+	rt := new(bytes.Buffer)
+	if err := decodeStream("", encoding.JsonMediaType, schema.GroupVersion{}, config, nil, "", true, frames, out, rt); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rt.Bytes(), pod) {
+		t.Errorf("for round trip, got:\n%s\nwanted:\n%s\n", rt.Bytes(), pod)
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	cases := []struct {
+		obj     string
+		want    string
+		wantErr string
+	}{
+		{obj: `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/pods/default/a"},
+		{obj: `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"a"}}`, wantErr: "error computing the key of Pod a: namespace is empty"},
+		{obj: `{"apiVersion":"v1","kind":"Endpoints","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/services/endpoints/default/a"},
+		{obj: `{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/ingress/default/a"},
+		{obj: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/networkpolicies/default/a"},
+		{obj: `{"apiVersion":"v1","kind":"Node","metadata":{"name":"a"}}`, want: "/registry/minions/a"},
+		{obj: `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/clusterroles/a"},
+		{obj: `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"a","namespace":"default"}}`, want: "/registry/example.com/widgets/default/a"},
+		{obj: `{"apiVersion":"v1","kind":"Pod","metadata":{"namespace":"default"}}`, wantErr: "error computing the key of Pod : name is empty"},
+	}
+	for _, tt := range cases {
+		got, err := objectKey("/registry", []byte(tt.obj))
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got %q, %v for %s, want error %s", got, err, tt.obj, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("got %q, %v for %s, want %s", got, err, tt.obj, tt.want)
+		}
+	}
+}
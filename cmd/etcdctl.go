@@ -0,0 +1,147 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// fromValue is a single value, as printed by 'etcdctl get --print-value-only'.
+	fromValue = "value"
+	// fromEtcdctlJSON is a range response, as printed by 'etcdctl get -w json'.
+	fromEtcdctlJSON = "etcdctl-json"
+	// fromEtcdctlProtobuf is a range response, as printed by 'etcdctl get -w protobuf'.
+	fromEtcdctlProtobuf = "etcdctl-protobuf"
+)
+
+// decodedKeyValue is a key-value of an etcd range response with its decoded value. Values that can't be
+// decoded have an error instead.
+type decodedKeyValue struct {
+	Key            string          `json:"key"`
+	CreateRevision int64           `json:"create_revision"`
+	ModRevision    int64           `json:"mod_revision"`
+	Version        int64           `json:"version"`
+	Lease          int64           `json:"lease"`
+	Value          json.RawMessage `json:"value,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// parseRangeResponse parses the output of 'etcdctl get' in the given format.
+func parseRangeResponse(from string, in []byte) (*etcdserverpb.RangeResponse, error) {
+	resp := &etcdserverpb.RangeResponse{}
+	switch from {
+	case fromEtcdctlJSON:
+		// etcdctl marshals the response with encoding/json, keys and values are base64 encoded.
+		if err := json.Unmarshal(in, resp); err != nil {
+			return nil, fmt.Errorf("error parsing etcdctl JSON output: %w", err)
+		}
+	case fromEtcdctlProtobuf:
+		if err := resp.Unmarshal(in); err != nil {
+			return nil, fmt.Errorf("error parsing etcdctl protobuf output: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized 'from' flag value: %v", from)
+	}
+	return resp, nil
+}
+
+// decodeRangeResponse decodes each value of the output of 'etcdctl get' and prints it with its key and
+// etcd metadata, as a YAML document or a line of JSON.
+func decodeRangeResponse(from string, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, in []byte, out io.Writer) error {
+	if outMediaType != encoding.YamlMediaType && outMediaType != encoding.JsonMediaType {
+		return fmt.Errorf("--from %s only supports yaml and json output", from)
+	}
+	resp, err := parseRangeResponse(from, in)
+	if err != nil {
+		return err
+	}
+	for i, kv := range resp.Kvs {
+		decoded := decodedKeyValue{
+			Key:            string(kv.Key),
+			CreateRevision: kv.CreateRevision,
+			ModRevision:    kv.ModRevision,
+			Version:        kv.Version,
+			Lease:          kv.Lease,
+		}
+		value, err := decodeToJSON(outVersion, config, redactor, string(kv.Key), kv.Value)
+		if err != nil {
+			decoded.Error = err.Error()
+		} else {
+			decoded.Value = value
+		}
+
+		buf, err := json.Marshal(decoded)
+		if err != nil {
+			return err
+		}
+		if outMediaType == encoding.YamlMediaType {
+			if buf, err = yaml.JSONToYAML(buf); err != nil {
+				return err
+			}
+			if i > 0 {
+				buf = append([]byte("---\n"), buf...)
+			}
+		} else {
+			buf = append(buf, '\n')
+		}
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeToJSON decrypts and decodes an etcd value to JSON, converted to outVersion unless it is empty, and
+// masks its sensitive values unless redactor is nil.
+func decodeToJSON(outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, key string, value []byte) (json.RawMessage, error) {
+	in, err := config.Decrypt(key, value)
+	if err != nil {
+		return nil, err
+	}
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		return nil, err
+	}
+	return convertToJSON(inMediaType, outVersion, redactor, in)
+}
+
+// convertToJSON converts the input to JSON, and to outVersion unless it is empty, and masks its sensitive
+// values unless redactor is nil. Unlike convert, objects whose kind is unknown are an error rather than
+// dumped as raw protobuf.
+func convertToJSON(inMediaType string, outVersion schema.GroupVersion, redactor *redact.Redactor, in []byte) (json.RawMessage, error) {
+	var buf []byte
+	var err error
+	if !outVersion.Empty() {
+		buf, err = convertToVersion(inMediaType, encoding.JsonMediaType, outVersion, in, io.Discard)
+	} else {
+		buf, _, err = decoder.Convert(inMediaType, encoding.JsonMediaType, in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return redactor.Redact(encoding.JsonMediaType, buf)
+}
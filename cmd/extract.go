@@ -22,14 +22,19 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 
+	"github.com/etcd-io/auger/pkg/crd"
 	"github.com/etcd-io/auger/pkg/data"
 	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/redact"
 	"github.com/etcd-io/auger/pkg/scheme"
 	"github.com/google/safetext/yamltemplate"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
 )
@@ -43,7 +48,27 @@ extract specific data entries. Data may be looked up either by etcd
 key and version, or by bolt page and item coordinates.
 
 Etcd must stopped when using this tool, or it will wait indefinitely
-for the '.db' file lock.`
+for the '.db' file lock.
+
+With --output-version, objects are converted to another version of
+their kind, as with 'auger decode --output-version'.
+
+Values encrypted at rest by kube-apiserver are decrypted using the
+providers of the EncryptionConfiguration file given by
+--encryption-config.
+
+With --crd-schemas, custom resources are checked against the structural
+schemas of the CustomResourceDefinitions stored in the same boltdb
+file. Validation errors, fields kube-apiserver prunes, and custom
+resources stored at a version that is no longer served or is not the
+storage version of their CustomResourceDefinition are reported. When
+extracting a single key the custom resource is pretty-printed and the
+problems are written to stderr, otherwise the problems of all custom
+resources are listed. --crd-schemas may not be combined with --fields or
+--template.
+
+With --redact, sensitive values are masked as with 'auger decode
+--redact', also in the values printed by --fields and --template.`
 
 	extractExample = `
         # Find an etcd value by it's key and extract it from a boltdb file:
@@ -58,6 +83,12 @@ for the '.db' file lock.`
         # Extract kubernetes objects using a filter
         auger extract -f <boltdb-file> --filter=".Value.metadata.namespace=kube-system"
 
+        # List the problems of all custom resources:
+        auger extract -f <boltdb-file> --crd-schemas
+
+        # Find a secret encrypted at rest and decrypt it:
+        auger extract -f <boltdb-file> -k /registry/secrets/default/<secret-name> --encryption-config <config-file>
+
         # Extract the etcd value stored in page 10, item 0 of a boltdb file:
         bolt page --item 0 --value-only <boltdb-file> 10 | auger extract --leaf-item
 
@@ -78,6 +109,7 @@ var extractCmd = &cobra.Command{
 
 type extractOptions struct {
 	out          string
+	outVersion   string
 	filename     string
 	key          string
 	version      string
@@ -87,17 +119,25 @@ type extractOptions struct {
 	leafItem     bool
 	printKey     bool
 	metaSummary  bool
+	metaFormat   string
 	raw          bool
 	fields       string
 	template     string
 	filter       string
+	crdSchemas   bool
+	redact       bool
+	redactPaths  []string
+	parallelism  int
+
+	encryptionConfig string
 }
 
 var opts = &extractOptions{}
 
 func init() {
 	RootCmd.AddCommand(extractCmd)
-	extractCmd.Flags().StringVarP(&opts.out, "output", "o", "yaml", "Output format. One of: json|yaml|proto")
+	extractCmd.Flags().StringVarP(&opts.out, "output", "o", "yaml", "Output format. One of: json|yaml|proto|proto-raw|cbor")
+	extractCmd.Flags().StringVar(&opts.outVersion, "output-version", "", "Convert objects to this <group>/<version> of their kind, e.g. apps/v1, instead of printing the stored version")
 	extractCmd.Flags().StringVarP(&opts.filename, "file", "f", "", "Bolt DB '.db' filename")
 	extractCmd.Flags().StringVarP(&opts.key, "key", "k", "", "Etcd object key to find in boltdb file")
 	extractCmd.Flags().StringVarP(&opts.version, "version", "v", "", "Version of etcd key to find, defaults to latest version")
@@ -107,9 +147,15 @@ func init() {
 	extractCmd.Flags().BoolVar(&opts.leafItem, "leaf-item", false, "Read the input as a boltdb leaf page item.")
 	extractCmd.Flags().BoolVar(&opts.printKey, "print-key", false, "Print the key of the matching entry")
 	extractCmd.Flags().BoolVar(&opts.metaSummary, "meta-summary", false, "Print a summary of the metadata of the matching entry")
+	extractCmd.Flags().StringVar(&opts.metaFormat, "meta-format", metaFormatText, "Format of --meta-summary output. One of: text|yaml|json")
 	extractCmd.Flags().BoolVar(&opts.raw, "raw", false, "Don't attempt to decode the etcd value")
 	extractCmd.Flags().StringVar(&opts.fields, "fields", Key, fmt.Sprintf("Fields to include when listing entries, comma separated list of: %v", SummaryFields))
 	extractCmd.Flags().StringVar(&opts.template, "template", "", fmt.Sprintf("golang template to use when listing entries, see https://golang.org/pkg/text/template, template is provided an object with the fields: %v. The Value field contains the entire kubernetes resource object which also may be dereferenced using a dot seperated path.", templateFields()))
+	extractCmd.Flags().StringVar(&opts.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+	extractCmd.Flags().BoolVar(&opts.crdSchemas, "crd-schemas", false, "Check custom resources against the schemas of the CustomResourceDefinitions in the boltdb file")
+	extractCmd.Flags().BoolVar(&opts.redact, "redact", false, "Mask the data of Secrets and kubeconfigs of ConfigMaps, keeping their keys and sizes")
+	extractCmd.Flags().StringArrayVar(&opts.redactPaths, "redact-path", nil, "Also mask the values at this dot separated path of all objects, * matches any field or item, implies --redact")
+	extractCmd.Flags().IntVar(&opts.parallelism, "parallelism", runtime.NumCPU(), "Number of workers decoding values when listing entries with --fields or --template, entries are written in key order")
 	extractCmd.Flags().StringVar(&opts.filter, "filter", "", "Filter entries using a comma separated list of '<field>=value' constraints. Fields used in filters use the same naming as --template fields, e.g. .Value.metadata.namespace")
 }
 
@@ -137,6 +183,10 @@ func extractValidateAndRun() error {
 	if err != nil {
 		return fmt.Errorf("invalid --output %s: %w", opts.out, err)
 	}
+	outVersion, err := parseOutputVersion(opts.outVersion)
+	if err != nil {
+		return err
+	}
 	out := os.Stdout
 	hasKey := opts.key != ""
 	hasVersion := opts.version != ""
@@ -144,6 +194,32 @@ func extractValidateAndRun() error {
 	hasFields := opts.fields != Key
 	hasTemplate := opts.template != ""
 
+	config, err := loadEncryptionConfig(opts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactor(opts.redact, opts.redactPaths, outMediaType)
+	if err != nil {
+		return err
+	}
+	if redactor != nil && opts.raw {
+		return errors.New("--redact may not be used with --raw")
+	}
+
+	var crds *crd.Registry
+	if opts.crdSchemas {
+		if opts.leafItem {
+			return errors.New("--crd-schemas may not be used with --leaf-item")
+		}
+		if hasFields || hasTemplate {
+			return errors.New("--crd-schemas may not be used with --fields or --template")
+		}
+		crds, err = crd.LoadFromDB(opts.filename, opts.revision, config)
+		if err != nil {
+			return fmt.Errorf("failed to load CustomResourceDefinitions: %w", err)
+		}
+	}
+
 	switch {
 	case opts.leafItem:
 		raw, err := readInput(opts.filename)
@@ -155,49 +231,64 @@ func extractValidateAndRun() error {
 			return fmt.Errorf("failed to extract etcd key-value record from boltdb leaf item: %w", err)
 		}
 		if opts.metaSummary {
-			return printLeafItemSummary(kv, out)
+			return printLeafItemSummary(kv, opts.metaFormat, config, out)
 		} else if opts.printKey {
 			return printLeafItemKey(kv, out)
 		}
-		return printLeafItemValue(kv, outMediaType, out)
+		return printLeafItemValue(kv, outMediaType, outVersion, config, redactor, out)
 	case hasKey && hasKeyPrefix:
 		return errors.New("--keys-by-prefix and --key may not be used together")
 	case hasKey && opts.listVersions:
 		return printVersions(opts.filename, opts.key, out)
 	case hasKey:
-		return printValue(opts.filename, opts.key, opts.version, opts.raw, outMediaType, out)
+		return printValue(opts.filename, opts.key, opts.version, opts.raw, outMediaType, outVersion, config, redactor, crds, out)
 	case !hasKey && opts.listVersions:
 		return errors.New("--list-versions may only be used with --key")
 	case !hasKey && hasVersion:
 		return errors.New("--version may only be used with --key")
+	case opts.crdSchemas:
+		return printCustomResourceProblems(opts.filename, opts.keyPrefix, opts.revision, config, crds, out)
 	case hasTemplate && hasFields:
 		return errors.New("--template and --fields may not be used together")
 	case hasTemplate:
-		return printTemplateSummaries(opts.filename, opts.keyPrefix, opts.revision, opts.template, opts.filter, out)
+		return printTemplateSummaries(opts.filename, opts.keyPrefix, opts.revision, opts.template, opts.filter, config, redactor, opts.parallelism, out)
 	default:
 		fields := strings.Split(opts.fields, ",")
-		return printKeySummaries(opts.filename, opts.keyPrefix, opts.revision, fields, out)
+		return printKeySummaries(opts.filename, opts.keyPrefix, opts.revision, fields, config, redactor, opts.parallelism, out)
 	}
 }
 
 // printVersions writes all versions of the given key.
 func printVersions(filename string, key string, out io.Writer) error {
-	versions, err := data.ListVersions(filename, key)
+	s, err := data.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	versions, err := s.History(key)
 	if err != nil {
 		return err
 	}
+	warnIndexError(s, os.Stderr)
 	for _, v := range versions {
 		fmt.Fprintf(out, "%d\n", v)
 	}
 	return nil
 }
 
-// printValue writes the value, in the desired media type, of the given key version.
-func printValue(filename string, key string, version string, raw bool, outMediaType string, out io.Writer) error {
+// printValue writes the value, in the desired media type, of the given key version. If crds is set and
+// the value is a custom resource, the problems found by checking it against its schema are written to
+// stderr and it is pretty-printed. Unless redactor is nil, the sensitive values of the object are masked.
+func printValue(filename string, key string, version string, raw bool, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, crds *crd.Registry, out io.Writer) error {
+	s, err := data.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
 	var v int64
-	var err error
 	if version == "" {
-		versions, err := data.ListVersions(filename, key)
+		versions, err := s.History(key)
 		if err != nil {
 			return err
 		}
@@ -212,10 +303,11 @@ func printValue(filename string, key string, version string, raw bool, outMediaT
 			return fmt.Errorf("version must be an int64, but got %s: %w", version, err)
 		}
 	}
-	in, err := data.GetValue(filename, key, v)
+	in, err := s.Get(key, v)
 	if err != nil {
 		return err
 	}
+	warnIndexError(s, os.Stderr)
 	if len(in) == 0 {
 		return errors.New("0 byte value")
 	}
@@ -223,7 +315,37 @@ func printValue(filename string, key string, version string, raw bool, outMediaT
 		fmt.Fprintf(out, "%s\n", string(in))
 		return nil
 	}
-	buf, _, err := encoding.DetectAndConvert(scheme.Codecs, outMediaType, in)
+	in, err = config.Decrypt(key, in)
+	if err != nil {
+		return err
+	}
+	if crds != nil && outVersion.Empty() {
+		result, err := crds.Check(in)
+		if err != nil {
+			return err
+		}
+		if result != nil {
+			for _, warning := range result.Warnings() {
+				fmt.Fprintf(os.Stderr, "warn: %s: %s\n", key, warning)
+			}
+			if outMediaType == encoding.JsonMediaType || outMediaType == encoding.YamlMediaType {
+				buf, err := result.Encode(outMediaType)
+				if err != nil {
+					return err
+				}
+				if buf, err = redactor.Redact(outMediaType, buf); err != nil {
+					return err
+				}
+				_, err = out.Write(buf)
+				return err
+			}
+		}
+	}
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		return err
+	}
+	buf, err := convert(inMediaType, outMediaType, outVersion, redactor, in, os.Stderr)
 	if err != nil {
 		return err
 	}
@@ -231,25 +353,53 @@ func printValue(filename string, key string, version string, raw bool, outMediaT
 	return err
 }
 
+// printCustomResourceProblems checks the latest version of each custom resource with the given key prefix
+// against the schema of its CustomResourceDefinition and prints the problems found, one per line.
+func printCustomResourceProblems(filename string, keyPrefix string, revision int64, config *encryption.Config, crds *crd.Registry, out io.Writer) error {
+	kvs, err := data.ListValues(filename, keyPrefix, revision)
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		value, err := config.Decrypt(string(kv.Key), kv.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", kv.Key, err)
+		}
+		// Values that can't be decoded, e.g. etcd's own keys, are not custom resources.
+		result, err := crds.Check(value)
+		if err != nil || result == nil {
+			continue
+		}
+		for _, warning := range result.Warnings() {
+			fmt.Fprintf(out, "%s: %s\n", kv.Key, warning)
+		}
+	}
+	return nil
+}
+
 // printLeafItemKey prints an etcd key for a given boltdb leaf item.
 func printLeafItemKey(kv *mvccpb.KeyValue, out io.Writer) error {
 	fmt.Fprintf(out, "%s\n", kv.Key)
 	return nil
 }
 
-// printLeafItemSummary prints etcd metadata summary
-func printLeafItemSummary(kv *mvccpb.KeyValue, out io.Writer) error {
-	fmt.Fprintf(out, "Key: %s\n", kv.Key)
-	fmt.Fprintf(out, "Version: %d\n", kv.Version)
-	fmt.Fprintf(out, "CreateRevision: %d\n", kv.CreateRevision)
-	fmt.Fprintf(out, "ModRevision: %d\n", kv.ModRevision)
-	fmt.Fprintf(out, "Lease: %d\n", kv.Lease)
-	return nil
+// printLeafItemSummary prints the etcd metadata summary of a boltdb leaf item, followed by the summary of
+// its value.
+func printLeafItemSummary(kv *mvccpb.KeyValue, metaFormat string, config *encryption.Config, out io.Writer) error {
+	return writeSummary(metaFormat, summarizeKeyValue(kv, config), out)
 }
 
 // printLeafItemValue prints an etcd value for a given boltdb leaf item.
-func printLeafItemValue(kv *mvccpb.KeyValue, outMediaType string, out io.Writer) error {
-	buf, _, err := encoding.DetectAndConvert(scheme.Codecs, outMediaType, kv.Value)
+func printLeafItemValue(kv *mvccpb.KeyValue, outMediaType string, outVersion schema.GroupVersion, config *encryption.Config, redactor *redact.Redactor, out io.Writer) error {
+	in, err := config.Decrypt(string(kv.Key), kv.Value)
+	if err != nil {
+		return err
+	}
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		return err
+	}
+	buf, err := convert(inMediaType, outMediaType, outVersion, redactor, in, os.Stderr)
 	if err != nil {
 		return err
 	}
@@ -257,8 +407,9 @@ func printLeafItemValue(kv *mvccpb.KeyValue, outMediaType string, out io.Writer)
 	return err
 }
 
-// printKeySummaries prints all keys in the db file with the given key prefix.
-func printKeySummaries(filename string, keyPrefix string, revision int64, fields []string, out io.Writer) error {
+// printKeySummaries prints all keys in the db file with the given key prefix. Values are decoded by
+// parallelism workers.
+func printKeySummaries(filename string, keyPrefix string, revision int64, fields []string, config *encryption.Config, redactor *redact.Redactor, parallelism int, out io.Writer) error {
 	if len(fields) == 0 {
 		return errors.New("no fields provided, nothing to output")
 	}
@@ -274,23 +425,26 @@ func printKeySummaries(filename string, keyPrefix string, revision int64, fields
 		}
 	}
 	proj := &data.KeySummaryProjection{HasKey: hasKey, HasValue: hasValue}
-	summaries, err := data.ListKeySummaries(scheme.Codecs, filename, []data.Filter{data.NewPrefixFilter(keyPrefix)}, proj, revision)
+	store, err := openStore(filename, parallelism)
 	if err != nil {
 		return err
 	}
-	for _, s := range summaries {
+	defer store.Close()
+	return store.WalkKeySummaries(scheme.Codecs, []data.Filter{data.NewPrefixFilter(keyPrefix)}, proj, revision, config, func(s *data.KeySummary) error {
+		redactSummary(redactor, s)
 		summary, err := summarize(s, fields)
 		if err != nil {
 			return err
 		}
 		fmt.Fprintf(out, "%s\n", summary)
-	}
-	return nil
+		return nil
+	})
 }
 
 // printTemplateSummaries prints out each KeySummary according to the given golang template.
-// See https://golang.org/pkg/text/template for details on the template format.
-func printTemplateSummaries(filename string, keyPrefix string, revision int64, templatestr string, filterstr string, out io.Writer) error {
+// See https://golang.org/pkg/text/template for details on the template format. Values are decoded by
+// parallelism workers.
+func printTemplateSummaries(filename string, keyPrefix string, revision int64, templatestr string, filterstr string, config *encryption.Config, redactor *redact.Redactor, parallelism int, out io.Writer) error {
 	var err error
 	t, err := yamltemplate.New("template").Parse(templatestr)
 	if err != nil {
@@ -309,19 +463,41 @@ func printTemplateSummaries(filename string, keyPrefix string, revision int64, t
 		}
 	}
 
-	// We don't have a simple way to determine if the template uses the key or value or not
-	summaries, err := data.ListKeySummaries(scheme.Codecs, filename, append(filters, data.NewPrefixFilter(keyPrefix)), &data.KeySummaryProjection{HasKey: true, HasValue: true}, revision)
+	store, err := openStore(filename, parallelism)
 	if err != nil {
 		return err
 	}
-	for _, s := range summaries {
-		err := t.Execute(out, s)
-		if err != nil {
+	defer store.Close()
+
+	// We don't have a simple way to determine if the template uses the key or value or not
+	return store.WalkKeySummaries(scheme.Codecs, append(filters, data.NewPrefixFilter(keyPrefix)), &data.KeySummaryProjection{HasKey: true, HasValue: true}, revision, config, func(s *data.KeySummary) error {
+		redactSummary(redactor, s)
+		if err := t.Execute(out, s); err != nil {
 			return err
 		}
 		fmt.Fprintf(out, "\n")
+		return nil
+	})
+}
+
+// openStore opens the db file, with parallelism workers decoding its values.
+func openStore(filename string, parallelism int) (*data.Store, error) {
+	if parallelism < 1 {
+		return nil, fmt.Errorf("--parallelism must be at least 1, got %d", parallelism)
+	}
+	s, err := data.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	s.Parallelism = parallelism
+	return s, nil
+}
+
+// redactSummary masks the sensitive values of the object of a KeySummary, after it has been filtered.
+func redactSummary(redactor *redact.Redactor, s *data.KeySummary) {
+	if value, ok := s.Value.(map[string]any); ok {
+		redactor.Object(value)
 	}
-	return nil
 }
 
 func summarize(s *data.KeySummary, fields []string) (string, error) {
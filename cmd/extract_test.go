@@ -18,10 +18,17 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/binary"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/etcd-io/auger/pkg/crd"
 	"github.com/etcd-io/auger/pkg/encoding"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
@@ -31,7 +38,7 @@ const (
 
 func TestListKeys(t *testing.T) {
 	out := new(bytes.Buffer)
-	if err := printKeySummaries(dbFile, "", 0, []string{"key"}, out); err != nil {
+	if err := printKeySummaries(dbFile, "", 0, []string{"key"}, nil, nil, 1, out); err != nil {
 		t.Fatal(err)
 	}
 	assertMatchesFile(t, out, "testdata/boltdb/keys.txt")
@@ -39,12 +46,30 @@ func TestListKeys(t *testing.T) {
 
 func TestListKeySummaries(t *testing.T) {
 	out := new(bytes.Buffer)
-	if err := printKeySummaries(dbWithHistoryFile, "", 0, []string{"key", "version-count", "value-size", "all-versions-value-size"}, out); err != nil {
+	if err := printKeySummaries(dbWithHistoryFile, "", 0, []string{"key", "version-count", "value-size", "all-versions-value-size"}, nil, nil, 1, out); err != nil {
 		t.Fatal(err)
 	}
 	assertMatchesFile(t, out, "testdata/boltdb/keys-with-history.txt")
 }
 
+func TestListKeySummariesParallel(t *testing.T) {
+	template := "{{.Key}} {{.Version}} {{.Value.kind}}"
+	want := new(bytes.Buffer)
+	if err := printTemplateSummaries(dbFile, "", 0, template, "", nil, nil, 1, want); err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := printTemplateSummaries(dbFile, "", 0, template, "", nil, nil, 8, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+	if err := printTemplateSummaries(dbFile, "", 0, template, "", nil, nil, 0, out); err == nil {
+		t.Error("expected an error for --parallelism 0")
+	}
+}
+
 func TestListKeyVersions(t *testing.T) {
 	out := new(bytes.Buffer)
 	if err := printVersions(dbFile, "/registry/jobs/default/pi", out); err != nil {
@@ -58,7 +83,7 @@ func TestListKeyVersions(t *testing.T) {
 
 func TestExtractByKey(t *testing.T) {
 	out := new(bytes.Buffer)
-	if err := printValue(dbFile, "/registry/jobs/default/pi", "3", false, encoding.YamlMediaType, out); err != nil {
+	if err := printValue(dbFile, "/registry/jobs/default/pi", "3", false, encoding.YamlMediaType, schema.GroupVersion{}, nil, nil, nil, out); err != nil {
 		t.Fatal(err)
 	}
 	assertMatchesFile(t, out, "testdata/yaml/job.yaml")
@@ -76,18 +101,126 @@ func TestExtractKeyFromLeaf(t *testing.T) {
 func TestExtractSummaryFromLeaf(t *testing.T) {
 	kv := readTestFileAsKv(t, "testdata/boltdb/page2item1.bin")
 	out := new(bytes.Buffer)
-	if err := printLeafItemSummary(kv, out); err != nil {
+	if err := printLeafItemSummary(kv, metaFormatText, nil, out); err != nil {
 		t.Fatal(err)
 	}
 	assertMatchesFile(t, out, "testdata/boltdb/page2item1-summary.txt")
+
+	out = new(bytes.Buffer)
+	if err := printLeafItemSummary(kv, metaFormatYAML, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFile(t, out, "testdata/boltdb/page2item1-summary.yaml")
 }
 
 // TODO: run a permutation grid of tests
 func TestExtractValueFromLeaf(t *testing.T) {
 	kv := readTestFileAsKv(t, "testdata/boltdb/page2item1.bin")
 	out := new(bytes.Buffer)
-	if err := printLeafItemValue(kv, encoding.YamlMediaType, out); err != nil {
+	if err := printLeafItemValue(kv, encoding.YamlMediaType, schema.GroupVersion{}, nil, nil, out); err != nil {
 		t.Fatal(err)
 	}
 	assertMatchesFile(t, out, "testdata/yaml/pod.yaml")
 }
+
+func TestExtractCustomResourceProblems(t *testing.T) {
+	file := createCustomResourceDB(t)
+	crds, err := crd.LoadFromDB(file, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := printCustomResourceProblems(file, "/registry", 0, nil, crds, out); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFile(t, out, "testdata/crd/problems.txt")
+}
+
+func TestExtractCustomResourceProblemsFlags(t *testing.T) {
+	defaults := *opts
+	defer func() { *opts = defaults }()
+	for _, set := range []func(){
+		func() { opts.fields = "key,value-size" },
+		func() { opts.template = "{{.Key}}" },
+	} {
+		*opts = defaults
+		opts.filename = dbFile
+		opts.crdSchemas = true
+		set()
+		if err := extractValidateAndRun(); err == nil || err.Error() != "--crd-schemas may not be used with --fields or --template" {
+			t.Errorf("got error %v, want --crd-schemas rejected", err)
+		}
+	}
+}
+
+func TestExtractCustomResource(t *testing.T) {
+	file := createCustomResourceDB(t)
+	crds, err := crd.LoadFromDB(file, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := printValue(file, "/registry/example.com/widgets/default/widget-cbor", "", false, encoding.JsonMediaType, schema.GroupVersion{}, nil, nil, crds, out); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFile(t, out, "testdata/crd/widget.json")
+}
+
+// createCustomResourceDB writes a boltdb file holding the widgets CustomResourceDefinition, widgets stored as
+// JSON and CBOR, and a pod.
+func createCustomResourceDB(t *testing.T) string {
+	t.Helper()
+	widget := readTestFile(t, "testdata/json/widget.json")
+	widgetCbor := readTestFile(t, "testdata/cbor/widget.cbor")
+	return createTestDB(t, []testKeyValue{
+		{key: crd.KeyPrefix + "widgets.example.com", value: readTestFile(t, "testdata/json/widget-crd.json")},
+		{key: "/registry/example.com/widgets/default/widget", value: widget[:len(widget)-1]},
+		{key: "/registry/example.com/widgets/default/widget-cbor", value: widgetCbor[:len(widgetCbor)-1]},
+		{key: "/registry/example.com/widgets/default/widget-old", value: []byte(`{"apiVersion":"example.com/v1beta1","kind":"Widget","metadata":{"name":"widget-old","namespace":"default"},"spec":{"size":0}}`)},
+		{key: "/registry/example.com/widgets/default/widget-small", value: []byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"widget-small","namespace":"default"},"spec":{"size":0}}`)},
+		{key: "/registry/pods/default/pod", value: readTestFile(t, "testdata/storage/pod.bin")},
+	})
+}
+
+type testKeyValue struct {
+	key   string
+	value []byte
+}
+
+// createTestDB writes a boltdb file holding the given key-values, each created at its own revision.
+func createTestDB(t *testing.T, kvs []testKeyValue) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "db")
+	db, err := bolt.Open(file, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket([]byte("meta")); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for i, kv := range kvs {
+			rev := int64(i + 1)
+			revBytes := make([]byte, 17)
+			binary.BigEndian.PutUint64(revBytes, uint64(rev))
+			revBytes[8] = '_'
+			v, err := (&mvccpb.KeyValue{Key: []byte(kv.key), Value: kv.value, CreateRevision: rev, ModRevision: rev, Version: 1}).Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(revBytes, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
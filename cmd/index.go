@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexLong = `
+Writes a key revision index of a boltdb '.db' file next to it, as
+<file>.index.
+
+etcd stores the revisions of all keys in revision order, so looking up a
+key requires scanning all revisions of all keys. The index maps each key to
+its revisions, versions and deletions, so that 'extract --key' and
+'extract --list-versions' read only the revisions they need. It holds no
+values or value offsets: values are read from the file by their revision,
+which boltdb looks up without scanning.
+
+The index is only used as long as the size, modification time and
+consistent index of the file are those it was built from. Run the command
+again once the file has changed.`
+
+	indexExample = `
+        # Index a boltdb file, then look up keys in it:
+        auger index -f <boltdb-file>
+        auger extract -f <boltdb-file> -k /registry/pods/default/<pod-name>
+`
+)
+
+var indexCmd = &cobra.Command{
+	Use:     "index",
+	Short:   "Writes an index of the revisions of the keys of a boltdb '.db' file next to it.",
+	Long:    indexLong,
+	Example: indexExample,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return indexValidateAndRun()
+	},
+}
+
+type indexOptions struct {
+	filename string
+}
+
+var indexOpts = &indexOptions{}
+
+func init() {
+	RootCmd.AddCommand(indexCmd)
+	indexCmd.Flags().StringVarP(&indexOpts.filename, "file", "f", "", "Bolt DB '.db' filename")
+}
+
+func indexValidateAndRun() error {
+	if indexOpts.filename == "" {
+		return errors.New("--file is required")
+	}
+	return writeIndex(indexOpts.filename, os.Stdout)
+}
+
+// writeIndex writes the key revision index of the boltdb file next to it.
+func writeIndex(filename string, out io.Writer) error {
+	keys, revisions, err := data.WriteIndex(filename)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s: %d keys, %d revisions\n", data.IndexFilename(filename), keys, revisions)
+	return nil
+}
+
+// warnIndexError warns if the index file of a store was not used to look up keys.
+func warnIndexError(s *data.Store, errOut io.Writer) {
+	if err := s.IndexError(); err != nil {
+		fmt.Fprintf(errOut, "warn: %v, rebuild it with 'auger index' to look up keys without scanning all revisions\n", err)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIndex(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db")
+	if err := os.WriteFile(file, readTestFile(t, dbFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := writeIndex(file, out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "wrote " + file + ".index: 4 keys, 4 revisions\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	out.Reset()
+	if err := printVersions(file, "/registry/jobs/default/pi", out); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(out.String()); s != "3" {
+		t.Errorf("got %s, want 3", s)
+	}
+	out.Reset()
+	if err := printValue(file, "/registry/jobs/default/pi", "", false, encoding.YamlMediaType, schema.GroupVersion{}, nil, nil, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFile(t, out, "testdata/yaml/job.yaml")
+}
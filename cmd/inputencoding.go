@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"unicode"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+)
+
+const (
+	// inputEncodingAuto detects the encoding of the input.
+	inputEncodingAuto = "auto"
+	// inputEncodingRaw is the value as stored in etcd, as printed by 'etcdctl get --print-value-only'.
+	inputEncodingRaw       = "raw"
+	inputEncodingHex       = "hex"
+	inputEncodingBase64    = "base64"
+	inputEncodingBase64URL = "base64url"
+	// inputEncodingQuoted is a Go quoted string, as printed by the %q verb.
+	inputEncodingQuoted = "quoted"
+)
+
+// detectedInputEncodings are the encodings tried, in order, when detecting the encoding of the input. Hex
+// comes first, hex strings are valid base64 as well.
+var detectedInputEncodings = []string{inputEncodingQuoted, inputEncodingHex, inputEncodingBase64, inputEncodingBase64URL}
+
+// unwrapInput decodes a value that was copied as text, e.g. from logs, bug reports or the etcd gRPC
+// gateway, and returns it with the encoding it was decoded from. With inputEncodingAuto, the first
+// encoding that decodes the input to a stored value is used. Input that is a stored value already, or that
+// no encoding decodes to one, is returned as raw.
+func unwrapInput(inputEncoding string, in []byte) ([]byte, string, error) {
+	if inputEncoding != inputEncodingAuto && inputEncoding != inputEncodingRaw && !slices.Contains(detectedInputEncodings, inputEncoding) {
+		return nil, "", fmt.Errorf("unrecognized 'input-encoding' flag value: %v", inputEncoding)
+	}
+	if inputEncoding != inputEncodingAuto {
+		out, err := decodeInput(inputEncoding, in)
+		if err != nil {
+			return nil, "", fmt.Errorf("error decoding %s input: %w", inputEncoding, err)
+		}
+		return out, inputEncoding, nil
+	}
+	if isStoredValue(in) {
+		return in, inputEncodingRaw, nil
+	}
+	for _, inputEncoding := range detectedInputEncodings {
+		out, err := decodeInput(inputEncoding, in)
+		if err == nil && isStoredValue(out) {
+			return out, inputEncoding, nil
+		}
+	}
+	return in, inputEncodingRaw, nil
+}
+
+// decodeInput decodes the input from the given encoding. Hex and base64 input may be wrapped across lines.
+func decodeInput(inputEncoding string, in []byte) ([]byte, error) {
+	switch inputEncoding {
+	case inputEncodingRaw:
+		return in, nil
+	case inputEncodingHex:
+		return hex.DecodeString(string(removeSpace(in)))
+	case inputEncodingBase64:
+		return base64.StdEncoding.DecodeString(string(removeSpace(in)))
+	case inputEncodingBase64URL:
+		return base64.URLEncoding.DecodeString(string(removeSpace(in)))
+	case inputEncodingQuoted:
+		s, err := strconv.Unquote(string(bytes.TrimSpace(in)))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	default:
+		return nil, fmt.Errorf("unrecognized 'input-encoding' flag value: %v", inputEncoding)
+	}
+}
+
+// isStoredValue reports whether the input looks like a value kube-apiserver stores in etcd.
+func isStoredValue(in []byte) bool {
+	if encryption.IsEncrypted(in) {
+		return true
+	}
+	_, _, err := encoding.DetectAndExtract(in)
+	return err == nil
+}
+
+func removeSpace(in []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, in)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnwrapInput(t *testing.T) {
+	pod := readTestFile(t, "testdata/storage/pod.bin")
+	pod = pod[:len(pod)-1]
+	encrypted := readTestFile(t, "testdata/storage/pod-aescbc.bin")
+	encrypted = encrypted[:len(encrypted)-1]
+	json := readTestFile(t, "testdata/json/pod.json")
+
+	// Wrapped at 76 characters, like the output of base64.
+	var wrapped strings.Builder
+	for s := base64.StdEncoding.EncodeToString(pod); len(s) > 0; {
+		n := min(76, len(s))
+		wrapped.WriteString(s[:n] + "\n")
+		s = s[n:]
+	}
+
+	cases := []struct {
+		name          string
+		inputEncoding string
+		in            string
+		want          []byte
+		wantEncoding  string
+	}{
+		{name: "raw", inputEncoding: inputEncodingAuto, in: string(pod), want: pod, wantEncoding: inputEncodingRaw},
+		{name: "json", inputEncoding: inputEncodingAuto, in: string(json), want: json, wantEncoding: inputEncodingRaw},
+		{name: "hex", inputEncoding: inputEncodingAuto, in: hex.EncodeToString(pod), want: pod, wantEncoding: inputEncodingHex},
+		{name: "base64", inputEncoding: inputEncodingAuto, in: base64.StdEncoding.EncodeToString(pod), want: pod, wantEncoding: inputEncodingBase64},
+		{name: "wrapped base64", inputEncoding: inputEncodingAuto, in: wrapped.String(), want: pod, wantEncoding: inputEncodingBase64},
+		{name: "base64url", inputEncoding: inputEncodingAuto, in: base64.URLEncoding.EncodeToString(pod), want: pod, wantEncoding: inputEncodingBase64URL},
+		{name: "quoted", inputEncoding: inputEncodingAuto, in: strconv.Quote(string(pod)) + "\n", want: pod, wantEncoding: inputEncodingQuoted},
+		{name: "encrypted base64", inputEncoding: inputEncodingAuto, in: base64.StdEncoding.EncodeToString(encrypted), want: encrypted, wantEncoding: inputEncodingBase64},
+		{name: "unknown", inputEncoding: inputEncodingAuto, in: "not a value", want: []byte("not a value"), wantEncoding: inputEncodingRaw},
+		{name: "explicit", inputEncoding: inputEncodingHex, in: hex.EncodeToString([]byte("abc")), want: []byte("abc"), wantEncoding: inputEncodingHex},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotEncoding, err := unwrapInput(tt.inputEncoding, []byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) || gotEncoding != tt.wantEncoding {
+				t.Errorf("got %s input %q, want %s input %q", gotEncoding, got, tt.wantEncoding, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapInputErrors(t *testing.T) {
+	if _, _, err := unwrapInput(inputEncodingHex, []byte("zz")); err == nil || err.Error() != "error decoding hex input: encoding/hex: invalid byte: U+007A 'z'" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, _, err := unwrapInput("base32", []byte("aa")); err == nil || err.Error() != "unrecognized 'input-encoding' flag value: base32" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
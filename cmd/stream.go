@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// framing separates the values of a stream, either by a delimiter or by prefixing each value with its
+// length as a 4 byte big-endian integer, the format of k8s.io/apimachinery/pkg/util/framer.
+type framing struct {
+	delimiter      []byte
+	lengthPrefixed bool
+}
+
+// newFraming returns the framing selected by the --delimiter and --length-prefixed flags, or nil if
+// neither is set. The delimiter may contain Go escape sequences, e.g. '\n' or '\x00'.
+func newFraming(delimiter string, lengthPrefixed bool) (*framing, error) {
+	switch {
+	case delimiter != "" && lengthPrefixed:
+		return nil, errors.New("only one of --delimiter and --length-prefixed may be set")
+	case lengthPrefixed:
+		return &framing{lengthPrefixed: true}, nil
+	case delimiter != "":
+		d, err := strconv.Unquote(`"` + delimiter + `"`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --delimiter %s: %w", delimiter, err)
+		}
+		return &framing{delimiter: []byte(d)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// readFrames calls f with each value of the stream. Empty values are skipped.
+func (fr *framing) readFrames(in io.Reader, f func(value []byte) error) error {
+	if fr.lengthPrefixed {
+		return readLengthPrefixed(in, f)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, math.MaxInt32)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, fr.delimiter); i >= 0 {
+			return i + len(fr.delimiter), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := f(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading stream: %w", err)
+	}
+	return nil
+}
+
+func readLengthPrefixed(in io.Reader, f func(value []byte) error) error {
+	r := bufio.NewReader(in)
+	var length [4]byte
+	for {
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("error reading length of frame: %w", err)
+		}
+		value := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(r, value); err != nil {
+			return fmt.Errorf("error reading frame of %d bytes: %w", len(value), err)
+		}
+		if len(value) == 0 {
+			continue
+		}
+		if err := f(value); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFrame writes a value to the stream.
+func (fr *framing) writeFrame(out io.Writer, value []byte) error {
+	if fr.lengthPrefixed {
+		if len(value) > math.MaxUint32 {
+			return fmt.Errorf("value of %d bytes is too large for a frame", len(value))
+		}
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(value)))
+		if _, err := out.Write(length[:]); err != nil {
+			return err
+		}
+		_, err := out.Write(value)
+		return err
+	}
+	if _, err := out.Write(value); err != nil {
+		return err
+	}
+	_, err := out.Write(fr.delimiter)
+	return err
+}
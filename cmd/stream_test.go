@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFraming(t *testing.T) {
+	values := [][]byte{[]byte("k8s\x00\n\x0bfirst"), []byte("second"), []byte("third\n")}
+	cases := []struct {
+		name           string
+		delimiter      string
+		lengthPrefixed bool
+		want           string
+	}{
+		{
+			name:      "delimiter",
+			delimiter: `\r\n`,
+			want:      "k8s\x00\n\x0bfirst\r\nsecond\r\nthird\n\r\n",
+		},
+		{
+			name:           "length prefixed",
+			lengthPrefixed: true,
+			want:           "\x00\x00\x00\x0bk8s\x00\n\x0bfirst\x00\x00\x00\x06second\x00\x00\x00\x06third\n",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := newFraming(tt.delimiter, tt.lengthPrefixed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := new(bytes.Buffer)
+			for _, v := range values {
+				if err := frames.writeFrame(out, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if out.String() != tt.want {
+				t.Errorf("got stream %q, want %q", out.String(), tt.want)
+			}
+			var got [][]byte
+			if err := frames.readFrames(out, func(value []byte) error {
+				got = append(got, bytes.Clone(value))
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, values) {
+				t.Errorf("got values %q, want %q", got, values)
+			}
+		})
+	}
+}
+
+func TestFramingErrors(t *testing.T) {
+	if _, err := newFraming(`\n`, true); err == nil {
+		t.Error("expected an error when both a delimiter and length prefixes are set")
+	}
+	frames, err := newFraming("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = frames.readFrames(bytes.NewReader([]byte("\x00\x00\x00\x0ashort")), func([]byte) error { return nil })
+	if err == nil {
+		t.Error("expected an error for a truncated frame")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	metaFormatText = "text"
+	metaFormatYAML = "yaml"
+	metaFormatJSON = "json"
+)
+
+// metaSummary is the summary of a stored value: the envelope of its encryption at rest, if encrypted,
+// and the metadata of its object, unless it can't be decrypted.
+type metaSummary struct {
+	Encryption *encryption.Summary `json:"encryption,omitempty"`
+	*encoding.Summary
+}
+
+func (s *metaSummary) writeText(out io.Writer) {
+	if s.Encryption != nil {
+		s.Encryption.WriteText(out)
+	}
+	if s.Summary != nil {
+		s.Summary.WriteText(out)
+	}
+}
+
+// leafItemSummary is the summary of an etcd key-value. Values that can't be summarized have an error
+// instead.
+type leafItemSummary struct {
+	Key            string `json:"key"`
+	Version        int64  `json:"version"`
+	CreateRevision int64  `json:"createRevision"`
+	ModRevision    int64  `json:"modRevision"`
+	Lease          int64  `json:"lease"`
+	*metaSummary
+	Error string `json:"error,omitempty"`
+}
+
+func (s *leafItemSummary) writeText(out io.Writer) {
+	fmt.Fprintf(out, "Key: %s\n", s.Key)
+	fmt.Fprintf(out, "Version: %d\n", s.Version)
+	fmt.Fprintf(out, "CreateRevision: %d\n", s.CreateRevision)
+	fmt.Fprintf(out, "ModRevision: %d\n", s.ModRevision)
+	fmt.Fprintf(out, "Lease: %d\n", s.Lease)
+	if s.metaSummary != nil {
+		s.metaSummary.writeText(out)
+	}
+	if s.Error != "" {
+		fmt.Fprintf(out, "Error: %s\n", s.Error)
+	}
+}
+
+// summarizeValue reads the summary of a value stored at key. Encrypted values are decrypted with config, and
+// only summarized by their envelope if config is nil.
+func summarizeValue(config *encryption.Config, key string, in []byte) (*metaSummary, error) {
+	summary := &metaSummary{}
+	if encryption.IsEncrypted(in) {
+		var err error
+		if summary.Encryption, err = encryption.Summarize(in); err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return summary, nil
+		}
+	}
+	in, err := config.Decrypt(key, in)
+	if err != nil {
+		return nil, err
+	}
+	inMediaType, in, err := encoding.DetectAndExtract(in)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Summary, err = encoding.Summarize(inMediaType, in); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// summarizeKeyValue reads the summary of an etcd key-value.
+func summarizeKeyValue(kv *mvccpb.KeyValue, config *encryption.Config) *leafItemSummary {
+	summary := &leafItemSummary{
+		Key:            string(kv.Key),
+		Version:        kv.Version,
+		CreateRevision: kv.CreateRevision,
+		ModRevision:    kv.ModRevision,
+		Lease:          kv.Lease,
+	}
+	meta, err := summarizeValue(config, string(kv.Key), kv.Value)
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	summary.metaSummary = meta
+	return summary
+}
+
+// writeSummary writes a summary as '<field>: <value>' lines, a YAML document or a line of JSON.
+func writeSummary(format string, summary interface{ writeText(io.Writer) }, out io.Writer) error {
+	if format == metaFormatText {
+		summary.writeText(out)
+		return nil
+	}
+	buf, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case metaFormatYAML:
+		if buf, err = yaml.JSONToYAML(buf); err != nil {
+			return err
+		}
+	case metaFormatJSON:
+		buf = append(buf, '\n')
+	default:
+		return fmt.Errorf("unrecognized 'meta-format' flag value: %v", format)
+	}
+	_, err = out.Write(buf)
+	return err
+}
+
+// validateMetaFormat checks the value of the --meta-format flag.
+func validateMetaFormat(format string) error {
+	switch format {
+	case metaFormatText, metaFormatYAML, metaFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unrecognized 'meta-format' flag value: %v", format)
+	}
+}
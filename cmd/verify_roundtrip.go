@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/encryption"
+	"github.com/etcd-io/auger/pkg/roundtrip"
+	"github.com/etcd-io/auger/pkg/scheme"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyRoundtripLong = `
+Verifies that the kubernetes objects in a boltdb '.db' file survive being
+decoded and re-encoded by this version of the kubernetes types, as
+kube-apiserver does when it reads and updates them.
+
+Each object stored as protobuf is decoded, encoded to the storage binary
+representation again and compared with the stored bytes field by field.
+Fields that are not declared by the go types, e.g. fields written by a
+newer kube-apiserver or of a removed API, and fields that are dropped or
+changed, are reported by apiVersion and kind with their byte offset in the
+etcd value. Objects of kinds that are not known are reported too, as
+their data would be lost entirely. Fields that only exist in the
+re-encoded object, e.g. defaults of fields added since the object was
+stored, are not data loss and are not reported.
+
+Objects stored as JSON or CBOR, e.g. custom resources, are skipped.
+
+Exits with a non-zero status if any object would lose data.`
+
+	verifyRoundtripExample = `
+        # Verify all objects of a boltdb file:
+        auger verify-roundtrip -f <boltdb-file>
+
+        # Verify the jobs, decrypting them with an EncryptionConfiguration:
+        auger verify-roundtrip -f <boltdb-file> --keys-by-prefix /registry/jobs/ --encryption-config <config-file>
+`
+)
+
+var verifyRoundtripCmd = &cobra.Command{
+	Use:     "verify-roundtrip",
+	Short:   "Verifies that kubernetes objects in boltdb '.db' files are re-encoded without losing data.",
+	Long:    verifyRoundtripLong,
+	Example: verifyRoundtripExample,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return verifyRoundtripValidateAndRun()
+	},
+}
+
+type verifyRoundtripOptions struct {
+	filename         string
+	revision         int64
+	keyPrefix        string
+	encryptionConfig string
+}
+
+var verifyRoundtripOpts = &verifyRoundtripOptions{}
+
+func init() {
+	RootCmd.AddCommand(verifyRoundtripCmd)
+	verifyRoundtripCmd.Flags().StringVarP(&verifyRoundtripOpts.filename, "file", "f", "", "Bolt DB '.db' filename")
+	verifyRoundtripCmd.Flags().Int64VarP(&verifyRoundtripOpts.revision, "revision", "r", 0, "etcd revision to verify data at, if 0, the latest revision is used, defaults to 0")
+	verifyRoundtripCmd.Flags().StringVar(&verifyRoundtripOpts.keyPrefix, "keys-by-prefix", "", "Only verify the keys with the given prefix")
+	verifyRoundtripCmd.Flags().StringVar(&verifyRoundtripOpts.encryptionConfig, "encryption-config", "", "EncryptionConfiguration file used to decrypt data encrypted at rest")
+}
+
+func verifyRoundtripValidateAndRun() error {
+	if verifyRoundtripOpts.filename == "" {
+		return errors.New("--file is required")
+	}
+	config, err := loadEncryptionConfig(verifyRoundtripOpts.encryptionConfig)
+	if err != nil {
+		return err
+	}
+	return verifyRoundtrip(verifyRoundtripOpts.filename, verifyRoundtripOpts.keyPrefix, verifyRoundtripOpts.revision, config, os.Stdout)
+}
+
+// lossyObject is an object that loses data when re-encoded, with the reasons why.
+type lossyObject struct {
+	key     string
+	reasons []string
+}
+
+// verifyRoundtrip verifies the latest version of each object with the given key prefix and writes the
+// objects that lose data grouped by apiVersion and kind. It returns an error if any object loses data.
+func verifyRoundtrip(filename string, keyPrefix string, revision int64, config *encryption.Config, out io.Writer) error {
+	kvs, err := data.ListValues(filename, keyPrefix, revision)
+	if err != nil {
+		return err
+	}
+	checked := map[string]int{}
+	lossy := map[string][]lossyObject{}
+	for _, kv := range kvs {
+		value, err := config.Decrypt(string(kv.Key), kv.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", kv.Key, err)
+		}
+		inMediaType, in, err := encoding.DetectAndExtract(value)
+		if err != nil || inMediaType != encoding.StorageBinaryMediaType {
+			continue
+		}
+		gvk := "unrecognized object"
+		if unknown, err := encoding.DecodeUnknown(in); err == nil {
+			gvk = fmt.Sprintf("%s/%s", unknown.APIVersion, unknown.Kind)
+		}
+		checked[gvk]++
+
+		var reasons []string
+		result, err := roundtrip.Verify(scheme.Codecs, in)
+		switch {
+		case errors.Is(err, encoding.ErrUnknownKind):
+			reasons = append(reasons, "unknown kind, the object can't be decoded")
+		case err != nil:
+			reasons = append(reasons, err.Error())
+		default:
+			// Offsets are relative to the etcd value, which may have data before the encoding prefix.
+			shift := len(value) - len(in)
+			for _, f := range result.Findings {
+				f.Offset += shift
+				reasons = append(reasons, f.String())
+			}
+		}
+		if len(reasons) > 0 {
+			lossy[gvk] = append(lossy[gvk], lossyObject{key: string(kv.Key), reasons: reasons})
+		}
+	}
+
+	gvks := make([]string, 0, len(lossy))
+	for gvk := range lossy {
+		gvks = append(gvks, gvk)
+	}
+	sort.Strings(gvks)
+	var total, lost int
+	for _, n := range checked {
+		total += n
+	}
+	for _, gvk := range gvks {
+		objects := lossy[gvk]
+		lost += len(objects)
+		fmt.Fprintf(out, "%s: %d of %d objects lose data\n", gvk, len(objects), checked[gvk])
+		for _, o := range objects {
+			fmt.Fprintf(out, "  %s\n", o.key)
+			for _, reason := range o.reasons {
+				fmt.Fprintf(out, "    %s\n", reason)
+			}
+		}
+	}
+	fmt.Fprintf(out, "checked %d objects, %d would lose data\n", total, lost)
+	if lost > 0 {
+		return fmt.Errorf("%d objects would lose data", lost)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestVerifyRoundtrip(t *testing.T) {
+	pod := readTestFile(t, "testdata/storage/pod.bin")
+	pod = pod[:len(pod)-1]
+	job := readTestFile(t, "testdata/storage/job.bin")
+	widget := readTestFile(t, "testdata/storage/widget.bin")
+	widgetJSON := readTestFile(t, "testdata/json/widget.json")
+
+	// A pod written by a newer kube-apiserver, with a field this version doesn't know.
+	unknown, err := encoding.DecodeUnknown(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown.Raw = protowire.AppendVarint(protowire.AppendTag(unknown.Raw, 99, protowire.VarintType), 1)
+	newerPod, err := unknown.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newerPod = append(append([]byte{}, encoding.ProtoEncodingPrefix...), newerPod...)
+
+	file := createTestDB(t, []testKeyValue{
+		{key: "/registry/example.com/widgets/default/widget", value: widget[:len(widget)-1]},
+		{key: "/registry/example.com/widgets/default/widget-json", value: widgetJSON[:len(widgetJSON)-1]},
+		{key: "/registry/jobs/default/pi", value: job[:len(job)-1]},
+		{key: "/registry/pods/default/newer", value: newerPod},
+		{key: "/registry/pods/default/pod", value: pod},
+	})
+	out := new(bytes.Buffer)
+	if err := verifyRoundtrip(file, "/registry", 0, nil, out); err == nil {
+		t.Error("expected an error for objects that lose data")
+	}
+	assertMatchesFile(t, out, "testdata/verify-roundtrip/report.txt")
+
+	out.Reset()
+	if err := verifyRoundtrip(file, "/registry/jobs/", 0, nil, out); err != nil {
+		t.Errorf("got error %v, want none for jobs", err)
+	}
+	if out.String() != "checked 1 objects, 0 would lose data\n" {
+		t.Errorf("got:\n%s", out)
+	}
+}
@@ -0,0 +1,477 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// gen_descriptors collects the protobuf descriptors of types that were removed from k8s.io/api from
+// past Kubernetes releases and writes them to pkg/descriptors.
+//
+// The generated.pb.go files of k8s.io/api and k8s.io/apimachinery register their gzipped descriptors
+// with gogo/protobuf, so for each release a throwaway module importing all of them is built and run to
+// dump the descriptors. When the same file is in several releases the newest one wins, with the messages
+// removed from it added back from older releases. Only files defining a kind that is not in pkg/scheme,
+// plus their dependencies, are kept.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/etcd-io/auger/pkg/scheme"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// releases are the k8s.io/api releases descriptors are collected from, newest first. Together they cover
+// every group version removed since Kubernetes 1.17.
+var releases = []string{
+	"v0.33.13",
+	"v0.29.15",
+	"v0.26.3",
+	"v0.23.16",
+	"v0.20.6",
+	"v0.17.3",
+}
+
+const (
+	apiModule          = "k8s.io/api"
+	apimachineryModule = "k8s.io/apimachinery"
+	// vendorPrefix is prepended to the descriptor names of releases before 1.18.
+	vendorPrefix = "k8s.io/kubernetes/vendor/"
+	gogoProto    = "github.com/gogo/protobuf/gogoproto/gogo.proto"
+)
+
+var (
+	registerFileRe = regexp.MustCompile(`proto\.RegisterFile\("([^"]+)"`)
+	groupNameRe    = regexp.MustCompile(`GroupName\s*=\s*"([^"]*)"`)
+)
+
+func main() {
+	outDir := flag.String("out", "pkg/descriptors", "directory to write the descriptors to")
+	flag.Parse()
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	groupVersions := map[string]string{}
+	inlineFields := map[string]bool{}
+	for _, release := range releases {
+		log.Printf("collecting descriptors of k8s.io/api %s", release)
+		set, groups, inline, err := collect(release)
+		if err != nil {
+			log.Fatalf("k8s.io/api %s: %v", release, err)
+		}
+		for _, file := range set.File {
+			if newer, ok := files[file.GetName()]; ok {
+				mergeRemoved(newer, file)
+			} else {
+				files[file.GetName()] = file
+			}
+		}
+		for _, field := range inline {
+			inlineFields[field] = true
+		}
+		for pkg, gv := range groups {
+			if _, ok := groupVersions[pkg]; !ok {
+				groupVersions[pkg] = gv
+			}
+		}
+	}
+
+	kept := map[string]bool{}
+	var keep func(name string)
+	keep = func(name string) {
+		if kept[name] {
+			return
+		}
+		file, ok := files[name]
+		if !ok {
+			log.Fatalf("missing descriptor of %s", name)
+		}
+		kept[name] = true
+		for _, dep := range file.Dependency {
+			keep(dep)
+		}
+	}
+	packages := map[string]string{}
+	for name, file := range files {
+		gv, ok := groupVersions[file.GetPackage()]
+		if !ok || !hasRemovedKind(gv, file) {
+			continue
+		}
+		keep(name)
+		packages[gv] = file.GetPackage()
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for name := range kept {
+		set.File = append(set.File, files[name])
+	}
+	sort.Slice(set.File, func(i, j int) bool { return set.File[i].GetName() < set.File[j].GetName() })
+	if _, err := protodesc.NewFiles(set); err != nil {
+		log.Fatalf("invalid descriptors: %v", err)
+	}
+	if err := writeDescriptors(filepath.Join(*outDir, "kubernetes.pb.gz"), set); err != nil {
+		log.Fatal(err)
+	}
+	var inline []string
+	for _, file := range set.File {
+		for _, msg := range file.MessageType {
+			for _, field := range msg.Field {
+				name := file.GetPackage() + "." + msg.GetName() + "." + field.GetName()
+				if inlineFields[name] {
+					inline = append(inline, name)
+				}
+			}
+		}
+	}
+	sort.Strings(inline)
+	if err := writePackages(filepath.Join(*outDir, "zz_generated.packages.go"), packages, inline); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d descriptors of %d removed group versions", len(set.File), len(packages))
+}
+
+// mergeRemoved adds the messages of an older release of a file that were removed from the newer release,
+// along with their dependencies.
+func mergeRemoved(newer, older *descriptorpb.FileDescriptorProto) {
+	messages := map[string]bool{}
+	for _, msg := range newer.MessageType {
+		messages[msg.GetName()] = true
+	}
+	removed := false
+	for _, msg := range older.MessageType {
+		if !messages[msg.GetName()] {
+			newer.MessageType = append(newer.MessageType, msg)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	deps := map[string]bool{}
+	for _, dep := range newer.Dependency {
+		deps[dep] = true
+	}
+	for _, dep := range older.Dependency {
+		if !deps[dep] {
+			newer.Dependency = append(newer.Dependency, dep)
+		}
+	}
+}
+
+// hasRemovedKind returns whether the file defines a kind, a message with a matching list message, that
+// the scheme does not know in the given group version.
+func hasRemovedKind(groupVersion string, file *descriptorpb.FileDescriptorProto) bool {
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil {
+		log.Fatal(err)
+	}
+	messages := map[string]bool{}
+	for _, msg := range file.MessageType {
+		messages[msg.GetName()] = true
+	}
+	for name := range messages {
+		if messages[name+"List"] && !scheme.Scheme.Recognizes(gv.WithKind(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// collect returns the descriptors registered by a release of k8s.io/api and k8s.io/apimachinery, the
+// group versions of the k8s.io/api proto packages, and the fields of embedded structs that are inlined in
+// JSON.
+func collect(release string) (*descriptorpb.FileDescriptorSet, map[string]string, []string, error) {
+	dir, err := os.MkdirTemp("", "gen_descriptors")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	goMod := fmt.Sprintf("module gen_descriptors\n\ngo 1.22\n\nrequire (\n\t%s %s\n\t%s %s\n)\n",
+		apiModule, release, apimachineryModule, release)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := run(dir, nil, "go", "mod", "download", apiModule, apimachineryModule); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var imports, names []string
+	groups := map[string]string{}
+	for _, module := range []string{apiModule, apimachineryModule} {
+		out := new(bytes.Buffer)
+		if err := run(dir, out, "go", "list", "-m", "-json", module); err != nil {
+			return nil, nil, nil, err
+		}
+		var m struct{ Dir string }
+		if err := json.Unmarshal(out.Bytes(), &m); err != nil {
+			return nil, nil, nil, err
+		}
+		err := filepath.Walk(m.Dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.Name() != "generated.pb.go" {
+				return err
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			match := registerFileRe.FindSubmatch(src)
+			if match == nil {
+				return nil
+			}
+			rel, err := filepath.Rel(m.Dir, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			imports = append(imports, module+"/"+filepath.ToSlash(rel))
+			names = append(names, string(match[1]))
+
+			if module != apiModule {
+				return nil
+			}
+			register, err := os.ReadFile(filepath.Join(filepath.Dir(path), "register.go"))
+			if err != nil {
+				return err
+			}
+			group := groupNameRe.FindSubmatch(register)
+			if group == nil {
+				return fmt.Errorf("no GroupName in %s", filepath.Dir(path))
+			}
+			gv := schema.GroupVersion{Group: string(group[1]), Version: filepath.Base(rel)}
+			groups["k8s.io.api."+strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")] = gv.String()
+			return nil
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	main := new(bytes.Buffer)
+	if err := dumpTemplate.Execute(main, struct{ Imports, Names []string }{imports, names}); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), main.Bytes(), 0o644); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := run(dir, nil, "go", "mod", "tidy"); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := run(dir, nil, "go", "run", "."); err != nil {
+		return nil, nil, nil, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "descriptors.pb"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, nil, nil, err
+	}
+	inline, err := os.ReadFile(filepath.Join(dir, "inline.txt"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, file := range set.File {
+		file.Name = proto.String(strings.TrimPrefix(file.GetName(), vendorPrefix))
+		var deps []string
+		for _, dep := range file.Dependency {
+			// Older releases import gogo.proto for field options, which are not needed to decode.
+			if dep != gogoProto {
+				deps = append(deps, strings.TrimPrefix(dep, vendorPrefix))
+			}
+		}
+		file.Dependency = deps
+		// Only the fields are needed to decode, drop the comments.
+		file.SourceCodeInfo = nil
+	}
+	return set, groups, strings.Fields(string(inline)), nil
+}
+
+func run(dir string, stdout *bytes.Buffer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	cmd.Stderr = os.Stderr
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func writeDescriptors(filename string, set *descriptorpb.FileDescriptorSet) error {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return err
+	}
+	out := new(bytes.Buffer)
+	w, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, out.Bytes(), 0o644)
+}
+
+func writePackages(filename string, packages map[string]string, inline []string) error {
+	src := new(bytes.Buffer)
+	data := struct {
+		Packages map[string]string
+		Inline   []string
+	}{packages, inline}
+	if err := packagesTemplate.Execute(src, data); err != nil {
+		return err
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, formatted, 0o644)
+}
+
+var dumpTemplate = template.Must(template.New("main").Parse(`package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+{{range .Imports}}
+	_ "{{.}}"
+{{- end}}
+)
+
+var names = []string{
+{{- range .Names}}
+	"{{.}}",
+{{- end}}
+}
+
+func main() {
+	set := &descriptor.FileDescriptorSet{}
+	inline := new(bytes.Buffer)
+	for _, name := range names {
+		r, err := gzip.NewReader(bytes.NewReader(proto.FileDescriptor(name)))
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		file := &descriptor.FileDescriptorProto{}
+		if err := proto.Unmarshal(b, file); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		set.File = append(set.File, file)
+
+		for _, msg := range file.MessageType {
+			fullName := file.GetPackage() + "." + msg.GetName()
+			t := proto.MessageType(fullName)
+			if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			for i := 0; i < t.Elem().NumField(); i++ {
+				f := t.Elem().Field(i)
+				if !strings.HasPrefix(f.Tag.Get("json"), ",inline") {
+					continue
+				}
+				// Without a name the field is named after the embedded type.
+				name := strings.ToLower(f.Name[:1]) + f.Name[1:]
+				for _, opt := range strings.Split(f.Tag.Get("protobuf"), ",") {
+					if strings.HasPrefix(opt, "name=") {
+						name = strings.TrimPrefix(opt, "name=")
+					}
+				}
+				fmt.Fprintf(inline, "%s.%s\n", fullName, name)
+			}
+		}
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("descriptors.pb", b, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("inline.txt", inline.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+`))
+
+var packagesTemplate = template.Must(template.New("packages").Parse(`/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package descriptors
+
+// Don't edit this file directly. It is generated by hack/gen_descriptors.
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// protoPackages maps the group versions of the embedded descriptors to their proto package.
+var protoPackages = map[string]string{
+{{- range $gv, $pkg := .Packages}}
+	"{{$gv}}": "{{$pkg}}",
+{{- end}}
+}
+
+// inlineFields are the fields of embedded structs, whose fields are inlined in JSON.
+var inlineFields = map[protoreflect.FullName]bool{
+{{- range .Inline}}
+	"{{.}}": true,
+{{- end}}
+}
+`))
@@ -0,0 +1,306 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package anonymize replaces the identifying data of kubernetes objects, decoded from JSON, and of their
+// etcd keys with stable pseudonyms, so that etcd data can be shared without disclosing it.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+// builtinNames are the names and namespaces kubernetes creates itself, which are kept as is.
+var builtinNames = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+	"kubernetes":      true,
+}
+
+// labelFields are the fields whose values are maps of label or annotation values.
+var labelFields = map[string]bool{
+	"labels":       true,
+	"matchLabels":  true,
+	"annotations":  true,
+	"nodeSelector": true,
+}
+
+// hostFields are the fields whose values are names of nodes or hosts, which are replaced whether or not an
+// object of that name was collected.
+var hostFields = map[string]bool{
+	"nodeName": true,
+	"hostname": true,
+	"host":     true,
+	"hosts":    true,
+}
+
+// Anonymizer replaces names, namespaces, label and annotation values, images, hosts, messages and the data
+// of Secrets and ConfigMaps with pseudonyms. Keys of labels, annotations and data are kept, as are the types
+// and shapes of all values.
+//
+// A value is always replaced with the same pseudonym, derived from it and the seed, so that objects still
+// refer to each other, e.g. by owner references, volumes or label selectors. Names and namespaces are
+// replaced wherever they are referred to once they have been collected from all objects.
+type Anonymizer struct {
+	seed []byte
+	// names are the names and namespaces of all collected objects.
+	names map[string]bool
+	// keys are the pseudonymous keys of the collected objects, by key.
+	keys map[string]string
+}
+
+// New returns an Anonymizer deriving pseudonyms with the seed. Without knowing the seed, pseudonyms can't
+// be traced back to the values they replace, e.g. by trying well-known names.
+func New(seed []byte) *Anonymizer {
+	return &Anonymizer{seed: seed, names: map[string]bool{}, keys: map[string]string{}}
+}
+
+// Collect records the name and namespace of an object stored at key, so that they are replaced wherever
+// they are referred to, and so that key is replaced consistently, even by revisions that deleted it.
+func (a *Anonymizer) Collect(key string, obj map[string]interface{}) {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	for _, n := range []string{name, namespace} {
+		if n != "" && !isBuiltin(n) {
+			a.names[n] = true
+		}
+	}
+	if _, ok := a.keys[key]; ok || name == "" {
+		return
+	}
+	suffix, anonymized := "/"+name, "/"+a.name(name)
+	if namespace != "" {
+		suffix, anonymized = "/"+namespace+suffix, "/"+a.name(namespace)+anonymized
+	}
+	if prefix, ok := strings.CutSuffix(key, suffix); ok {
+		a.keys[key] = prefix + anonymized
+	}
+}
+
+// registryPrefix is the prefix kube-apiserver stores objects under by default.
+const registryPrefix = "/registry/"
+
+// singletonKeys are the keys of the objects kube-apiserver stores once per cluster, e.g. the allocation
+// maps of service IPs and ports, whose last segment is not a name. They are kept as is.
+var singletonKeys = map[string]bool{
+	registryPrefix + "ranges/serviceips":          true,
+	registryPrefix + "ranges/secondaryserviceips": true,
+	registryPrefix + "ranges/servicenodeports":    true,
+}
+
+// Key returns the pseudonymous etcd key of key. The namespace and name of the keys of collected objects
+// are replaced. The namespace and name of other object keys under /registry/, the one or two segments that
+// follow the resource, are replaced whether or not they are collected names, since their values, e.g.
+// encrypted ones, may not have been decoded. Singleton keys are kept. For all other keys, the last two
+// segments are replaced if they are collected names.
+func (a *Anonymizer) Key(key string) string {
+	if anonymized, ok := a.keys[key]; ok {
+		return anonymized
+	}
+	if singletonKeys[key] {
+		return key
+	}
+	rest, ok := strings.CutPrefix(key, registryPrefix)
+	if !ok {
+		return a.path(key, 2)
+	}
+	segments := strings.Split(rest, "/")
+	names := segments[resourceSegments(segments):]
+	if len(names) > 2 {
+		return a.path(key, 2)
+	}
+	for i := range names {
+		if names[i] != "" {
+			names[i] = a.name(names[i])
+		}
+	}
+	return registryPrefix + strings.Join(segments, "/")
+}
+
+// resourceSegments returns the number of segments of a key, without the registry prefix, that are the
+// prefix of its resource: '<resource>', '<group>/<resource>' for groups that are domain names, or
+// 'services/specs' and 'services/endpoints'.
+func resourceSegments(segments []string) int {
+	if len(segments) > 1 && (strings.Contains(segments[0], ".") || segments[0] == "services") {
+		return 2
+	}
+	return 1
+}
+
+// path replaces the segments of a slash separated path that are collected names, only in the last n
+// segments unless n is 0.
+func (a *Anonymizer) path(path string, n int) string {
+	segments := strings.Split(path, "/")
+	first := 0
+	if n > 0 {
+		first = max(len(segments)-n, 0)
+	}
+	for i := first; i < len(segments); i++ {
+		if a.names[segments[i]] {
+			segments[i] = a.name(segments[i])
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Object replaces the identifying data of an object decoded from JSON, e.g. unstructured.Unstructured
+// contents, in place.
+func (a *Anonymizer) Object(obj map[string]interface{}) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	hasPayloads := apiVersion == "v1" && (kind == "Secret" || kind == "ConfigMap")
+	for field, value := range obj {
+		payloads, ok := value.(map[string]interface{})
+		if !hasPayloads || !ok || field != "data" && field != "binaryData" {
+			obj[field] = a.walk(field, value)
+			continue
+		}
+		for key, payload := range payloads {
+			s, _ := payload.(string)
+			if kind == "ConfigMap" && field == "data" {
+				payloads[key] = a.text(s)
+			} else {
+				payloads[key] = a.binary(s)
+			}
+		}
+	}
+}
+
+// walk replaces the values of the fields of an object, by their field name.
+func (a *Anonymizer) walk(field string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if labelFields[field] || field == "selector" && isStringMap(value) {
+			for key, v := range value {
+				if s, ok := v.(string); ok {
+					value[key] = a.value(s)
+				}
+			}
+			return value
+		}
+		for key, v := range value {
+			value[key] = a.walk(key, v)
+		}
+		return value
+	case []interface{}:
+		for i, v := range value {
+			if field == "names" {
+				// Images of the status of nodes.
+				if s, ok := v.(string); ok {
+					value[i] = a.image(s)
+					continue
+				}
+			}
+			value[i] = a.walk(field, v)
+		}
+		return value
+	case string:
+		switch {
+		case field == "apiVersion" || field == "kind":
+			return value
+		case field == "image" || field == "imageID":
+			return a.image(value)
+		case field == "message" || field == "note" || field == "generateName" || hostFields[field]:
+			return a.value(value)
+		case field == "selfLink":
+			return a.path(value, 0)
+		case a.names[value]:
+			return a.name(value)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// name returns the pseudonym of a name or namespace.
+func (a *Anonymizer) name(name string) string {
+	if isBuiltin(name) {
+		return name
+	}
+	return "anon-" + hex.EncodeToString(a.sum(name, 0)[:6])
+}
+
+// value returns the pseudonym of a label or annotation value or of text, which is empty if the value is.
+func (a *Anonymizer) value(value string) string {
+	if value == "" {
+		return ""
+	}
+	return a.name(value)
+}
+
+// image returns the pseudonym of an image reference.
+func (a *Anonymizer) image(image string) string {
+	if image == "" {
+		return ""
+	}
+	return "registry.invalid/anon-" + hex.EncodeToString(a.sum(image, 0)[:6])
+}
+
+// text returns pseudonymous text of the same length as the text of a ConfigMap.
+func (a *Anonymizer) text(text string) string {
+	return hex.EncodeToString(a.stream(text, (len(text)+1)/2))[:len(text)]
+}
+
+// binary returns the pseudonym of base64 encoded binary data, of the same length once decoded.
+func (a *Anonymizer) binary(encoded string) string {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		decoded = []byte(encoded)
+	}
+	return base64.StdEncoding.EncodeToString(a.stream(encoded, len(decoded)))
+}
+
+// stream returns n pseudonymous bytes derived from value.
+func (a *Anonymizer) stream(value string, n int) []byte {
+	out := make([]byte, 0, n+sha256.Size)
+	for i := uint64(1); len(out) < n; i++ {
+		out = append(out, a.sum(value, i)...)
+	}
+	return out[:n]
+}
+
+// sum returns the HMAC of value and counter, keyed with the seed.
+func (a *Anonymizer) sum(value string, counter uint64) []byte {
+	h := hmac.New(sha256.New, a.seed)
+	h.Write(binary.BigEndian.AppendUint64(nil, counter))
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+func isBuiltin(name string) bool {
+	return builtinNames[name] || strings.HasPrefix(name, "system:")
+}
+
+func isStringMap(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, v := range m {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
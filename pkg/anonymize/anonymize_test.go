@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anonymize
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	podJSON = `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web-1","namespace":"shop","selfLink":"/api/v1/namespaces/shop/pods/web-1","labels":{"app":"web"},` +
+		`"annotations":{"owner":"alice"},"ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"web","uid":"1"}]},` +
+		`"spec":{"serviceAccountName":"default","nodeName":"node-a","hostname":"db.acme.io","containers":[{"name":"web","image":"acme.io/shop/web:1.0"}],` +
+		`"volumes":[{"name":"tls","secret":{"secretName":"web-tls"}}]},"status":{"conditions":[{"type":"Ready","message":"acme is down"}]}}`
+	secretJSON    = `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"web-tls","namespace":"shop"},"type":"kubernetes.io/tls","data":{"tls.key":"aHVudGVyMg=="}}`
+	configMapJSON = `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"shop"},"data":{"color":"blue"},"binaryData":{"logo":"AAEC"}}`
+	serviceJSON   = `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"shop"},"spec":{"selector":{"app":"web"},"ports":[{"port":80}]}}`
+	nodeJSON      = `{"apiVersion":"v1","kind":"Node","metadata":{"name":"node-a"},"status":{"images":[{"names":["acme.io/shop/web:1.0"],"sizeBytes":10}]}}`
+)
+
+func decode(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestAnonymizer(t *testing.T) {
+	a := New([]byte("seed"))
+	objects := map[string]string{
+		"/registry/pods/shop/web-1":          podJSON,
+		"/registry/secrets/shop/web-tls":     secretJSON,
+		"/registry/configmaps/shop/settings": configMapJSON,
+		"/registry/services/specs/shop/web":  serviceJSON,
+		"/registry/minions/node-a":           nodeJSON,
+	}
+	for key, s := range objects {
+		a.Collect(key, decode(t, s))
+	}
+
+	anonymized := map[string]string{}
+	for key, s := range objects {
+		obj := decode(t, s)
+		a.Object(obj)
+		buf, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		anonymized[key] = string(buf)
+		for _, secret := range []string{"web-1", "shop", "alice", "node-a", "acme", "web-tls", "aHVudGVyMg==", "blue", "settings", `"web"`} {
+			if strings.Contains(string(buf), secret) {
+				t.Errorf("%s still contains %q: %s", key, secret, buf)
+			}
+		}
+	}
+
+	pod := decode(t, anonymized["/registry/pods/shop/web-1"])
+	svc := decode(t, anonymized["/registry/services/specs/shop/web"])
+	secret := decode(t, anonymized["/registry/secrets/shop/web-tls"])
+	node := decode(t, anonymized["/registry/minions/node-a"])
+	metadata := pod["metadata"].(map[string]interface{})
+	spec := pod["spec"].(map[string]interface{})
+
+	// References and selectors still match.
+	if got, want := metadata["labels"].(map[string]interface{})["app"], svc["spec"].(map[string]interface{})["selector"].(map[string]interface{})["app"]; got != want {
+		t.Errorf("got label %v, want the value of the selector %v", got, want)
+	}
+	if got, want := spec["volumes"].([]interface{})[0].(map[string]interface{})["secret"].(map[string]interface{})["secretName"], secret["metadata"].(map[string]interface{})["name"]; got != want {
+		t.Errorf("got secretName %v, want the name of the secret %v", got, want)
+	}
+	if got, want := spec["nodeName"], node["metadata"].(map[string]interface{})["name"]; got != want {
+		t.Errorf("got nodeName %v, want the name of the node %v", got, want)
+	}
+	if got, want := spec["containers"].([]interface{})[0].(map[string]interface{})["image"], node["status"].(map[string]interface{})["images"].([]interface{})[0].(map[string]interface{})["names"].([]interface{})[0]; got != want {
+		t.Errorf("got image %v, want the image of the node %v", got, want)
+	}
+
+	// Built-in names, kinds and keys are kept.
+	if spec["serviceAccountName"] != "default" || pod["kind"] != "Pod" || metadata["ownerReferences"].([]interface{})[0].(map[string]interface{})["kind"] != "ReplicaSet" {
+		t.Errorf("got %s, want built-in names and kinds kept", anonymized["/registry/pods/shop/web-1"])
+	}
+
+	// Payloads keep their size.
+	data, err := base64.StdEncoding.DecodeString(secret["data"].(map[string]interface{})["tls.key"].(string))
+	if err != nil || len(data) != len("hunter2") {
+		t.Errorf("got secret data %q, %v, want 7 bytes", data, err)
+	}
+	cm := decode(t, anonymized["/registry/configmaps/shop/settings"])
+	if got := cm["data"].(map[string]interface{})["color"].(string); len(got) != len("blue") {
+		t.Errorf("got configmap data %q, want 4 bytes", got)
+	}
+
+	// Keys are replaced consistently with the objects.
+	key := a.Key("/registry/pods/shop/web-1")
+	if want := "/registry/pods/" + metadata["namespace"].(string) + "/" + metadata["name"].(string); key != want {
+		t.Errorf("got key %s, want %s", key, want)
+	}
+	if got, want := a.Key("/registry/events/shop/web-1"), "/registry/events/"+metadata["namespace"].(string)+"/"+metadata["name"].(string); got != want {
+		t.Errorf("got key %s of an object that was not collected, want %s", got, want)
+	}
+	// The namespace and name of object keys are replaced even if no object of that name was collected, e.g.
+	// if their values are encrypted. Singleton keys are kept, and only the collected names of keys with more
+	// segments are replaced.
+	for key, want := range map[string]string{
+		"/registry/secrets/prod/db-password":                   "/registry/secrets/" + a.name("prod") + "/" + a.name("db-password"),
+		"/registry/secrets/kube-system/db-password":            "/registry/secrets/kube-system/" + a.name("db-password"),
+		"/registry/namespaces/prod":                            "/registry/namespaces/" + a.name("prod"),
+		"/registry/services/endpoints/prod/db":                 "/registry/services/endpoints/" + a.name("prod") + "/" + a.name("db"),
+		"/registry/example.com/widgets/prod/db":                "/registry/example.com/widgets/" + a.name("prod") + "/" + a.name("db"),
+		"/registry/apiregistration.k8s.io/apiservices/v1.acme": "/registry/apiregistration.k8s.io/apiservices/" + a.name("v1.acme"),
+		"/registry/ranges/serviceips":                          "/registry/ranges/serviceips",
+		"/registry/ranges/servicenodeports":                    "/registry/ranges/servicenodeports",
+		"/registry/pods/prod/db/extra":                         "/registry/pods/prod/db/extra",
+		"/registry/pods/shop/web-1/extra":                      "/registry/pods/shop/" + a.name("web-1") + "/extra",
+	} {
+		if got := a.Key(key); got != want {
+			t.Errorf("got key %s for %s, want %s", got, key, want)
+		}
+	}
+	if got := a.Key("compact_rev_key"); got != "compact_rev_key" {
+		t.Errorf("got key %s, want compact_rev_key", got)
+	}
+
+	// Pseudonyms are stable for a seed.
+	if New([]byte("seed")).name("shop") != metadata["namespace"] || New([]byte("other")).name("shop") == metadata["namespace"] {
+		t.Error("expected pseudonyms derived from the seed")
+	}
+}
@@ -106,7 +106,7 @@ func opOption(opts []OpOption) op {
 	return opt
 }
 
-// KeyValue is the key-value pair.
+// KeyValue is the key-value pair. Values can be decoded to objects with encoding.DecodeObject.
 type KeyValue struct {
 	Key   []byte
 	Value []byte
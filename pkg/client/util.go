@@ -102,3 +102,12 @@ func getPrefix(prefix string, gr schema.GroupResource, name, namespace string) (
 	}
 	return strings.Join(s, "/"), single, nil
 }
+
+// Key returns the etcd key kube-apiserver stores the named object of the given GroupResource at.
+func Key(prefix string, gr schema.GroupResource, name, namespace string) (string, error) {
+	if name == "" {
+		return "", errors.New("name is empty")
+	}
+	key, _, err := getPrefix(prefix, gr, name, namespace)
+	return key, err
+}
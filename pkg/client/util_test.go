@@ -236,3 +236,28 @@ func TestGetPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		gr        schema.GroupResource
+		name      string
+		namespace string
+		want      string
+		wantErr   bool
+	}{
+		{gr: schema.GroupResource{Resource: "pods"}, name: "pod", namespace: "default", want: "/registry/pods/default/pod"},
+		{gr: schema.GroupResource{Resource: "nodes"}, name: "node", want: "/registry/minions/node"},
+		{gr: schema.GroupResource{Group: "auger.x-k8s.io", Resource: "foo"}, name: "foo", namespace: "default", want: "/registry/auger.x-k8s.io/foo/default/foo"},
+		{gr: schema.GroupResource{Resource: "pods"}, namespace: "default", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Key("/registry", tt.gr, tt.name, tt.namespace)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Key(%s, %q, %q) error = %v, wantErr %v", tt.gr, tt.name, tt.namespace, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Key(%s, %q, %q) = %v, want %v", tt.gr, tt.name, tt.namespace, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// The annotations that keep the fields of autoscaling/v2 without an equivalent in older versions, so that
+// they survive a round trip through them. Metrics are kept in the format of autoscaling/v2beta1.
+const (
+	hpaMetricsAnnotation        = "autoscaling.alpha.kubernetes.io/metrics"
+	hpaCurrentMetricsAnnotation = "autoscaling.alpha.kubernetes.io/current-metrics"
+	hpaBehaviorAnnotation       = "autoscaling.alpha.kubernetes.io/behavior"
+	hpaConditionsAnnotation     = "autoscaling.alpha.kubernetes.io/conditions"
+)
+
+var hpaAnnotations = []string{hpaMetricsAnnotation, hpaCurrentMetricsAnnotation, hpaBehaviorAnnotation, hpaConditionsAnnotation}
+
+// metricField is a field of an autoscaling/v2beta1 metric source or status, and the path of the field of
+// autoscaling/v2 it is converted to.
+type metricField struct {
+	v2beta1 string
+	v2      []string
+}
+
+var (
+	metricName     = metricField{"metricName", []string{"metric", "name"}}
+	metricSelector = metricField{"selector", []string{"metric", "selector"}}
+	describedBy    = metricField{"target", []string{"describedObject"}}
+)
+
+// metricSourceFields are the fields of the autoscaling/v2beta1 metric sources, by source.
+var metricSourceFields = map[string][]metricField{
+	"resource": {
+		{"targetAverageUtilization", []string{"target", "averageUtilization"}},
+		{"targetAverageValue", []string{"target", "averageValue"}},
+	},
+	"containerResource": {
+		{"targetAverageUtilization", []string{"target", "averageUtilization"}},
+		{"targetAverageValue", []string{"target", "averageValue"}},
+	},
+	"pods": {
+		metricName,
+		metricSelector,
+		{"targetAverageValue", []string{"target", "averageValue"}},
+	},
+	"object": {
+		describedBy,
+		metricName,
+		metricSelector,
+		{"targetValue", []string{"target", "value"}},
+		{"averageValue", []string{"target", "averageValue"}},
+	},
+	"external": {
+		metricName,
+		{"metricSelector", []string{"metric", "selector"}},
+		{"targetValue", []string{"target", "value"}},
+		{"targetAverageValue", []string{"target", "averageValue"}},
+	},
+}
+
+// metricStatusFields are the fields of the autoscaling/v2beta1 metric statuses, by source.
+var metricStatusFields = map[string][]metricField{
+	"resource": {
+		{"currentAverageUtilization", []string{"current", "averageUtilization"}},
+		{"currentAverageValue", []string{"current", "averageValue"}},
+	},
+	"containerResource": {
+		{"currentAverageUtilization", []string{"current", "averageUtilization"}},
+		{"currentAverageValue", []string{"current", "averageValue"}},
+	},
+	"pods": {
+		metricName,
+		metricSelector,
+		{"currentAverageValue", []string{"current", "averageValue"}},
+	},
+	"object": {
+		describedBy,
+		metricName,
+		metricSelector,
+		{"currentValue", []string{"current", "value"}},
+		{"averageValue", []string{"current", "averageValue"}},
+	},
+	"external": {
+		metricName,
+		{"metricSelector", []string{"metric", "selector"}},
+		{"currentValue", []string{"current", "value"}},
+		{"currentAverageValue", []string{"current", "averageValue"}},
+	},
+}
+
+// convertHorizontalPodAutoscalerFromV1 converts the CPU utilization target and status of autoscaling/v1
+// to a resource metric, and restores the metrics, behavior and conditions kept in annotations.
+func convertHorizontalPodAutoscalerFromV1(obj map[string]interface{}) error {
+	var metrics []interface{}
+	if others, ok := annotationValue(obj, hpaMetricsAnnotation).([]interface{}); ok {
+		convertMetrics(others, metricSourceFields, true)
+		metrics = others
+	}
+	if target, found, _ := unstructured.NestedFieldNoCopy(obj, "spec", "targetCPUUtilizationPercentage"); found {
+		unstructured.RemoveNestedField(obj, "spec", "targetCPUUtilizationPercentage")
+		metrics = append(metrics, cpuMetric("target", map[string]interface{}{"type": "Utilization", "averageUtilization": target}))
+	}
+	if len(metrics) > 0 {
+		if err := unstructured.SetNestedSlice(obj, metrics, "spec", "metrics"); err != nil {
+			return err
+		}
+	}
+
+	current, found, _ := unstructured.NestedFieldNoCopy(obj, "status", "currentCPUUtilizationPercentage")
+	unstructured.RemoveNestedField(obj, "status", "currentCPUUtilizationPercentage")
+	if currentMetrics, ok := annotationValue(obj, hpaCurrentMetricsAnnotation).([]interface{}); ok {
+		// The annotation holds all current metrics, including the CPU utilization.
+		convertMetrics(currentMetrics, metricStatusFields, true)
+		if err := unstructured.SetNestedSlice(obj, currentMetrics, "status", "currentMetrics"); err != nil {
+			return err
+		}
+	} else if found {
+		metric := cpuMetric("current", map[string]interface{}{"averageUtilization": current})
+		if err := unstructured.SetNestedSlice(obj, []interface{}{metric}, "status", "currentMetrics"); err != nil {
+			return err
+		}
+	}
+	if conditions, ok := annotationValue(obj, hpaConditionsAnnotation).([]interface{}); ok {
+		if err := unstructured.SetNestedSlice(obj, conditions, "status", "conditions"); err != nil {
+			return err
+		}
+	}
+	return restoreBehavior(obj)
+}
+
+// convertHorizontalPodAutoscalerToV1 converts the CPU utilization resource metric to the CPU utilization
+// target and status of autoscaling/v1, and keeps the other metrics, the behavior and the conditions in
+// annotations.
+func convertHorizontalPodAutoscalerToV1(obj map[string]interface{}) error {
+	removeAnnotations(obj, hpaAnnotations...)
+
+	metrics, _, _ := unstructured.NestedSlice(obj, "spec", "metrics")
+	unstructured.RemoveNestedField(obj, "spec", "metrics")
+	var others []interface{}
+	for _, m := range metrics {
+		utilization, ok := cpuUtilization(m, "target")
+		if !ok {
+			others = append(others, m)
+			continue
+		}
+		if err := unstructured.SetNestedField(obj, utilization, "spec", "targetCPUUtilizationPercentage"); err != nil {
+			return err
+		}
+	}
+	if len(others) > 0 {
+		convertMetrics(others, metricSourceFields, false)
+		if err := setAnnotationValue(obj, hpaMetricsAnnotation, others); err != nil {
+			return err
+		}
+	}
+
+	currentMetrics, _, _ := unstructured.NestedSlice(obj, "status", "currentMetrics")
+	unstructured.RemoveNestedField(obj, "status", "currentMetrics")
+	for _, m := range currentMetrics {
+		if utilization, ok := cpuUtilization(m, "current"); ok {
+			if err := unstructured.SetNestedField(obj, utilization, "status", "currentCPUUtilizationPercentage"); err != nil {
+				return err
+			}
+		}
+	}
+	if len(currentMetrics) > 0 {
+		convertMetrics(currentMetrics, metricStatusFields, false)
+		if err := setAnnotationValue(obj, hpaCurrentMetricsAnnotation, currentMetrics); err != nil {
+			return err
+		}
+	}
+
+	if conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions"); len(conditions) > 0 {
+		unstructured.RemoveNestedField(obj, "status", "conditions")
+		if err := setAnnotationValue(obj, hpaConditionsAnnotation, conditions); err != nil {
+			return err
+		}
+	}
+	return keepBehavior(obj)
+}
+
+// convertHorizontalPodAutoscalerFromV2beta1 converts the metrics of autoscaling/v2beta1, which have their
+// target and current values in fields of the metric source, to metrics with a target and a current value,
+// and restores the behavior kept in an annotation.
+func convertHorizontalPodAutoscalerFromV2beta1(obj map[string]interface{}) error {
+	metrics, _, _ := unstructured.NestedFieldNoCopy(obj, "spec", "metrics")
+	convertMetrics(metrics, metricSourceFields, true)
+	currentMetrics, _, _ := unstructured.NestedFieldNoCopy(obj, "status", "currentMetrics")
+	convertMetrics(currentMetrics, metricStatusFields, true)
+	return restoreBehavior(obj)
+}
+
+// convertHorizontalPodAutoscalerToV2beta1 converts metrics to the metrics of autoscaling/v2beta1, and
+// keeps the behavior in an annotation.
+func convertHorizontalPodAutoscalerToV2beta1(obj map[string]interface{}) error {
+	removeAnnotations(obj, hpaAnnotations...)
+	metrics, _, _ := unstructured.NestedFieldNoCopy(obj, "spec", "metrics")
+	convertMetrics(metrics, metricSourceFields, false)
+	currentMetrics, _, _ := unstructured.NestedFieldNoCopy(obj, "status", "currentMetrics")
+	convertMetrics(currentMetrics, metricStatusFields, false)
+	return keepBehavior(obj)
+}
+
+// convertMetrics converts the sources of a list of metrics between autoscaling/v2beta1 and
+// autoscaling/v2 in place.
+func convertMetrics(metrics interface{}, fields map[string][]metricField, toV2 bool) {
+	items, _ := metrics.([]interface{})
+	for _, m := range items {
+		m, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for source, sourceFields := range fields {
+			if s, ok := m[source].(map[string]interface{}); ok {
+				m[source] = convertMetricSource(source, s, sourceFields, toV2)
+			}
+		}
+	}
+}
+
+// convertMetricSource moves the fields of a metric source or status between autoscaling/v2beta1 and
+// autoscaling/v2. Fields of autoscaling/v2 without an equivalent, like the type of a target, are dropped.
+func convertMetricSource(source string, in map[string]interface{}, fields []metricField, toV2 bool) map[string]interface{} {
+	out := map[string]interface{}{}
+	moved := map[string]bool{}
+	for _, f := range fields {
+		from, to := []string{f.v2beta1}, f.v2
+		if !toV2 {
+			from, to = to, from
+		}
+		moved[from[0]] = true
+		if v, found, _ := unstructured.NestedFieldNoCopy(in, from...); found {
+			_ = unstructured.SetNestedField(out, v, to...)
+		}
+	}
+	for k, v := range in {
+		if !moved[k] {
+			out[k] = v
+		}
+	}
+	if target, ok := out["target"].(map[string]interface{}); ok && toV2 {
+		target["type"] = metricTargetType(source, target)
+	}
+	return out
+}
+
+// metricTargetType returns the type of an autoscaling/v2 metric target converted from autoscaling/v2beta1,
+// by the values it has.
+func metricTargetType(source string, target map[string]interface{}) string {
+	_, utilization := target["averageUtilization"]
+	_, value := target["value"]
+	_, averageValue := target["averageValue"]
+	switch source {
+	case "resource", "containerResource":
+		if utilization {
+			return "Utilization"
+		}
+	case "object":
+		if !averageValue {
+			return "Value"
+		}
+	case "external":
+		if value {
+			return "Value"
+		}
+	}
+	return "AverageValue"
+}
+
+// restoreBehavior restores the behavior of an autoscaler kept in an annotation, and removes the
+// annotations that keep fields for older versions.
+func restoreBehavior(obj map[string]interface{}) error {
+	behavior, ok := annotationValue(obj, hpaBehaviorAnnotation).(map[string]interface{})
+	removeAnnotations(obj, hpaAnnotations...)
+	if !ok || len(behavior) == 0 {
+		return nil
+	}
+	return unstructured.SetNestedMap(obj, behavior, "spec", "behavior")
+}
+
+// keepBehavior moves the behavior of an autoscaler to an annotation.
+func keepBehavior(obj map[string]interface{}) error {
+	behavior, _, _ := unstructured.NestedMap(obj, "spec", "behavior")
+	unstructured.RemoveNestedField(obj, "spec", "behavior")
+	if len(behavior) == 0 {
+		return nil
+	}
+	return setAnnotationValue(obj, hpaBehaviorAnnotation, behavior)
+}
+
+// annotationValue returns the JSON value kept in an annotation of an object, or nil. Like kube-apiserver,
+// annotations that don't hold JSON are ignored.
+func annotationValue(obj map[string]interface{}, name string) interface{} {
+	s, found, _ := unstructured.NestedString(obj, "metadata", "annotations", name)
+	if !found {
+		return nil
+	}
+	var v interface{}
+	if err := utiljson.Unmarshal([]byte(s), &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func setAnnotationValue(obj map[string]interface{}, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(obj, string(data), "metadata", "annotations", name)
+}
+
+func removeAnnotations(obj map[string]interface{}, names ...string) {
+	annotations, found, _ := unstructured.NestedFieldNoCopy(obj, "metadata", "annotations")
+	a, ok := annotations.(map[string]interface{})
+	if !found || !ok {
+		return
+	}
+	for _, name := range names {
+		delete(a, name)
+	}
+	if len(a) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "annotations")
+	}
+}
+
+func cpuMetric(field string, value map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "Resource",
+		"resource": map[string]interface{}{"name": "cpu", field: value},
+	}
+}
+
+// cpuUtilization returns the average utilization of a CPU resource metric.
+func cpuUtilization(metric interface{}, field string) (interface{}, bool) {
+	m, ok := metric.(map[string]interface{})
+	if !ok || m["type"] != "Resource" {
+		return nil, false
+	}
+	if name, _, _ := unstructured.NestedString(m, "resource", "name"); name != "cpu" {
+		return nil, false
+	}
+	utilization, found, _ := unstructured.NestedFieldNoCopy(m, "resource", field, "averageUtilization")
+	return utilization, found
+}
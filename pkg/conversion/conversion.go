@@ -0,0 +1,182 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conversion converts kubernetes objects between the external versions of their kind.
+//
+// kube-apiserver converts between versions through the internal types of k8s.io/kubernetes, which can't
+// be imported. Objects are instead converted field by field, which is how most conversion functions
+// generated for the internal types behave, with the conversion functions of this package adjusting
+// objects for the versions of a kind whose fields differ.
+package conversion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ErrNoConversionPath is returned when an object can't be converted to the requested version.
+var ErrNoConversionPath = errors.New("no conversion path")
+
+// movedKinds are the kinds served in more than one group, which may be converted between groups. The
+// last group is the one the kind moved to, and is stored in.
+var movedKinds = [][]schema.GroupKind{
+	{{Group: "extensions", Kind: "DaemonSet"}, {Group: "apps", Kind: "DaemonSet"}},
+	{{Group: "extensions", Kind: "Deployment"}, {Group: "apps", Kind: "Deployment"}},
+	{{Group: "extensions", Kind: "ReplicaSet"}, {Group: "apps", Kind: "ReplicaSet"}},
+	{{Group: "extensions", Kind: "Ingress"}, {Group: "networking.k8s.io", Kind: "Ingress"}},
+	{{Group: "extensions", Kind: "NetworkPolicy"}, {Group: "networking.k8s.io", Kind: "NetworkPolicy"}},
+	{{Group: "extensions", Kind: "PodSecurityPolicy"}, {Group: "policy", Kind: "PodSecurityPolicy"}},
+}
+
+// Convert converts an object to the given version of its kind. Fields of the object that don't exist
+// in the given version are dropped and returned by path.
+func Convert(s *runtime.Scheme, in *unstructured.Unstructured, version schema.GroupVersion) (runtime.Object, []string, error) {
+	from := in.GroupVersionKind()
+	to := version.WithKind(from.Kind)
+	if !convertible(from.GroupKind(), to.GroupKind()) {
+		return nil, nil, fmt.Errorf("%w from %s to %s: %s and %s are different kinds", ErrNoConversionPath, from.GroupVersion(), version, from.GroupKind(), to.GroupKind())
+	}
+	if !s.Recognizes(to) {
+		return nil, nil, fmt.Errorf("%w from %s to %s: %s is not served at %s%s", ErrNoConversionPath, from.GroupVersion(), version, from.Kind, version, servedAt(s, from.GroupKind()))
+	}
+
+	if !hasConversion(from, to) {
+		return nil, nil, fmt.Errorf("%w from %s to %s: the fields of %s differ between the versions and no conversion function is known", ErrNoConversionPath, from.GroupVersion(), version, from.Kind)
+	}
+
+	obj := in.DeepCopy().Object
+	for _, f := range conversionFuncs {
+		if f.applies(from, to) {
+			if err := f.convert(obj); err != nil {
+				return nil, nil, fmt.Errorf("error converting %s to %s: %w", from, version, err)
+			}
+		}
+	}
+	obj["apiVersion"] = version.String()
+
+	out, err := s.New(to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, out); err != nil {
+		return nil, nil, fmt.Errorf("error converting %s to %s: %w", from, version, err)
+	}
+	out.GetObjectKind().SetGroupVersionKind(to)
+	converted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error converting %s to %s: %w", from, version, err)
+	}
+	return out, droppedFields(obj, converted, ""), nil
+}
+
+func convertible(from, to schema.GroupKind) bool {
+	if from == to {
+		return true
+	}
+	for _, kinds := range movedKinds {
+		var hasFrom, hasTo bool
+		for _, gk := range kinds {
+			hasFrom = hasFrom || gk == from
+			hasTo = hasTo || gk == to
+		}
+		if hasFrom && hasTo {
+			return true
+		}
+	}
+	return false
+}
+
+// servedAt lists the versions a kind may be converted to, to help pick one.
+func servedAt(s *runtime.Scheme, gk schema.GroupKind) string {
+	var versions []string
+	for gvk := range s.AllKnownTypes() {
+		if convertible(gk, gvk.GroupKind()) {
+			versions = append(versions, gvk.GroupVersion().String())
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	sort.Strings(versions)
+	return fmt.Sprintf(", it is served at %s", strings.Join(versions, ", "))
+}
+
+// droppedFields returns the paths of the fields that are set in the given object, but not in the
+// converted object.
+func droppedFields(obj, converted map[string]interface{}, path string) []string {
+	var dropped []string
+	for k, v := range obj {
+		fieldPath := k
+		if path != "" {
+			fieldPath = path + "." + k
+		}
+		if isEmpty(v) {
+			continue
+		}
+		c, ok := converted[k]
+		if !ok {
+			dropped = append(dropped, fieldPath)
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if c, ok := c.(map[string]interface{}); ok {
+				dropped = append(dropped, droppedFields(v, c, fieldPath)...)
+			}
+		case []interface{}:
+			c, ok := c.([]interface{})
+			if !ok || len(c) != len(v) {
+				continue
+			}
+			for i := range v {
+				item, ok := v[i].(map[string]interface{})
+				convertedItem, convertedOk := c[i].(map[string]interface{})
+				if ok && convertedOk {
+					dropped = append(dropped, droppedFields(item, convertedItem, fmt.Sprintf("%s[%d]", fieldPath, i))...)
+				}
+			}
+		}
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/scheme"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		version schema.GroupVersion
+		want    string
+		dropped []string
+	}{
+		{
+			name:    "moved group",
+			in:      `{"apiVersion":"extensions/v1beta1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":2,"rollbackTo":{"revision":3},"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web"}},"spec":{"containers":[{"name":"web","image":"nginx"}]}}}}`,
+			version: schema.GroupVersion{Group: "apps", Version: "v1"},
+			want:    `{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"web"},"spec":{"replicas":2,"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web"}},"spec":{"containers":[{"name":"web","image":"nginx","resources":{}}]}},"strategy":{}},"status":{}}`,
+			dropped: []string{"spec.rollbackTo"},
+		},
+		{
+			name:    "empty pod disruption budget selector",
+			in:      `{"apiVersion":"policy/v1beta1","kind":"PodDisruptionBudget","metadata":{"name":"pdb"},"spec":{"minAvailable":1,"selector":{}}}`,
+			version: schema.GroupVersion{Group: "policy", Version: "v1"},
+			want:    `{"kind":"PodDisruptionBudget","apiVersion":"policy/v1","metadata":{"name":"pdb"},"spec":{"minAvailable":1,"selector":{"matchExpressions":[{"key":"pdb.kubernetes.io/deprecated-v1beta1-empty-selector-match","operator":"Exists"}]}},"status":{"disruptionsAllowed":0,"currentHealthy":0,"desiredHealthy":0,"expectedPods":0}}`,
+		},
+		{
+			name:    "empty pod disruption budget selector to v1beta1",
+			in:      `{"apiVersion":"policy/v1","kind":"PodDisruptionBudget","metadata":{"name":"pdb"},"spec":{"minAvailable":1,"selector":{}}}`,
+			version: schema.GroupVersion{Group: "policy", Version: "v1beta1"},
+			want:    `{"kind":"PodDisruptionBudget","apiVersion":"policy/v1beta1","metadata":{"name":"pdb"},"spec":{"minAvailable":1,"selector":{"matchExpressions":[{"key":"pdb.kubernetes.io/deprecated-v1beta1-empty-selector-match","operator":"DoesNotExist"}]}},"status":{"disruptionsAllowed":0,"currentHealthy":0,"desiredHealthy":0,"expectedPods":0}}`,
+		},
+		{
+			name:    "ingress backends",
+			in:      `{"apiVersion":"extensions/v1beta1","kind":"Ingress","metadata":{"name":"web"},"spec":{"backend":{"serviceName":"default","servicePort":80},"rules":[{"host":"example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"serviceName":"web","servicePort":"http"}}]}}]}}`,
+			version: schema.GroupVersion{Group: "networking.k8s.io", Version: "v1"},
+			want:    `{"kind":"Ingress","apiVersion":"networking.k8s.io/v1","metadata":{"name":"web"},"spec":{"defaultBackend":{"service":{"name":"default","port":{"number":80}}},"rules":[{"host":"example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"service":{"name":"web","port":{"name":"http"}}}}]}}]},"status":{"loadBalancer":{}}}`,
+		},
+		{
+			name:    "ingress backends to v1beta1",
+			in:      `{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","metadata":{"name":"web"},"spec":{"defaultBackend":{"service":{"name":"default","port":{"number":80}}}}}`,
+			version: schema.GroupVersion{Group: "networking.k8s.io", Version: "v1beta1"},
+			want:    `{"kind":"Ingress","apiVersion":"networking.k8s.io/v1beta1","metadata":{"name":"web"},"spec":{"backend":{"serviceName":"default","servicePort":80}},"status":{"loadBalancer":{}}}`,
+		},
+		{
+			name:    "cpu utilization",
+			in:      `{"apiVersion":"autoscaling/v1","kind":"HorizontalPodAutoscaler","metadata":{"name":"web"},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"targetCPUUtilizationPercentage":80},"status":{"currentReplicas":2,"desiredReplicas":2,"currentCPUUtilizationPercentage":50}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v2"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v2","metadata":{"name":"web"},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"metrics":[{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":80}}}]},"status":{"currentReplicas":2,"desiredReplicas":2,"currentMetrics":[{"type":"Resource","resource":{"name":"cpu","current":{"averageUtilization":50}}}]}}`,
+		},
+		{
+			name:    "cpu utilization to v1",
+			in:      `{"apiVersion":"autoscaling/v2","kind":"HorizontalPodAutoscaler","metadata":{"name":"web"},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"metrics":[{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":80}}},{"type":"Pods","pods":{"metric":{"name":"requests"},"target":{"type":"AverageValue","averageValue":"10"}}}]}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v1"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v1","metadata":{"name":"web","annotations":{"autoscaling.alpha.kubernetes.io/metrics":"[{\"pods\":{\"metricName\":\"requests\",\"targetAverageValue\":\"10\"},\"type\":\"Pods\"}]"}},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"targetCPUUtilizationPercentage":80},"status":{"currentReplicas":0,"desiredReplicas":0}}`,
+		},
+		{
+			name:    "v2beta1 metrics",
+			in:      `{"apiVersion":"autoscaling/v2beta1","kind":"HorizontalPodAutoscaler","metadata":{"name":"web"},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"metrics":[{"type":"Resource","resource":{"name":"cpu","targetAverageUtilization":50}},{"type":"External","external":{"metricName":"queue","metricSelector":{"matchLabels":{"queue":"jobs"}},"targetAverageValue":"30"}}]},"status":{"currentReplicas":2,"desiredReplicas":2,"currentMetrics":[{"type":"Resource","resource":{"name":"cpu","currentAverageUtilization":40,"currentAverageValue":"200m"}}],"conditions":[{"type":"AbleToScale","status":"True"}]}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v2"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v2","metadata":{"name":"web"},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"metrics":[{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":50}}},{"type":"External","external":{"metric":{"name":"queue","selector":{"matchLabels":{"queue":"jobs"}}},"target":{"type":"AverageValue","averageValue":"30"}}}]},"status":{"currentReplicas":2,"desiredReplicas":2,"currentMetrics":[{"type":"Resource","resource":{"name":"cpu","current":{"averageValue":"200m","averageUtilization":40}}}],"conditions":[{"type":"AbleToScale","status":"True","lastTransitionTime":null}]}}`,
+		},
+		{
+			name:    "metrics to v2beta1",
+			in:      `{"apiVersion":"autoscaling/v2","kind":"HorizontalPodAutoscaler","metadata":{"name":"web"},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"metrics":[{"type":"Object","object":{"describedObject":{"kind":"Ingress","name":"web"},"metric":{"name":"requests"},"target":{"type":"Value","value":"2k"}}}],"behavior":{"scaleDown":{"stabilizationWindowSeconds":60}}}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v2beta1"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v2beta1","metadata":{"name":"web","annotations":{"autoscaling.alpha.kubernetes.io/behavior":"{\"scaleDown\":{\"stabilizationWindowSeconds\":60}}"}},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"metrics":[{"type":"Object","object":{"target":{"kind":"Ingress","name":"web"},"metricName":"requests","targetValue":"2k"}}]},"status":{"currentReplicas":0,"desiredReplicas":0,"currentMetrics":null,"conditions":null}}`,
+		},
+		{
+			name:    "v1 annotations",
+			in:      `{"apiVersion":"autoscaling/v1","kind":"HorizontalPodAutoscaler","metadata":{"name":"web","annotations":{"autoscaling.alpha.kubernetes.io/metrics":"[{\"type\":\"Pods\",\"pods\":{\"metricName\":\"requests\",\"targetAverageValue\":\"10\"}}]","autoscaling.alpha.kubernetes.io/conditions":"[{\"type\":\"ScalingActive\",\"status\":\"True\"}]","team":"web"}},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"targetCPUUtilizationPercentage":80}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v2"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v2","metadata":{"name":"web","annotations":{"team":"web"}},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"metrics":[{"type":"Pods","pods":{"metric":{"name":"requests"},"target":{"type":"AverageValue","averageValue":"10"}}},{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":80}}}]},"status":{"desiredReplicas":0,"currentMetrics":null,"conditions":[{"type":"ScalingActive","status":"True","lastTransitionTime":null}]}}`,
+		},
+		{
+			name:    "cpu utilization to v2beta1",
+			in:      `{"apiVersion":"autoscaling/v1","kind":"HorizontalPodAutoscaler","metadata":{"name":"web"},"spec":{"maxReplicas":5,"scaleTargetRef":{"kind":"Deployment","name":"web"},"targetCPUUtilizationPercentage":80}}`,
+			version: schema.GroupVersion{Group: "autoscaling", Version: "v2beta1"},
+			want:    `{"kind":"HorizontalPodAutoscaler","apiVersion":"autoscaling/v2beta1","metadata":{"name":"web"},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"},"maxReplicas":5,"metrics":[{"type":"Resource","resource":{"name":"cpu","targetAverageUtilization":80}}]},"status":{"currentReplicas":0,"desiredReplicas":0,"currentMetrics":null,"conditions":null}}`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &unstructured.Unstructured{}
+			if err := in.UnmarshalJSON([]byte(tt.in)); err != nil {
+				t.Fatal(err)
+			}
+			out, dropped, err := Convert(scheme.Scheme, in, tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(dropped, tt.dropped) {
+				t.Errorf("got dropped fields %v, want %v", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestConvertNoPath(t *testing.T) {
+	cases := []struct {
+		in      string
+		version schema.GroupVersion
+		want    string
+	}{
+		{
+			in:      `{"apiVersion":"extensions/v1beta1","kind":"Deployment","metadata":{"name":"web"}}`,
+			version: schema.GroupVersion{Group: "batch", Version: "v1"},
+			want:    "no conversion path from extensions/v1beta1 to batch/v1: Deployment.extensions and Deployment.batch are different kinds",
+		},
+		{
+			in:      `{"apiVersion":"extensions/v1beta1","kind":"Deployment","metadata":{"name":"web"}}`,
+			version: schema.GroupVersion{Group: "apps", Version: "v2"},
+			want:    "no conversion path from extensions/v1beta1 to apps/v2: Deployment is not served at apps/v2, it is served at apps/v1, apps/v1beta1, apps/v1beta2, extensions/v1beta1",
+		},
+		{
+			in:      `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"widget"}}`,
+			version: schema.GroupVersion{Group: "example.com", Version: "v2"},
+			want:    "no conversion path from example.com/v1 to example.com/v2: Widget is not served at example.com/v2",
+		},
+		{
+			in:      `{"apiVersion":"discovery.k8s.io/v1beta1","kind":"EndpointSlice","metadata":{"name":"web"},"addressType":"IPv4"}`,
+			version: schema.GroupVersion{Group: "discovery.k8s.io", Version: "v1"},
+			want:    "no conversion path from discovery.k8s.io/v1beta1 to discovery.k8s.io/v1: the fields of EndpointSlice differ between the versions and no conversion function is known",
+		},
+	}
+	for _, tt := range cases {
+		in := &unstructured.Unstructured{}
+		if err := in.UnmarshalJSON([]byte(tt.in)); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := Convert(scheme.Scheme, in, tt.version)
+		if !errors.Is(err, ErrNoConversionPath) {
+			t.Errorf("got error %v, want %v", err, ErrNoConversionPath)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("got error:\n%s\nwant:\n%s", err, tt.want)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	cases := []struct {
+		gk   schema.GroupKind
+		want schema.GroupVersionKind
+	}{
+		{schema.GroupKind{Kind: "Pod"}, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+		{schema.GroupKind{Group: "apps", Kind: "Deployment"}, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{schema.GroupKind{Group: "extensions", Kind: "Deployment"}, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{schema.GroupKind{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}, schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}},
+		{schema.GroupKind{Group: "events.k8s.io", Kind: "Event"}, schema.GroupVersionKind{Version: "v1", Kind: "Event"}},
+	}
+	for _, tt := range cases {
+		got, err := LatestVersion(scheme.Scheme, tt.gk)
+		if err != nil {
+			t.Errorf("%s: %v", tt.gk, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("got latest version %s of %s, want %s", got, tt.gk, tt.want)
+		}
+	}
+	if _, err := LatestVersion(scheme.Scheme, schema.GroupKind{Group: "example.com", Kind: "Widget"}); err == nil {
+		t.Error("expected an error for a kind that is not registered")
+	}
+}
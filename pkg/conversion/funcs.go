@@ -0,0 +1,237 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// conversionFunc adjusts an object, as a map of its JSON fields, converted between two sets of versions
+// of a kind. The conversion functions of k8s.io/kubernetes for the internal types are the reference.
+type conversionFunc struct {
+	kind     string
+	from, to []schema.GroupVersion
+	convert  func(obj map[string]interface{}) error
+}
+
+func (f conversionFunc) applies(from, to schema.GroupVersionKind) bool {
+	return f.kind == from.Kind && containsVersion(f.from, from.GroupVersion()) && containsVersion(f.to, to.GroupVersion())
+}
+
+func containsVersion(versions []schema.GroupVersion, gv schema.GroupVersion) bool {
+	for _, v := range versions {
+		if v == gv {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	extensionsV1beta1   = schema.GroupVersion{Group: "extensions", Version: "v1beta1"}
+	networkingV1beta1   = schema.GroupVersion{Group: "networking.k8s.io", Version: "v1beta1"}
+	networkingV1        = schema.GroupVersion{Group: "networking.k8s.io", Version: "v1"}
+	policyV1beta1       = schema.GroupVersion{Group: "policy", Version: "v1beta1"}
+	policyV1            = schema.GroupVersion{Group: "policy", Version: "v1"}
+	autoscalingV1       = schema.GroupVersion{Group: "autoscaling", Version: "v1"}
+	autoscalingV2beta1  = schema.GroupVersion{Group: "autoscaling", Version: "v2beta1"}
+	autoscalingV2beta2  = schema.GroupVersion{Group: "autoscaling", Version: "v2beta2"}
+	autoscalingV2       = schema.GroupVersion{Group: "autoscaling", Version: "v2"}
+	discoveryV1beta1    = schema.GroupVersion{Group: "discovery.k8s.io", Version: "v1beta1"}
+	discoveryV1         = schema.GroupVersion{Group: "discovery.k8s.io", Version: "v1"}
+	nodeV1alpha1        = schema.GroupVersion{Group: "node.k8s.io", Version: "v1alpha1"}
+	nodeV1beta1         = schema.GroupVersion{Group: "node.k8s.io", Version: "v1beta1"}
+	nodeV1              = schema.GroupVersion{Group: "node.k8s.io", Version: "v1"}
+	flowcontrolV1beta1  = schema.GroupVersion{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1"}
+	flowcontrolV1beta2  = schema.GroupVersion{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2"}
+	flowcontrolV1beta3  = schema.GroupVersion{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3"}
+	flowcontrolV1       = schema.GroupVersion{Group: "flowcontrol.apiserver.k8s.io", Version: "v1"}
+	ingressV1beta1      = []schema.GroupVersion{extensionsV1beta1, networkingV1beta1}
+	autoscalingMetricV2 = []schema.GroupVersion{autoscalingV2beta2, autoscalingV2}
+)
+
+// versionSchemas are the kinds whose fields changed between versions, by the sets of versions that share
+// their fields. Objects are converted field by field within a set, but between sets only by conversion
+// functions.
+var versionSchemas = map[string][][]schema.GroupVersion{
+	"PodDisruptionBudget":        {{policyV1beta1}, {policyV1}},
+	"Ingress":                    {ingressV1beta1, {networkingV1}},
+	"HorizontalPodAutoscaler":    {{autoscalingV1}, {autoscalingV2beta1}, autoscalingMetricV2},
+	"EndpointSlice":              {{discoveryV1beta1}, {discoveryV1}},
+	"RuntimeClass":               {{nodeV1alpha1}, {nodeV1beta1, nodeV1}},
+	"PriorityLevelConfiguration": {{flowcontrolV1beta1, flowcontrolV1beta2}, {flowcontrolV1beta3, flowcontrolV1}},
+}
+
+var conversionFuncs = []conversionFunc{
+	{kind: "PodDisruptionBudget", from: []schema.GroupVersion{policyV1beta1}, to: []schema.GroupVersion{policyV1}, convert: convertPodDisruptionBudgetFromV1beta1},
+	{kind: "PodDisruptionBudget", from: []schema.GroupVersion{policyV1}, to: []schema.GroupVersion{policyV1beta1}, convert: convertPodDisruptionBudgetToV1beta1},
+	{kind: "Ingress", from: ingressV1beta1, to: []schema.GroupVersion{networkingV1}, convert: convertIngressFromV1beta1},
+	{kind: "Ingress", from: []schema.GroupVersion{networkingV1}, to: ingressV1beta1, convert: convertIngressToV1beta1},
+	{kind: "HorizontalPodAutoscaler", from: []schema.GroupVersion{autoscalingV1}, to: autoscalingMetricV2, convert: convertHorizontalPodAutoscalerFromV1},
+	{kind: "HorizontalPodAutoscaler", from: autoscalingMetricV2, to: []schema.GroupVersion{autoscalingV1}, convert: convertHorizontalPodAutoscalerToV1},
+	{kind: "HorizontalPodAutoscaler", from: []schema.GroupVersion{autoscalingV2beta1}, to: autoscalingMetricV2, convert: convertHorizontalPodAutoscalerFromV2beta1},
+	{kind: "HorizontalPodAutoscaler", from: autoscalingMetricV2, to: []schema.GroupVersion{autoscalingV2beta1}, convert: convertHorizontalPodAutoscalerToV2beta1},
+	{kind: "HorizontalPodAutoscaler", from: []schema.GroupVersion{autoscalingV1}, to: []schema.GroupVersion{autoscalingV2beta1}, convert: chain(convertHorizontalPodAutoscalerFromV1, convertHorizontalPodAutoscalerToV2beta1)},
+	{kind: "HorizontalPodAutoscaler", from: []schema.GroupVersion{autoscalingV2beta1}, to: []schema.GroupVersion{autoscalingV1}, convert: chain(convertHorizontalPodAutoscalerFromV2beta1, convertHorizontalPodAutoscalerToV1)},
+}
+
+// chain returns a conversion function that converts through the versions the given functions convert
+// between, the way kube-apiserver converts through the internal version.
+func chain(funcs ...func(obj map[string]interface{}) error) func(obj map[string]interface{}) error {
+	return func(obj map[string]interface{}) error {
+		for _, f := range funcs {
+			if err := f(obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// hasConversion returns whether an object may be converted between two versions of its kind.
+func hasConversion(from, to schema.GroupVersionKind) bool {
+	schemas := versionSchemas[from.Kind]
+	fromSchema, toSchema := -1, -1
+	for i, versions := range schemas {
+		if containsVersion(versions, from.GroupVersion()) {
+			fromSchema = i
+		}
+		if containsVersion(versions, to.GroupVersion()) {
+			toSchema = i
+		}
+	}
+	if fromSchema == toSchema {
+		return true
+	}
+	for _, f := range conversionFuncs {
+		if f.applies(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// pdbV1beta1SelectorKey is the label key of the selectors that preserve the meaning of empty policy/v1beta1
+// selectors, which match no pods where empty policy/v1 selectors match all pods.
+const pdbV1beta1SelectorKey = "pdb.kubernetes.io/deprecated-v1beta1-empty-selector-match"
+
+var (
+	emptySelector     = map[string]interface{}{}
+	matchNoneSelector = pdbV1beta1Selector("Exists")
+	matchAllSelector  = pdbV1beta1Selector("DoesNotExist")
+)
+
+func pdbV1beta1Selector(operator string) map[string]interface{} {
+	return map[string]interface{}{
+		"matchExpressions": []interface{}{map[string]interface{}{"key": pdbV1beta1SelectorKey, "operator": operator}},
+	}
+}
+
+func convertPodDisruptionBudgetFromV1beta1(obj map[string]interface{}) error {
+	return replaceSelector(obj, [][2]map[string]interface{}{{emptySelector, matchNoneSelector}, {matchAllSelector, emptySelector}})
+}
+
+func convertPodDisruptionBudgetToV1beta1(obj map[string]interface{}) error {
+	return replaceSelector(obj, [][2]map[string]interface{}{{emptySelector, matchAllSelector}, {matchNoneSelector, emptySelector}})
+}
+
+// replaceSelector replaces spec.selector by the second selector of the first pair whose first selector
+// it is equal to.
+func replaceSelector(obj map[string]interface{}, replacements [][2]map[string]interface{}) error {
+	selector, found, err := unstructured.NestedMap(obj, "spec", "selector")
+	if err != nil || !found {
+		return err
+	}
+	for _, r := range replacements {
+		if reflect.DeepEqual(selector, r[0]) {
+			return unstructured.SetNestedMap(obj, r[1], "spec", "selector")
+		}
+	}
+	return nil
+}
+
+// convertIngressFromV1beta1 converts backends, which name their service and port with the serviceName
+// and servicePort fields, to networking.k8s.io/v1 backends, which have a service field. The default
+// backend is renamed from backend to defaultBackend.
+func convertIngressFromV1beta1(obj map[string]interface{}) error {
+	forEachIngressBackend(obj, "backend", "defaultBackend", func(backend map[string]interface{}) {
+		name, hasName := backend["serviceName"]
+		port, hasPort := backend["servicePort"]
+		if !hasName && !hasPort {
+			return
+		}
+		delete(backend, "serviceName")
+		delete(backend, "servicePort")
+		service := map[string]interface{}{"name": name}
+		switch port := port.(type) {
+		case string:
+			service["port"] = map[string]interface{}{"name": port}
+		case nil:
+		default:
+			service["port"] = map[string]interface{}{"number": port}
+		}
+		backend["service"] = service
+	})
+	return nil
+}
+
+func convertIngressToV1beta1(obj map[string]interface{}) error {
+	forEachIngressBackend(obj, "defaultBackend", "backend", func(backend map[string]interface{}) {
+		service, ok := backend["service"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		delete(backend, "service")
+		backend["serviceName"] = service["name"]
+		port, _ := service["port"].(map[string]interface{})
+		if name, ok := port["name"].(string); ok && name != "" {
+			backend["servicePort"] = name
+		} else if number, ok := port["number"]; ok {
+			backend["servicePort"] = number
+		}
+	})
+	return nil
+}
+
+// forEachIngressBackend renames the default backend of an ingress and calls f with it and the backends
+// of all paths of its rules.
+func forEachIngressBackend(obj map[string]interface{}, defaultBackend, renamed string, f func(backend map[string]interface{})) {
+	spec, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if backend, ok := spec[defaultBackend].(map[string]interface{}); ok {
+		delete(spec, defaultBackend)
+		spec[renamed] = backend
+		f(backend)
+	}
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		rule, _ := rule.(map[string]interface{})
+		paths, _, _ := unstructured.NestedFieldNoCopy(rule, "http", "paths")
+		items, _ := paths.([]interface{})
+		for _, path := range items {
+			path, _ := path.(map[string]interface{})
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				f(backend)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// storedAs are the kinds served as another kind, which they are stored as.
+var storedAs = map[schema.GroupKind]schema.GroupKind{
+	{Group: "events.k8s.io", Kind: "Event"}: {Kind: "Event"},
+}
+
+// LatestVersion returns the highest version of the given kind in the scheme, in the order kube-apiserver
+// prioritizes versions, e.g. v1 before v1beta1 and v2 before v1, of the group the kind is stored in.
+//
+// It approximates the version kube-apiserver stores objects of the kind at, which depends on the release
+// and configuration of kube-apiserver: a release may keep storing a kind at an older version than the
+// latest it serves, and kinds whose versions are all newer than the scheme's are reported at the latest
+// version the scheme has.
+func LatestVersion(s *runtime.Scheme, gk schema.GroupKind) (schema.GroupVersionKind, error) {
+	stored := gk
+	if as, ok := storedAs[gk]; ok {
+		stored = as
+	}
+	for _, kinds := range movedKinds {
+		for _, moved := range kinds {
+			if moved == gk {
+				stored = kinds[len(kinds)-1]
+			}
+		}
+	}
+
+	var latest schema.GroupVersionKind
+	for gvk := range s.AllKnownTypes() {
+		if gvk.GroupKind() != stored {
+			continue
+		}
+		if latest.Empty() || version.CompareKubeAwareVersionStrings(gvk.Version, latest.Version) > 0 {
+			latest = gvk
+		}
+	}
+	if latest.Empty() {
+		return schema.GroupVersionKind{}, fmt.Errorf("%s is not registered in the scheme", stored)
+	}
+	return latest, nil
+}
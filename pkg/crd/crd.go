@@ -0,0 +1,283 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crd checks custom resources against the structural schemas of the
+// CustomResourceDefinitions stored in the same etcd datastore.
+package crd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/etcd-io/auger/pkg/client"
+	"github.com/etcd-io/auger/pkg/data"
+	"github.com/etcd-io/auger/pkg/encoding"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/install"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuralpruning "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/cbor"
+	"sigs.k8s.io/yaml"
+)
+
+// KeyPrefix is the etcd key prefix kube-apiserver stores CustomResourceDefinitions under.
+const KeyPrefix = "/registry/apiextensions.k8s.io/customresourcedefinitions/"
+
+// GroupResource is the resource of CustomResourceDefinitions.
+var GroupResource = schema.GroupResource{Group: apiextensions.GroupName, Resource: "customresourcedefinitions"}
+
+var (
+	crdScheme = runtime.NewScheme()
+	crdCodecs = serializer.NewCodecFactory(crdScheme, serializer.WithSerializer(cbor.NewSerializerInfo))
+)
+
+func init() {
+	install.Install(crdScheme)
+}
+
+// Registry indexes CustomResourceDefinitions by the group and kind of their custom resources.
+type Registry struct {
+	definitions map[schema.GroupKind]*definition
+}
+
+type definition struct {
+	name                  string
+	storageVersion        string
+	preserveUnknownFields bool
+	versions              map[string]*version
+}
+
+type version struct {
+	served     bool
+	structural *structuralschema.Structural
+	validator  validation.SchemaValidator
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{definitions: map[schema.GroupKind]*definition{}}
+}
+
+// LoadFromDB loads the latest CustomResourceDefinitions, at the given revision if greater than 0, from
+// a boltdb '.db' file. Values are decrypted with the given decrypter first, if any.
+func LoadFromDB(filename string, revision int64, decrypter data.ValueDecrypter) (*Registry, error) {
+	kvs, err := data.ListValues(filename, KeyPrefix, revision)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRegistry()
+	for _, kv := range kvs {
+		value := kv.Value
+		if decrypter != nil {
+			value, err = decrypter.Decrypt(string(kv.Key), value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", kv.Key, err)
+			}
+		}
+		if err := r.Add(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", kv.Key, err)
+		}
+	}
+	return r, nil
+}
+
+// LoadFromClient loads the CustomResourceDefinitions stored under the given prefix, e.g. '/registry',
+// of a live etcd. Values are decrypted with the given decrypter first, if any.
+func LoadFromClient(ctx context.Context, etcdclient client.Client, prefix string, decrypter data.ValueDecrypter) (*Registry, error) {
+	r := NewRegistry()
+	_, err := etcdclient.Get(ctx, prefix,
+		client.WithGroupResource(GroupResource),
+		client.WithResponse(func(kv *client.KeyValue) error {
+			value := kv.Value
+			if decrypter != nil {
+				var err error
+				value, err = decrypter.Decrypt(string(kv.Key), value)
+				if err != nil {
+					return fmt.Errorf("%s: %w", kv.Key, err)
+				}
+			}
+			if err := r.Add(value); err != nil {
+				return fmt.Errorf("%s: %w", kv.Key, err)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Add decodes a CustomResourceDefinition, as stored in etcd in any apiextensions.k8s.io version, and adds
+// it to the registry.
+func (r *Registry) Add(value []byte) error {
+	_, in, err := encoding.DetectAndExtract(value)
+	if err != nil {
+		return err
+	}
+	obj, _, err := crdCodecs.UniversalDecoder().Decode(in, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error decoding CustomResourceDefinition: %w", err)
+	}
+	crd, ok := obj.(*apiextensions.CustomResourceDefinition)
+	if !ok {
+		return fmt.Errorf("expected a CustomResourceDefinition, got %T", obj)
+	}
+
+	def := &definition{
+		name:                  crd.Name,
+		preserveUnknownFields: crd.Spec.PreserveUnknownFields != nil && *crd.Spec.PreserveUnknownFields,
+		versions:              map[string]*version{},
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			def.storageVersion = v.Name
+		}
+		ver := &version{served: v.Served}
+		validationSchema, err := apiextensions.GetSchemaForVersion(crd, v.Name)
+		if err != nil {
+			return err
+		}
+		if validationSchema != nil && validationSchema.OpenAPIV3Schema != nil {
+			ver.structural, err = structuralschema.NewStructural(validationSchema.OpenAPIV3Schema)
+			if err != nil {
+				return fmt.Errorf("CustomResourceDefinition %s version %s does not have a structural schema: %w", crd.Name, v.Name, err)
+			}
+			ver.validator, _, err = validation.NewSchemaValidator(validationSchema.OpenAPIV3Schema)
+			if err != nil {
+				return fmt.Errorf("invalid schema for CustomResourceDefinition %s version %s: %w", crd.Name, v.Name, err)
+			}
+		}
+		def.versions[v.Name] = ver
+	}
+	r.definitions[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = def
+	return nil
+}
+
+// Result is the outcome of checking a custom resource against the schema of its CustomResourceDefinition.
+type Result struct {
+	// Object is the custom resource.
+	Object *unstructured.Unstructured
+	// CRD is the name of the CustomResourceDefinition of the custom resource.
+	CRD string
+	// Version is the version the custom resource is stored at.
+	Version string
+	// StorageVersion is the version the CustomResourceDefinition currently stores custom resources at.
+	StorageVersion string
+	// Defined is set if Version is one of the versions of the CustomResourceDefinition.
+	Defined bool
+	// Served is set if Version is served by the CustomResourceDefinition.
+	Served bool
+	// Errors are the errors of validating the custom resource against the schema of its version.
+	Errors []string
+	// PrunedFields are the paths of fields not in the schema, kube-apiserver drops them when reading
+	// the custom resource.
+	PrunedFields []string
+}
+
+// Check decodes the given stored value and, if it is a custom resource of a CustomResourceDefinition in
+// the registry, checks it against its schema. A nil result is returned for any other value.
+func (r *Registry) Check(value []byte) (*Result, error) {
+	inMediaType, in, err := encoding.DetectAndExtract(value)
+	if err != nil {
+		return nil, err
+	}
+	typeMeta, err := encoding.DecodeTypeMeta(inMediaType, in)
+	if err != nil {
+		return nil, err
+	}
+	gvk := schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind)
+	def, ok := r.definitions[gvk.GroupKind()]
+	if !ok {
+		return nil, nil
+	}
+	js, _, err := encoding.Convert(crdCodecs, inMediaType, encoding.JsonMediaType, in)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(js); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Object:         obj,
+		CRD:            def.name,
+		Version:        gvk.Version,
+		StorageVersion: def.storageVersion,
+	}
+	ver, ok := def.versions[gvk.Version]
+	if !ok {
+		return result, nil
+	}
+	result.Defined = true
+	result.Served = ver.served
+	for _, fieldErr := range validation.ValidateCustomResource(nil, obj.UnstructuredContent(), ver.validator) {
+		result.Errors = append(result.Errors, fieldErr.Error())
+	}
+	sort.Strings(result.Errors)
+	if ver.structural != nil && !def.preserveUnknownFields {
+		pruned := runtime.DeepCopyJSON(obj.UnstructuredContent())
+		result.PrunedFields = structuralpruning.PruneWithOptions(pruned, ver.structural, true, structuralschema.UnknownFieldPathOptions{TrackUnknownFieldPaths: true})
+	}
+	return result, nil
+}
+
+// Warnings returns a human readable description of each problem found by the check, or none if the
+// custom resource is valid and stored at the storage version of its CustomResourceDefinition. A version
+// that is not defined is only reported as such, it is never the storage version.
+func (res *Result) Warnings() []string {
+	var warnings []string
+	if !res.Defined {
+		return append(warnings, fmt.Sprintf("stored at version %s, which is not a version of CustomResourceDefinition %s", res.Version, res.CRD))
+	}
+	if !res.Served {
+		warnings = append(warnings, fmt.Sprintf("stored at version %s, which is no longer served by CustomResourceDefinition %s", res.Version, res.CRD))
+	}
+	if res.Version != res.StorageVersion {
+		warnings = append(warnings, fmt.Sprintf("stored at version %s, but CustomResourceDefinition %s stores version %s", res.Version, res.CRD, res.StorageVersion))
+	}
+	for _, err := range res.Errors {
+		warnings = append(warnings, "invalid: "+err)
+	}
+	for _, field := range res.PrunedFields {
+		warnings = append(warnings, fmt.Sprintf("unknown field %q is pruned by CustomResourceDefinition %s", field, res.CRD))
+	}
+	return warnings
+}
+
+// Encode pretty-prints the custom resource as indented JSON or YAML.
+func (res *Result) Encode(outMediaType string) ([]byte, error) {
+	switch outMediaType {
+	case encoding.JsonMediaType:
+		js, err := json.MarshalIndent(res.Object.UnstructuredContent(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(js, '\n'), nil
+	case encoding.YamlMediaType:
+		return yaml.Marshal(res.Object.UnstructuredContent())
+	default:
+		return nil, fmt.Errorf("unsupported media type %s for custom resources", outMediaType)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/scheme"
+)
+
+const crontabSchema = `{"openAPIV3Schema": {"type": "object", "properties": {"spec": {"type": "object", "properties": {
+  "cronSpec": {"type": "string"},
+  "replicas": {"type": "integer", "minimum": 1}}}}}}`
+
+var crontabCRD = `{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition",
+"metadata": {"name": "crontabs.stable.example.com"},
+"spec": {"group": "stable.example.com", "scope": "Namespaced",
+  "names": {"plural": "crontabs", "singular": "crontab", "kind": "CronTab"},
+  "versions": [
+    {"name": "v1alpha1", "served": false, "storage": false, "schema": ` + crontabSchema + `},
+    {"name": "v1", "served": true, "storage": true, "schema": ` + crontabSchema + `},
+    {"name": "v2", "served": true, "storage": false, "schema": ` + crontabSchema + `}]}}`
+
+// widgetCRD is a v1beta1 CustomResourceDefinition, which preserves unknown fields by default.
+var widgetCRD = `{"apiVersion": "apiextensions.k8s.io/v1beta1", "kind": "CustomResourceDefinition",
+"metadata": {"name": "widgets.example.com"},
+"spec": {"group": "example.com", "version": "v1", "scope": "Namespaced",
+  "names": {"plural": "widgets", "singular": "widget", "kind": "Widget"},
+  "validation": {"openAPIV3Schema": {"properties": {"spec": {"properties": {"size": {"type": "integer"}}}}}}}}`
+
+func crontab(version, spec string) string {
+	return `{"apiVersion": "stable.example.com/` + version + `", "kind": "CronTab", "metadata": {"name": "cron", "namespace": "default"}, "spec": ` + spec + `}`
+}
+
+func TestCheck(t *testing.T) {
+	r := NewRegistry()
+	for _, crd := range []string{crontabCRD, widgetCRD} {
+		if err := r.Add([]byte(crd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name     string
+		value    string
+		warnings []string
+	}{
+		{
+			name:  "valid",
+			value: crontab("v1", `{"cronSpec": "* * * * */5", "replicas": 1}`),
+		},
+		{
+			name:  "invalid",
+			value: crontab("v1", `{"cronSpec": 5, "replicas": 0}`),
+			warnings: []string{
+				`invalid: spec.cronSpec: Invalid value: "integer": spec.cronSpec in body must be of type string: "integer"`,
+				`invalid: spec.replicas: Invalid value: 0: spec.replicas in body should be greater than or equal to 1`,
+			},
+		},
+		{
+			name:     "pruned",
+			value:    crontab("v1", `{"cronSpec": "* * * * */5", "image": "cron"}`),
+			warnings: []string{`unknown field "spec.image" is pruned by CustomResourceDefinition crontabs.stable.example.com`},
+		},
+		{
+			name:     "not-storage-version",
+			value:    crontab("v2", `{}`),
+			warnings: []string{"stored at version v2, but CustomResourceDefinition crontabs.stable.example.com stores version v1"},
+		},
+		{
+			name:  "not-served",
+			value: crontab("v1alpha1", `{}`),
+			warnings: []string{
+				"stored at version v1alpha1, which is no longer served by CustomResourceDefinition crontabs.stable.example.com",
+				"stored at version v1alpha1, but CustomResourceDefinition crontabs.stable.example.com stores version v1",
+			},
+		},
+		{
+			name:     "not-defined",
+			value:    crontab("v0", `{}`),
+			warnings: []string{"stored at version v0, which is not a version of CustomResourceDefinition crontabs.stable.example.com"},
+		},
+		{
+			name:  "preserve-unknown-fields",
+			value: `{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w"}, "spec": {"size": 3, "color": "blue"}}`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := r.Check([]byte(tt.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result == nil {
+				t.Fatal("expected a result for a custom resource")
+			}
+			if warnings := result.Warnings(); !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("got warnings %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestCheckIgnoresOtherValues(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add([]byte(crontabCRD)); err != nil {
+		t.Fatal(err)
+	}
+	pod, _, err := encoding.Convert(scheme.Codecs, encoding.JsonMediaType, encoding.StorageBinaryMediaType,
+		[]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range [][]byte{pod, []byte(`{"apiVersion": "other.example.com/v1", "kind": "CronTab"}`)} {
+		result, err := r.Check(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != nil {
+			t.Errorf("got result %+v for %q, expected none", result, value)
+		}
+	}
+}
+
+func TestCheckCbor(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add([]byte(crontabCRD)); err != nil {
+		t.Fatal(err)
+	}
+	value, _, err := encoding.Convert(scheme.Codecs, encoding.JsonMediaType, encoding.CborMediaType, []byte(crontab("v1", `{"cronSpec": "* * * * */5"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := r.Check(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || len(result.Warnings()) != 0 {
+		t.Fatalf("got %+v, expected a valid result", result)
+	}
+	yaml, err := result.Encode(encoding.YamlMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(yaml), "cronSpec: '* * * * */5'") {
+		t.Errorf("got %s, expected the custom resource spec", yaml)
+	}
+}
+
+func TestAddInvalid(t *testing.T) {
+	r := NewRegistry()
+	err := r.Add([]byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "cm"}}`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
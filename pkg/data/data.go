@@ -20,10 +20,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"hash/crc32"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"text/template"
@@ -44,7 +43,8 @@ var (
 	finishedCompactKeyName = []byte("finishedCompactRev")
 )
 
-// KeySummary represents a kubernetes object stored in etcd.
+// KeySummary represents a kubernetes object stored in etcd. Its Value and TypeMeta are decoded from the
+// latest revision of the key, whose Version and ValueSize it has.
 type KeySummary struct {
 	Key      string
 	Version  int64
@@ -84,11 +84,25 @@ type KeySummaryProjection struct {
 
 var ProjectEverything = &KeySummaryProjection{HasKey: true, HasValue: true}
 
+// ValueDecrypter decrypts values that kube-apiserver encrypted at rest before they are decoded. It must be
+// safe for concurrent use if values are decoded by more than one worker, see Store.Parallelism.
+type ValueDecrypter interface {
+	Decrypt(key string, value []byte) ([]byte, error)
+}
+
 // Filter declares an interface for filtering KeySummary results.
 type Filter interface {
 	Accept(ks *KeySummary) (bool, error)
 }
 
+// ProjectedFilter is a Filter that declares which optional fields of KeySummary it needs. Values are only
+// decoded if the projection or a filter needs them; filters that don't implement ProjectedFilter are
+// assumed to need all fields.
+type ProjectedFilter interface {
+	Filter
+	Projection() *KeySummaryProjection
+}
+
 // PrefixFilter filter by key prefix.
 type PrefixFilter struct {
 	prefix string
@@ -102,6 +116,14 @@ func (ff *PrefixFilter) Accept(ks *KeySummary) (bool, error) {
 	return strings.HasPrefix(ks.Key, ff.prefix), nil
 }
 
+func (ff *PrefixFilter) Projection() *KeySummaryProjection {
+	return &KeySummaryProjection{HasKey: true}
+}
+
+// wholeSummaryPattern matches template expressions that refer to the whole KeySummary, e.g. '.' or
+// 'printf "%v" .', rather than to its fields.
+var wholeSummaryPattern = regexp.MustCompile(`\.([^A-Za-z_]|$)`)
+
 type ConstraintOp int
 
 const (
@@ -156,6 +178,18 @@ func (ff *FieldFilter) Accept(ks *KeySummary) (bool, error) {
 	}
 }
 
+// Projection returns the fields the constraint looks up, by name, e.g. only the value for
+// '.Value.metadata.namespace' and neither the key nor the value for '.TypeMeta.Kind'.
+func (ff *FieldFilter) Projection() *KeySummaryProjection {
+	if wholeSummaryPattern.MatchString(ff.lhs) {
+		return ProjectEverything
+	}
+	return &KeySummaryProjection{
+		HasKey:   strings.Contains(ff.lhs, "Key"),
+		HasValue: strings.Contains(ff.lhs, "Value"),
+	}
+}
+
 type Checksum struct {
 	Hash            uint32
 	Revision        int64
@@ -182,281 +216,321 @@ func boltOpen(path string) (*bolt.DB, error) {
 // is checksumed.  The resulting hash is consistent particular revision in the presence of
 // compactions; so long as the revions itself has not been compacted, the hash never changes.
 func HashByRevision(filename string, revision int64) (Checksum, error) {
-	db, err := boltOpen(filename)
+	s, err := Open(filename)
 	if err != nil {
 		return Checksum{}, err
 	}
-	defer db.Close()
+	defer s.Close()
+	return s.Hash(revision)
+}
 
-	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
-	h.Write(keyBucket)
+// ListKeySummaries returns a result set with all the provided filters and projections applied.
+func ListKeySummaries(codecs serializer.CodecFactory, filename string, filters []Filter, proj *KeySummaryProjection, revision int64) ([]*KeySummary, error) {
+	return ListKeySummariesWithDecrypter(codecs, filename, filters, proj, revision, nil)
+}
 
-	compactRevision, err := getCompactRevision(db)
+// ListKeySummariesWithDecrypter is like ListKeySummaries but decrypts each value with the given
+// decrypter, if any, before it is decoded.
+func ListKeySummariesWithDecrypter(codecs serializer.CodecFactory, filename string, filters []Filter, proj *KeySummaryProjection, revision int64, decrypter ValueDecrypter) ([]*KeySummary, error) {
+	s, err := Open(filename)
 	if err != nil {
-		return Checksum{}, err
+		return nil, err
 	}
-	latestRevision := compactRevision
-	err = walkRevision(db, revision, func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
-		if r.main > latestRevision {
-			latestRevision = r.main
-		}
-		h.Write(kv.Key)
-		h.Write(kv.Value)
-		return false, nil
-	})
+	defer s.Close()
+	return s.KeySummaries(codecs, filters, proj, revision, decrypter)
+}
+
+// WalkKeySummaries is like ListKeySummariesWithDecrypter but calls f with each KeySummary, in key order,
+// rather than returning them all. See Store.WalkKeySummaries.
+func WalkKeySummaries(codecs serializer.CodecFactory, filename string, filters []Filter, proj *KeySummaryProjection, revision int64, decrypter ValueDecrypter, f func(ks *KeySummary) error) error {
+	s, err := Open(filename)
 	if err != nil {
-		return Checksum{}, err
+		return err
 	}
-	return Checksum{h.Sum32(), latestRevision, compactRevision}, nil
+	defer s.Close()
+	return s.WalkKeySummaries(codecs, filters, proj, revision, decrypter, f)
 }
 
-func getCompactRevision(db *bolt.DB) (int64, error) {
-	compactRev := int64(0)
-	err := db.View(func(tx *bolt.Tx) error {
-		b, err := bucketOrError(tx, metaBucket)
-		if err != nil {
-			return err
-		}
-		finishedCompactBytes := b.Get(finishedCompactKeyName)
-		if len(finishedCompactBytes) != 0 {
-			compactRev = bytesToRev(finishedCompactBytes).main
-		}
+// KeySummaries returns a result set with all the provided filters and projections applied, decrypting
+// each value with the given decrypter, if any, before it is decoded.
+func (s *Store) KeySummaries(codecs serializer.CodecFactory, filters []Filter, proj *KeySummaryProjection, revision int64, decrypter ValueDecrypter) ([]*KeySummary, error) {
+	var result []*KeySummary
+	err := s.WalkKeySummaries(codecs, filters, proj, revision, decrypter, func(ks *KeySummary) error {
+		result = append(result, ks)
 		return nil
 	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return compactRev, nil
+	return result, nil
 }
 
-// ListKeySummaries returns a result set with all the provided filters and projections applied.
-func ListKeySummaries(codecs serializer.CodecFactory, filename string, filters []Filter, proj *KeySummaryProjection, revision int64) ([]*KeySummary, error) {
-	var err error
-	db, err := boltOpen(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer db.Close()
+// keyStats are the statistics of a key, and the revision of its latest version, since it was last
+// deleted.
+type keyStats struct {
+	rev     revKey
+	version int64
+	stats   KeySummaryStats
+}
 
+// WalkKeySummaries calls f with the KeySummary of each key, in key order, with all the provided filters
+// and projections applied. Each value is decrypted with the given decrypter, if any, before it is decoded.
+//
+// The file is read in two passes, so that memory use doesn't grow with the size of the values: the first
+// collects the statistics of each key from all revisions, the second reads the latest value of one key at
+// a time and decodes it just before f is called with its KeySummary. Values are decoded by Parallelism
+// workers, a few at a time each; f is still called in key order, on the calling goroutine, but it may not
+// use the store while values are decoded by more than one worker.
+func (s *Store) WalkKeySummaries(codecs serializer.CodecFactory, filters []Filter, proj *KeySummaryProjection, revision int64, decrypter ValueDecrypter, f func(ks *KeySummary) error) error {
 	prefixFilter, filters := separatePrefixFilter(filters)
-	m := make(map[string]*KeySummary)
-	err = walk(db, func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
+	m := make(map[string]*keyStats)
+	err := s.walk(func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
 		if revision > 0 && r.main > revision {
-			return false, nil
-		}
-		if r.tombstone {
-			delete(m, string(kv.Key))
+			// Revisions are in order, all the remaining ones are too recent.
+			return true, nil
 		}
-		prefixAllowed := true
 		if prefixFilter != nil {
-			prefixAllowed, err = prefixFilter.Accept(&KeySummary{Key: string(kv.Key)})
-			if err != nil {
+			ok, err := prefixFilter.Accept(&KeySummary{Key: string(kv.Key)})
+			if err != nil || !ok {
 				return false, err
 			}
 		}
-		if prefixAllowed {
-			ks, ok := m[string(kv.Key)]
-			if !ok {
-				var buf []byte
-				var valJSON string
-				var typeMeta *runtime.TypeMeta
-				var err error
-				if buf, typeMeta, err = encoding.DetectAndConvert(codecs, encoding.JsonMediaType, kv.Value); err == nil {
-					valJSON = strings.TrimSpace(string(buf))
-				}
-				var key string
-				var value map[string]any
-				if proj.HasKey {
-					key = string(kv.Key)
-				}
-				// If the caller or filters need the value, we need to deserialize it.
-				// For filters we don't yet know if they need it, so if there are any filters we must include it.
-				if proj.HasValue || len(filters) > 0 {
-					value = rawJSONUnmarshal(valJSON)
-				}
-				ks = &KeySummary{
-					Key:     key,
-					Version: kv.Version,
-					Stats: &KeySummaryStats{
-						KeySize:              len(kv.Key),
-						ValueSize:            len(kv.Value),
-						AllVersionsKeySize:   len(kv.Key),
-						AllVersionsValueSize: len(kv.Value),
-						VersionCount:         1,
-					},
-					Value:    value,
-					TypeMeta: typeMeta,
-				}
-				for _, filter := range filters {
-					ok, err := filter.Accept(ks)
-					if err != nil {
-						return false, err
-					}
-					if !ok {
-						return false, nil
-					}
-				}
-				m[string(kv.Key)] = ks
-			} else {
-				if kv.Version > ks.Version {
-					ks.Version = kv.Version
-					ks.Stats.ValueSize = len(kv.Value)
-				}
-				ks.Stats.VersionCount++
-				ks.Stats.AllVersionsKeySize += len(kv.Key)
-				ks.Stats.AllVersionsValueSize += len(kv.Value)
+		ks, ok := m[string(kv.Key)]
+		if !ok || r.tombstone {
+			m[string(kv.Key)] = &keyStats{
+				rev:     r,
+				version: kv.Version,
+				stats: KeySummaryStats{
+					KeySize:              len(kv.Key),
+					ValueSize:            len(kv.Value),
+					AllVersionsKeySize:   len(kv.Key),
+					AllVersionsValueSize: len(kv.Value),
+					VersionCount:         1,
+				},
 			}
+			return false, nil
+		}
+		if kv.Version > ks.version {
+			ks.rev = r
+			ks.version = kv.Version
+			ks.stats.ValueSize = len(kv.Value)
 		}
+		ks.stats.VersionCount++
+		ks.stats.AllVersionsKeySize += len(kv.Key)
+		ks.stats.AllVersionsValueSize += len(kv.Value)
 		return false, nil
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	result := sortKeySummaries(m)
-	return result, nil
-}
 
-func sortKeySummaries(m map[string]*KeySummary) []*KeySummary {
-	result := make([]*KeySummary, len(m))
-	i := 0
-	for _, v := range m {
-		result[i] = v
-		i++
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	decoder := encoding.NewDecoder(codecs)
+	needs := filterProjection(proj, filters)
+	type summarized struct {
+		ks       *KeySummary
+		accepted bool
+		err      error
 	}
-	sort.Slice(result, func(i, j int) bool {
-		return strings.Compare(result[i].Key, result[j].Key) <= 0
+	read := func(yield func(*mvccpb.KeyValue) bool) error {
+		for _, key := range keys {
+			kv, err := s.keyValue(m[key].rev)
+			if err != nil {
+				return err
+			}
+			if !yield(kv) {
+				return nil
+			}
+		}
+		return nil
+	}
+	summarize := func(kv *mvccpb.KeyValue) summarized {
+		ks := summarizeKeyValue(decoder, decrypter, kv, m[string(kv.Key)], needs)
+		for _, filter := range filters {
+			accepted, err := filter.Accept(ks)
+			if err != nil || !accepted {
+				return summarized{err: err}
+			}
+		}
+		return summarized{ks: ks, accepted: true}
+	}
+	return pipeline(s.Parallelism, read, summarize, func(r summarized) error {
+		if r.err != nil || !r.accepted {
+			return r.err
+		}
+		return f(r.ks)
 	})
-
-	return result
 }
 
-// ListVersions lists all versions of a object with the given key.
-func ListVersions(filename string, key string) ([]int64, error) {
-	db, err := boltOpen(filename)
-	if err != nil {
-		return nil, err
+// filterProjection returns the fields of KeySummary that are needed by the projection or any filter.
+func filterProjection(proj *KeySummaryProjection, filters []Filter) *KeySummaryProjection {
+	needs := *proj
+	for _, filter := range filters {
+		fp := ProjectEverything
+		if pf, ok := filter.(ProjectedFilter); ok {
+			fp = pf.Projection()
+		}
+		needs.HasKey = needs.HasKey || fp.HasKey
+		needs.HasValue = needs.HasValue || fp.HasValue
 	}
-	defer db.Close()
-
-	var result []int64
+	return &needs
+}
 
-	err = walk(db, func(_ revKey, kv *mvccpb.KeyValue) (bool, error) {
-		if string(kv.Key) == key {
-			result = append(result, kv.Version)
+// summarizeKeyValue returns the KeySummary of the latest version of a key, with the fields of the
+// projection. The value is only converted to JSON if it is needed, otherwise just its TypeMeta is read,
+// e.g. from the runtime.Unknown envelope of protobuf.
+func summarizeKeyValue(decoder *encoding.Decoder, decrypter ValueDecrypter, kv *mvccpb.KeyValue, stats *keyStats, proj *KeySummaryProjection) *KeySummary {
+	var typeMeta *runtime.TypeMeta
+	var value map[string]any
+	var err error
+	stored := kv.Value
+	if decrypter != nil {
+		stored, err = decrypter.Decrypt(string(kv.Key), stored)
+	}
+	if err == nil {
+		inMediaType, in, err := encoding.DetectAndExtract(stored)
+		switch {
+		case err != nil:
+		case proj.HasValue:
+			var buf []byte
+			if buf, typeMeta, err = decoder.Convert(inMediaType, encoding.JsonMediaType, in); err == nil {
+				value = rawJSONUnmarshal(strings.TrimSpace(string(buf)))
+			}
+		default:
+			typeMeta, _ = encoding.DecodeTypeMeta(inMediaType, in)
 		}
-		return false, nil
-	})
+	}
+	var key string
+	if proj.HasKey {
+		key = string(kv.Key)
+	}
+	return &KeySummary{
+		Key:      key,
+		Version:  stats.version,
+		Stats:    &stats.stats,
+		Value:    value,
+		TypeMeta: typeMeta,
+	}
+}
+
+// ListVersions lists all versions of a object with the given key.
+func ListVersions(filename string, key string) ([]int64, error) {
+	s, err := Open(filename)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	defer s.Close()
+	return s.History(key)
 }
 
 // GetValue scans the bucket of the bolt db file for a etcd v3 record with the given key and returns the value.
 // Because bolt db files are indexed by revision
 func GetValue(filename string, key string, version int64) ([]byte, error) {
-	db, err := boltOpen(filename)
+	s, err := Open(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
-	var result []byte
-	found := false
-	err = walk(db, func(_ revKey, kv *mvccpb.KeyValue) (bool, error) {
-		if string(kv.Key) == key && kv.Version == version {
-			result = kv.Value
-			found = true
-			return true, nil
-		}
-		return false, nil
-	})
+	defer s.Close()
+	return s.Get(key, version)
+}
+
+// ListValues returns the latest key-value of each key with the given prefix, at the given revision if
+// greater than 0, in key order. Deleted keys are omitted. Values can be decoded to objects with
+// encoding.DecodeObject.
+func ListValues(filename string, prefix string, revision int64) ([]*mvccpb.KeyValue, error) {
+	s, err := Open(filename)
 	if err != nil {
 		return nil, err
 	}
-	if !found {
-		return nil, fmt.Errorf("key not found: %s", key)
-	}
-	return result, nil
-}
-
-type kvr struct {
-	kv  *mvccpb.KeyValue
-	rev revKey
+	defer s.Close()
+	return s.List(prefix, revision)
 }
 
-func walkRevision(db *bolt.DB, revision int64, f func(r revKey, kv *mvccpb.KeyValue) (bool, error)) error {
-	compactRev, err := getCompactRevision(db)
+// WalkKeyValues calls f with each revision of each key-value, in revision order. Deletions are included, as
+// key-values without a value.
+func WalkKeyValues(filename string, f func(kv *mvccpb.KeyValue) error) error {
+	s, err := Open(filename)
 	if err != nil {
 		return err
 	}
-	if revision > 0 && revision < compactRev {
-		return errors.New("required revision has been compacted")
-	}
+	defer s.Close()
+	return s.Iterate(f)
+}
 
-	m := map[string]kvr{}
-	err = walk(db, func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
-		if revision > 0 && r.main > revision {
-			return false, nil
-		}
-		k := string(kv.Key)
-		if m[k].rev.main < r.main {
-			m[k] = kvr{kv, r}
-		}
-		return false, nil
-	})
+// RewriteKeyValues copies the boltdb file in to a new boltdb file out, replacing each revision of each
+// key-value, deletions included, with the result of f. Revisions and all other buckets are copied as is.
+// If f fails, out is removed.
+func RewriteKeyValues(in, out string, f func(kv *mvccpb.KeyValue) (*mvccpb.KeyValue, error)) error {
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("file already exists: %s", out)
+	}
+	src, err := boltOpen(in)
 	if err != nil {
 		return err
 	}
-
-	sorted := make([]kvr, len(m))
-	i := 0
-	for _, v := range m {
-		sorted[i] = v
-		i++
+	defer src.Close()
+	dst, err := bolt.Open(out, 0o600, nil)
+	if err != nil {
+		return err
 	}
-	sort.Slice(sorted, func(i, j int) bool {
-		return bytes.Compare(sorted[i].kv.Key, sorted[j].kv.Key) <= 0
-	})
 
-	for _, s := range sorted {
-		if !s.rev.tombstone {
-			if _, err := f(s.rev, s.kv); err != nil {
-				return err
-			}
+	err = src.View(func(srcTx *bolt.Tx) error {
+		if _, err := bucketOrError(srcTx, keyBucket); err != nil {
+			return err
 		}
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			return srcTx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				copied, err := dstTx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(name, keyBucket) {
+					return copyBucket(b, copied)
+				}
+				return b.ForEach(func(k, v []byte) error {
+					kv := &mvccpb.KeyValue{}
+					if err := kv.Unmarshal(v); err != nil {
+						return err
+					}
+					rewritten, err := f(kv)
+					if err != nil {
+						return fmt.Errorf("error rewriting key %s: %w", kv.Key, err)
+					}
+					v, err = rewritten.Marshal()
+					if err != nil {
+						return err
+					}
+					return copied.Put(k, v)
+				})
+			})
+		})
+	})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
 	}
-	return nil
+	if err != nil {
+		os.Remove(out)
+	}
+	return err
 }
 
-func walk(db *bolt.DB, f func(r revKey, kv *mvccpb.KeyValue) (bool, error)) error {
-	err := db.View(func(tx *bolt.Tx) error {
-		b, err := bucketOrError(tx, keyBucket)
+// copyBucket copies the keys and nested buckets of src to dst.
+func copyBucket(src, dst *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
 		if err != nil {
 			return err
 		}
-		c := b.Cursor()
-
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			revision := bytesToRev(k)
-			kv := &mvccpb.KeyValue{}
-			err := kv.Unmarshal(v)
-			if err != nil {
-				return err
-			}
-			done, err := f(revision, kv)
-			if err != nil {
-				return fmt.Errorf("error handling key %s: %w", kv.Key, err)
-			}
-			if done {
-				break
-			}
-		}
-		return nil
+		return copyBucket(src.Bucket(k), nested)
 	})
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 func bucketOrError(tx *bolt.Tx, bucketName []byte) (*bolt.Bucket, error) {
@@ -17,14 +17,22 @@ limitations under the License.
 package data
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/etcd-io/auger/pkg/encoding"
 	"github.com/etcd-io/auger/pkg/scheme"
 
 	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -140,6 +148,216 @@ func TestListKeySummariesUsesKeyVersion(t *testing.T) {
 	}
 }
 
+func TestWalkKeySummaries(t *testing.T) {
+	configMap := func(name, value string) string {
+		return `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","namespace":"default"},"data":{"value":"` + value + `"}}`
+	}
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		for _, kv := range []struct {
+			rev       int64
+			tombstone bool
+			key       string
+			version   int64
+			value     string
+		}{
+			{1, false, "/registry/configmaps/default/b", 1, configMap("b", "1")},
+			{2, false, "/registry/configmaps/default/a", 1, configMap("a", "1")},
+			{3, false, "/registry/configmaps/default/b", 2, configMap("b", "2")},
+			{4, true, "/registry/configmaps/default/a", 0, ""},
+			{5, false, "/registry/configmaps/default/a", 1, configMap("a", "3")},
+			{6, false, "/registry/configmaps/default/b", 3, configMap("b", "3")},
+		} {
+			v, err := (&mvccpb.KeyValue{Key: []byte(kv.key), Value: []byte(kv.value), ModRevision: kv.rev, Version: kv.version}).Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(revToBytes(revKey{main: kv.rev, tombstone: kv.tombstone}), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	for _, tt := range []struct {
+		name     string
+		revision int64
+		filters  []Filter
+		want     []string
+	}{
+		{
+			name: "latest",
+			want: []string{"/registry/configmaps/default/a 1 3 2", "/registry/configmaps/default/b 3 3 3"},
+		},
+		{
+			// Values are those of the latest revision, not of the first one since the key was created.
+			name:     "latest value at revision",
+			revision: 5,
+			want:     []string{"/registry/configmaps/default/a 1 3 2", "/registry/configmaps/default/b 2 2 2"},
+		},
+		{
+			name:     "revision",
+			revision: 3,
+			want:     []string{"/registry/configmaps/default/a 1 1 1", "/registry/configmaps/default/b 2 2 2"},
+		},
+		{
+			name:     "deleted",
+			revision: 4,
+			want:     []string{"/registry/configmaps/default/a 0 <no value> 1", "/registry/configmaps/default/b 2 2 2"},
+		},
+		{
+			name:    "filters",
+			filters: []Filter{NewPrefixFilter("/registry/configmaps/"), mustBuildFilter(&FieldConstraint{lhs: ".Value.data.value", op: Equals, rhs: "3"})},
+			want:    []string{"/registry/configmaps/default/a 1 3 2", "/registry/configmaps/default/b 3 3 3"},
+		},
+		{
+			name:    "filtered out",
+			filters: []Filter{NewPrefixFilter("/registry/configmaps/default/b")},
+			want:    []string{"/registry/configmaps/default/b 3 3 3"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := WalkKeySummaries(scheme.Codecs, file, tt.filters, ProjectEverything, tt.revision, nil, func(ks *KeySummary) error {
+				value, _ := ks.Value.(map[string]any)
+				data, _ := value["data"].(map[string]any)
+				v, ok := data["value"]
+				if !ok {
+					v = "<no value>"
+				}
+				got = append(got, fmt.Sprintf("%s %d %v %d", ks.Key, ks.Version, v, ks.Stats.VersionCount))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Values are only decoded if the projection or a filter needs them.
+	for _, tt := range []struct {
+		name      string
+		filters   []Filter
+		wantValue bool
+	}{
+		{
+			name:    "type filter",
+			filters: []Filter{NewPrefixFilter("/registry/configmaps/default/a"), mustBuildFilter(&FieldConstraint{lhs: ".TypeMeta.Kind", op: Equals, rhs: "ConfigMap"})},
+		},
+		{
+			name:      "value filter",
+			filters:   []Filter{mustBuildFilter(&FieldConstraint{lhs: ".Value.metadata.name", op: Equals, rhs: "a"})},
+			wantValue: true,
+		},
+		{
+			name:      "filter without projection",
+			filters:   []Filter{acceptFunc(func(ks *KeySummary) (bool, error) { return ks.Key == "/registry/configmaps/default/a", nil })},
+			wantValue: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []*KeySummary
+			err := WalkKeySummaries(scheme.Codecs, file, tt.filters, &KeySummaryProjection{}, 0, nil, func(ks *KeySummary) error {
+				got = append(got, ks)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].TypeMeta == nil || got[0].TypeMeta.Kind != "ConfigMap" {
+				t.Fatalf("got %+v, want the summary of the ConfigMap a", got)
+			}
+			if value, _ := got[0].Value.(map[string]any); (value != nil) != tt.wantValue {
+				t.Errorf("got value %v, want it decoded: %t", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestFieldFilterProjection(t *testing.T) {
+	for _, tt := range []struct {
+		lhs  string
+		want KeySummaryProjection
+	}{
+		{lhs: ".TypeMeta.Kind", want: KeySummaryProjection{}},
+		{lhs: ".Value.metadata.namespace", want: KeySummaryProjection{HasValue: true}},
+		{lhs: ".ValueJSON", want: KeySummaryProjection{HasValue: true}},
+		{lhs: ".Key", want: KeySummaryProjection{HasKey: true}},
+		{lhs: ".", want: *ProjectEverything},
+		{lhs: `printf "%v" .`, want: *ProjectEverything},
+	} {
+		filter := mustBuildFilter(&FieldConstraint{lhs: tt.lhs, op: Equals, rhs: "x"}).(ProjectedFilter)
+		if got := filter.Projection(); *got != tt.want {
+			t.Errorf("got projection %+v for %s, want %+v", *got, tt.lhs, tt.want)
+		}
+	}
+}
+
+type acceptFunc func(ks *KeySummary) (bool, error)
+
+func (f acceptFunc) Accept(ks *KeySummary) (bool, error) {
+	return f(ks)
+}
+
+func TestPipeline(t *testing.T) {
+	read := func(n int, err error) func(yield func(int) bool) error {
+		return func(yield func(int) bool) error {
+			for i := 0; i < n; i++ {
+				if !yield(i) {
+					return nil
+				}
+			}
+			return err
+		}
+	}
+	square := func(i int) int { return i * i }
+
+	for _, parallelism := range []int{0, 1, 8} {
+		var got []int
+		err := pipeline(parallelism, read(100, nil), square, func(i int) error {
+			got = append(got, i)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range got {
+			if v != i*i {
+				t.Fatalf("got %d at %d with parallelism %d, want the results in order", v, i, parallelism)
+			}
+		}
+		if len(got) != 100 {
+			t.Errorf("got %d results with parallelism %d, want 100", len(got), parallelism)
+		}
+
+		emitted := 0
+		err = pipeline(parallelism, read(100, nil), square, func(i int) error {
+			emitted++
+			if emitted == 10 {
+				return errors.New("emit")
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "emit" || emitted != 10 {
+			t.Errorf("got %v after %d results with parallelism %d, want the error of emit after 10", err, emitted, parallelism)
+		}
+
+		err = pipeline(parallelism, read(3, errors.New("read")), square, func(int) error { return nil })
+		if err == nil || err.Error() != "read" {
+			t.Errorf("got %v with parallelism %d, want the error of read", err, parallelism)
+		}
+	}
+}
+
 func TestParseFilters(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -226,6 +444,410 @@ func TestHashByRevisionMissingMetaBucket(t *testing.T) {
 	}
 }
 
+func TestListValues(t *testing.T) {
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		for _, kv := range []struct {
+			rev       int64
+			tombstone bool
+			key       string
+			value     string
+		}{
+			{rev: 1, key: "/registry/configmaps/default/a", value: "a1"},
+			{rev: 2, key: "/registry/configmaps/default/b", value: "b1"},
+			{rev: 3, key: "/registry/configmaps/default/a", value: "a2"},
+			{rev: 4, key: "/registry/configmaps/default/b", tombstone: true},
+			{rev: 5, key: "/registry/secrets/default/c", value: "c1"},
+		} {
+			if err := putTestKeyValue(b, kv.rev, kv.tombstone, kv.key, kv.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	cases := []struct {
+		name     string
+		prefix   string
+		revision int64
+		want     []string
+	}{
+		{name: "latest", prefix: "/registry/configmaps/", want: []string{"/registry/configmaps/default/a=a2"}},
+		{name: "revision", prefix: "/registry/configmaps/", revision: 2, want: []string{"/registry/configmaps/default/a=a1", "/registry/configmaps/default/b=b1"}},
+		{name: "all", prefix: "/registry/", want: []string{"/registry/configmaps/default/a=a2", "/registry/secrets/default/c=c1"}},
+		{name: "none", prefix: "/registry/pods/"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			kvs, err := ListValues(file, tt.prefix, tt.revision)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, kv := range kvs {
+				got = append(got, string(kv.Key)+"="+string(kv.Value))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListValuesDecodeObject(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Data:       map[string]string{"k": "v"},
+	}
+	stored, err := encoding.EncodeObject(scheme.Codecs, cm, encoding.StorageBinaryMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		return putTestKeyValue(b, 1, false, "/registry/configmaps/default/a", string(stored))
+	})
+
+	kvs, err := ListValues(file, "/registry/configmaps/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 {
+		t.Fatalf("got %d values, want 1", len(kvs))
+	}
+	obj, err := encoding.DecodeObject(scheme.Codecs, kvs[0].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		t.Fatalf("got %T, want *corev1.ConfigMap", obj)
+	}
+	if !reflect.DeepEqual(got, cm) {
+		t.Errorf("got %+v, want %+v", got, cm)
+	}
+}
+
+func TestRewriteKeyValues(t *testing.T) {
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		if err := meta.Put([]byte("consistent_index"), []byte{0, 0, 0, 0, 0, 0, 0, 7}); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		if err := putTestKeyValue(b, 1, false, "/registry/configmaps/default/a", "a1"); err != nil {
+			return err
+		}
+		if err := putTestKeyValue(b, 2, false, "/registry/configmaps/default/b", "b1"); err != nil {
+			return err
+		}
+		return putTestKeyValue(b, 3, true, "/registry/configmaps/default/b", "")
+	})
+
+	out := filepath.Join(t.TempDir(), "out.db")
+	err := RewriteKeyValues(file, out, func(kv *mvccpb.KeyValue) (*mvccpb.KeyValue, error) {
+		rewritten := *kv
+		rewritten.Key = []byte(strings.ToUpper(string(kv.Key)))
+		rewritten.Value = []byte(strings.ToUpper(string(kv.Value)))
+		return &rewritten, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = WalkKeyValues(out, func(kv *mvccpb.KeyValue) error {
+		got = append(got, fmt.Sprintf("%d %s=%s", kv.ModRevision, kv.Key, kv.Value))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1 /REGISTRY/CONFIGMAPS/DEFAULT/A=A1", "2 /REGISTRY/CONFIGMAPS/DEFAULT/B=B1", "3 /REGISTRY/CONFIGMAPS/DEFAULT/B="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The deletion is still a tombstone, and the other buckets are copied.
+	kvs, err := ListValues(out, "/REGISTRY/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != "/REGISTRY/CONFIGMAPS/DEFAULT/A" {
+		t.Errorf("got %v, want only /REGISTRY/CONFIGMAPS/DEFAULT/A", kvs)
+	}
+	db, err := boltOpen(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get([]byte("consistent_index")); !reflect.DeepEqual(v, []byte{0, 0, 0, 0, 0, 0, 0, 7}) {
+			t.Errorf("got consistent_index %v, want 7", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RewriteKeyValues(file, out, nil); err == nil {
+		t.Error("expected an error for an existing output file")
+	}
+}
+
+func TestStore(t *testing.T) {
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		for _, kv := range []struct {
+			rev       int64
+			tombstone bool
+			key       string
+			version   int64
+			value     string
+		}{
+			{1, false, "/registry/configmaps/default/a", 1, "a1"},
+			{2, false, "/registry/configmaps/default/b", 1, "b1"},
+			{3, false, "/registry/configmaps/default/a", 2, "a2"},
+			{4, true, "/registry/configmaps/default/b", 0, ""},
+			{5, false, "/registry/configmaps/default/b", 1, "b3"},
+			{6, false, "/registry/secrets/default/c", 1, "c1"},
+		} {
+			v, err := (&mvccpb.KeyValue{Key: []byte(kv.key), Value: []byte(kv.value), ModRevision: kv.rev, Version: kv.version}).Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(revToBytes(revKey{main: kv.rev, tombstone: kv.tombstone}), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	s, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	versions, err := s.History("/registry/configmaps/default/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1, 0, 1}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("got versions %v, want %v", versions, want)
+	}
+	value, err := s.Get("/registry/configmaps/default/a", 2)
+	if err != nil || string(value) != "a2" {
+		t.Errorf("got %q, %v, want a2", value, err)
+	}
+	if _, err := s.Get("/registry/configmaps/default/a", 3); err == nil {
+		t.Error("expected an error for a missing version")
+	}
+
+	for _, tt := range []struct {
+		revision int64
+		want     []string
+	}{
+		{0, []string{"/registry/configmaps/default/a=a2", "/registry/configmaps/default/b=b3"}},
+		{4, []string{"/registry/configmaps/default/a=a2"}},
+		{2, []string{"/registry/configmaps/default/a=a1", "/registry/configmaps/default/b=b1"}},
+	} {
+		kvs, err := s.List("/registry/configmaps/", tt.revision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, kv := range kvs {
+			got = append(got, string(kv.Key)+"="+string(kv.Value))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got %q at revision %d, want %q", got, tt.revision, tt.want)
+		}
+	}
+
+	revisions := 0
+	if err := s.Iterate(func(*mvccpb.KeyValue) error {
+		revisions++
+		return nil
+	}); err != nil || revisions != 6 {
+		t.Errorf("got %d revisions, %v, want 6", revisions, err)
+	}
+
+	checksum, err := s.Hash(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := HashByRevision(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != want || checksum.Revision != 6 {
+		t.Errorf("got checksum %+v, want %+v at revision 6", checksum, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	setConsistentIndex := func(tx *bolt.Tx, index byte) error {
+		return tx.Bucket(metaBucket).Put(consistentIndexKeyName, []byte{0, 0, 0, 0, 0, 0, 0, index})
+	}
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		if err := setConsistentIndex(tx, 3); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		if err := putTestKeyValue(b, 1, false, "/registry/configmaps/default/a", "a1"); err != nil {
+			return err
+		}
+		if err := putTestKeyValue(b, 2, false, "/registry/configmaps/default/b", "b1"); err != nil {
+			return err
+		}
+		return putTestKeyValue(b, 3, true, "/registry/configmaps/default/a", "")
+	})
+
+	keys, revisions, err := WriteIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys != 2 || revisions != 3 {
+		t.Errorf("got %d keys and %d revisions, want 2 and 3", keys, revisions)
+	}
+
+	s, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := s.readIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := s.scanIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index, scanned) {
+		t.Errorf("got index %v, want %v", index, scanned)
+	}
+	value, err := s.Get("/registry/configmaps/default/b", 1)
+	if err != nil || string(value) != "b1" || s.IndexError() != nil {
+		t.Errorf("got %q, %v, index error %v, want b1 looked up with the index", value, err, s.IndexError())
+	}
+	s.Close()
+
+	// The index is not used once the file has changed.
+	db, err := bolt.Open(file, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := setConsistentIndex(tx, 4); err != nil {
+			return err
+		}
+		return putTestKeyValue(tx.Bucket(keyBucket), 4, false, "/registry/configmaps/default/a", "a2")
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	versions, err := s.History("/registry/configmaps/default/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Errorf("got versions %v, want those of 3 revisions", versions)
+	}
+	if err := s.IndexError(); err == nil || !strings.Contains(err.Error(), "older state") {
+		t.Errorf("got index error %v, want the index to be out of date", err)
+	}
+}
+
+func TestIndexTruncated(t *testing.T) {
+	file := createTestDB(t, func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket(metaBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(keyBucket)
+		if err != nil {
+			return err
+		}
+		return putTestKeyValue(b, 1, false, "/registry/configmaps/default/a", "a1")
+	})
+	if _, _, err := WriteIndex(file); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(IndexFilename(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(IndexFilename(file), buf[:len(buf)-3], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	value, err := s.Get("/registry/configmaps/default/a", 1)
+	if err != nil || string(value) != "a1" {
+		t.Errorf("got %q, %v, want a1", value, err)
+	}
+	if err := s.IndexError(); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("got index error %v, want the index to be truncated", err)
+	}
+}
+
+func putTestKeyValue(b *bolt.Bucket, rev int64, tombstone bool, key, value string) error {
+	revBytes := make([]byte, revBytesLen, markedRevBytesLen)
+	binary.BigEndian.PutUint64(revBytes[0:8], uint64(rev))
+	revBytes[8] = '_'
+	if tombstone {
+		revBytes = append(revBytes, markTombstone)
+	}
+	kv := &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), CreateRevision: rev, ModRevision: rev, Version: 1}
+	v, err := kv.Marshal()
+	if err != nil {
+		return err
+	}
+	return b.Put(revBytes, v)
+}
+
 func mustBuildFilter(fc *FieldConstraint) Filter {
 	filter, err := fc.BuildFilter()
 	if err != nil {
@@ -243,14 +865,14 @@ type keyVersionInfo struct {
 func collectKeyVersionInfo(t *testing.T, file string) map[string]keyVersionInfo {
 	t.Helper()
 
-	db, err := boltOpen(file)
+	s, err := Open(file)
 	if err != nil {
 		t.Fatalf("failed to open db file %s: %v", file, err)
 	}
-	defer db.Close()
+	defer s.Close()
 
 	info := make(map[string]keyVersionInfo)
-	err = walk(db, func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
+	err = s.walk(func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
 		key := string(kv.Key)
 		if r.tombstone {
 			delete(info, key)
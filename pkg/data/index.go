@@ -0,0 +1,238 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// indexMagic starts index files, followed by the version of their format.
+var indexMagic = []byte("auger-index\x00\x01")
+
+// consistentIndexKeyName is the key of the meta bucket holding the index of the last raft entry etcd
+// applied to the file. See etcd/server/storage/schema/cindex.go.
+var consistentIndexKeyName = []byte("consistent_index")
+
+// IndexFilename returns the name of the index file of a boltdb file, next to it.
+func IndexFilename(filename string) string {
+	return filename + ".index"
+}
+
+// indexHeader identifies the boltdb file an index was built from, so that it is not used once the file
+// has changed.
+type indexHeader struct {
+	size            int64
+	modTime         int64
+	consistentIndex uint64
+}
+
+// WriteIndex writes the key revision index of a boltdb file to IndexFilename(filename), replacing the index
+// it may have. It returns the number of keys and of revisions indexed.
+//
+// The index holds the revisions, versions and deletions of each key, not values or their offsets in the
+// file. Stores of the file read it rather than scanning all revisions to look up keys, e.g. by Get and
+// History, as long as the size, modification time and consistent index of the file are those it was built
+// from. Values are then read by their revision, the key of the bucket holding them, which boltdb finds
+// without scanning.
+func WriteIndex(filename string) (int, int, error) {
+	s, err := Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer s.Close()
+	header, err := s.indexHeader()
+	if err != nil {
+		return 0, 0, err
+	}
+	index, err := s.scanIndex()
+	if err != nil {
+		return 0, 0, err
+	}
+	keys, revisions, err := writeIndexFile(IndexFilename(filename), header, index)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error writing index of %s: %w", filename, err)
+	}
+	return keys, revisions, nil
+}
+
+// indexHeader returns the header of the index of the store.
+func (s *Store) indexHeader() (indexHeader, error) {
+	info, err := os.Stat(s.db.Path())
+	if err != nil {
+		return indexHeader{}, err
+	}
+	b, err := bucketOrError(s.tx, metaBucket)
+	if err != nil {
+		return indexHeader{}, err
+	}
+	var consistentIndex uint64
+	if v := b.Get(consistentIndexKeyName); len(v) == 8 {
+		consistentIndex = binary.BigEndian.Uint64(v)
+	}
+	return indexHeader{size: info.Size(), modTime: info.ModTime().UnixNano(), consistentIndex: consistentIndex}, nil
+}
+
+// readIndex reads the index file of the store. An error wrapping os.ErrNotExist is returned if there is
+// none, and an error if it was built from another state of the file.
+func (s *Store) readIndex() (map[string][]indexEntry, error) {
+	filename := IndexFilename(s.db.Path())
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	header, err := s.indexHeader()
+	if err != nil {
+		return nil, err
+	}
+	r := &indexReader{buf: buf}
+	if !bytes.HasPrefix(buf, indexMagic) {
+		return nil, fmt.Errorf("%s is not an index of a supported format", filename)
+	}
+	r.buf = r.buf[len(indexMagic):]
+	built := indexHeader{size: r.varint(), modTime: r.varint(), consistentIndex: r.uvarint()}
+	if r.err == nil && built != header {
+		return nil, fmt.Errorf("%s was built from an older state of the file", filename)
+	}
+
+	index := map[string][]indexEntry{}
+	for keys := r.uvarint(); keys > 0 && r.err == nil; keys-- {
+		key := string(r.bytes())
+		n := r.uvarint()
+		if n > uint64(len(r.buf)) {
+			// Each revision takes 4 bytes at least.
+			r.err = errTruncatedIndex
+			break
+		}
+		entries := make([]indexEntry, n)
+		for i := range entries {
+			entries[i].rev = revKey{main: r.varint(), sub: r.varint(), tombstone: r.uvarint() == 1}
+			entries[i].version = r.varint()
+		}
+		index[key] = entries
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", filename, r.err)
+	}
+	return index, nil
+}
+
+// writeIndexFile writes an index to filename, keys in key order, and returns the number of keys and of
+// revisions it holds. The file is replaced once written, so that it is never read partially written.
+func writeIndexFile(filename string, header indexHeader, index map[string][]indexEntry) (int, int, error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	var buf []byte
+	buf = append(buf, indexMagic...)
+	buf = binary.AppendVarint(buf, header.size)
+	buf = binary.AppendVarint(buf, header.modTime)
+	buf = binary.AppendUvarint(buf, header.consistentIndex)
+	buf = binary.AppendUvarint(buf, uint64(len(index)))
+	revisions := 0
+	for _, key := range sortedIndexKeys(index) {
+		buf = binary.AppendUvarint(buf, uint64(len(key)))
+		buf = append(buf, key...)
+		buf = binary.AppendUvarint(buf, uint64(len(index[key])))
+		for _, e := range index[key] {
+			tombstone := uint64(0)
+			if e.rev.tombstone {
+				tombstone = 1
+			}
+			buf = binary.AppendVarint(buf, e.rev.main)
+			buf = binary.AppendVarint(buf, e.rev.sub)
+			buf = binary.AppendUvarint(buf, tombstone)
+			buf = binary.AppendVarint(buf, e.version)
+			revisions++
+		}
+		if _, err := w.Write(buf); err != nil {
+			return 0, 0, err
+		}
+		buf = buf[:0]
+	}
+	if _, err := w.Write(buf); err != nil {
+		return 0, 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return 0, 0, err
+	}
+	return len(index), revisions, nil
+}
+
+// indexReader reads the varints and byte strings of an index file, until the first error.
+type indexReader struct {
+	buf []byte
+	err error
+}
+
+var errTruncatedIndex = errors.New("truncated index")
+
+func (r *indexReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errTruncatedIndex
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *indexReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errTruncatedIndex
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *indexReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.err = errTruncatedIndex
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package data
+
+import "sync"
+
+// pipelineItem is an item read by a pipeline, and the channel its worker sends the result to.
+type pipelineItem[I, O any] struct {
+	in  I
+	out chan O
+}
+
+// pipeline calls read, which yields items until yield returns false, and work with each item, in
+// parallelism workers, and then emit with the results in the order the items were read. Items are read by a
+// single goroutine, so read may use a bolt transaction, which is not safe for concurrent use, but work
+// must not. Results are emitted on the calling goroutine, until emit returns an error. All goroutines have
+// returned once pipeline returns.
+//
+// Without parallelism greater than 1, each item is read, worked on and emitted in turn on the calling
+// goroutine.
+func pipeline[I, O any](parallelism int, read func(yield func(I) bool) error, work func(I) O, emit func(O) error) error {
+	if parallelism <= 1 {
+		var emitErr error
+		err := read(func(in I) bool {
+			emitErr = emit(work(in))
+			return emitErr == nil
+		})
+		if emitErr != nil {
+			return emitErr
+		}
+		return err
+	}
+
+	items := make(chan *pipelineItem[I, O], parallelism)
+	ordered := make(chan *pipelineItem[I, O], 4*parallelism)
+	done := make(chan struct{})
+
+	var readErr error
+	var reader sync.WaitGroup
+	reader.Add(1)
+	go func() {
+		defer reader.Done()
+		defer close(ordered)
+		defer close(items)
+		readErr = read(func(in I) bool {
+			item := &pipelineItem[I, O]{in: in, out: make(chan O, 1)}
+			select {
+			case ordered <- item:
+			case <-done:
+				return false
+			}
+			select {
+			case items <- item:
+			case <-done:
+				return false
+			}
+			return true
+		})
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for item := range items {
+				item.out <- work(item.in)
+			}
+		}()
+	}
+
+	var emitErr error
+	for item := range ordered {
+		if emitErr = emit(<-item.out); emitErr != nil {
+			break
+		}
+	}
+	close(done)
+	reader.Wait()
+	workers.Wait()
+	if emitErr != nil {
+		return emitErr
+	}
+	return readErr
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"sort"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// Store is a read-only handle on a boltdb '.db' file. All its queries share one read transaction, so they
+// see the file as it was when it was opened. The revisions of each key are indexed by the first query that
+// looks up keys, so that further queries don't scan the whole file again, or read from the index file
+// written by WriteIndex.
+//
+// A Store must be closed, and may not be used concurrently.
+type Store struct {
+	// Parallelism is the number of workers decoding values for WalkKeySummaries and KeySummaries. Values
+	// are decoded one at a time unless it is greater than 1.
+	Parallelism int
+
+	db *bolt.DB
+	tx *bolt.Tx
+	// index holds the revisions of each key, in revision order, once it is built.
+	index map[string][]indexEntry
+	// sortedKeys are the keys of index, in key order.
+	sortedKeys []string
+	// indexErr is why the index file was not read to build index.
+	indexErr error
+}
+
+// indexEntry is a revision of a key.
+type indexEntry struct {
+	rev     revKey
+	version int64
+}
+
+// Open opens the boltdb file in read-only mode.
+func Open(filename string) (*Store, error) {
+	db, err := boltOpen(filename)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin(false)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, tx: tx}, nil
+}
+
+// Close ends the read transaction of the store and closes its file.
+func (s *Store) Close() error {
+	return errors.Join(s.tx.Rollback(), s.db.Close())
+}
+
+// Get returns the value of the given version of a key. Deletions have version 0 and no value.
+func (s *Store) Get(key string, version int64) ([]byte, error) {
+	if err := s.buildIndex(); err != nil {
+		return nil, err
+	}
+	for _, e := range s.index[key] {
+		if e.version == version {
+			kv, err := s.keyValue(e.rev)
+			if err != nil {
+				return nil, err
+			}
+			return kv.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("key not found: %s", key)
+}
+
+// History returns the versions of all revisions of a key, in revision order. Deletions have version 0.
+func (s *Store) History(key string) ([]int64, error) {
+	if err := s.buildIndex(); err != nil {
+		return nil, err
+	}
+	var result []int64
+	for _, e := range s.index[key] {
+		result = append(result, e.version)
+	}
+	return result, nil
+}
+
+// List returns the latest key-value of each key with the given prefix, at the given revision if greater
+// than 0, in key order. Deleted keys are omitted.
+func (s *Store) List(prefix string, revision int64) ([]*mvccpb.KeyValue, error) {
+	var result []*mvccpb.KeyValue
+	err := s.walkRevision(revision, func(_ revKey, kv *mvccpb.KeyValue) (bool, error) {
+		if strings.HasPrefix(string(kv.Key), prefix) {
+			result = append(result, kv)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Iterate calls f with each revision of each key-value, in revision order. Deletions are included, as
+// key-values without a value.
+func (s *Store) Iterate(f func(kv *mvccpb.KeyValue) error) error {
+	return s.walk(func(_ revKey, kv *mvccpb.KeyValue) (bool, error) {
+		return false, f(kv)
+	})
+}
+
+// Hash returns the checksum of the live keyspace at the given revision, or at the latest revision if
+// revision is 0. See HashByRevision.
+func (s *Store) Hash(revision int64) (Checksum, error) {
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	h.Write(keyBucket)
+
+	compactRevision, err := s.compactRevision()
+	if err != nil {
+		return Checksum{}, err
+	}
+	latestRevision := compactRevision
+	err = s.walkRevision(revision, func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
+		if r.main > latestRevision {
+			latestRevision = r.main
+		}
+		h.Write(kv.Key)
+		h.Write(kv.Value)
+		return false, nil
+	})
+	if err != nil {
+		return Checksum{}, err
+	}
+	return Checksum{h.Sum32(), latestRevision, compactRevision}, nil
+}
+
+func (s *Store) compactRevision() (int64, error) {
+	b, err := bucketOrError(s.tx, metaBucket)
+	if err != nil {
+		return 0, err
+	}
+	compactRev := int64(0)
+	finishedCompactBytes := b.Get(finishedCompactKeyName)
+	if len(finishedCompactBytes) != 0 {
+		compactRev = bytesToRev(finishedCompactBytes).main
+	}
+	return compactRev, nil
+}
+
+// buildIndex indexes the revisions of each key, unless that has been done already. The index file of the
+// store is read if it has one that is up to date, see WriteIndex, otherwise all revisions are scanned.
+func (s *Store) buildIndex() error {
+	if s.index != nil {
+		return nil
+	}
+	index, err := s.readIndex()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.indexErr = err
+		}
+		if index, err = s.scanIndex(); err != nil {
+			return err
+		}
+	}
+	s.index, s.sortedKeys = index, sortedIndexKeys(index)
+	return nil
+}
+
+// IndexError returns why the index file of the store was not used to look up keys, e.g. because the
+// file changed since it was written. It is nil if it was used, if there is none, or if no keys were
+// looked up yet.
+func (s *Store) IndexError() error {
+	return s.indexErr
+}
+
+// scanIndex indexes the revisions of each key by scanning all revisions.
+func (s *Store) scanIndex() (map[string][]indexEntry, error) {
+	index := map[string][]indexEntry{}
+	err := s.walk(func(r revKey, kv *mvccpb.KeyValue) (bool, error) {
+		index[string(kv.Key)] = append(index[string(kv.Key)], indexEntry{rev: r, version: kv.Version})
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func sortedIndexKeys(index map[string][]indexEntry) []string {
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keyValue reads the key-value of a revision.
+func (s *Store) keyValue(r revKey) (*mvccpb.KeyValue, error) {
+	b, err := bucketOrError(s.tx, keyBucket)
+	if err != nil {
+		return nil, err
+	}
+	v := b.Get(revToBytes(r))
+	if v == nil {
+		return nil, fmt.Errorf("revision %d_%d not found", r.main, r.sub)
+	}
+	kv := &mvccpb.KeyValue{}
+	if err := kv.Unmarshal(v); err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+// walkRevision calls f with the latest revision of each key at the given revision, or at the latest
+// revision if revision is 0, in key order. Deleted keys are skipped.
+func (s *Store) walkRevision(revision int64, f func(r revKey, kv *mvccpb.KeyValue) (bool, error)) error {
+	compactRev, err := s.compactRevision()
+	if err != nil {
+		return err
+	}
+	if revision > 0 && revision < compactRev {
+		return errors.New("required revision has been compacted")
+	}
+	if err := s.buildIndex(); err != nil {
+		return err
+	}
+
+	for _, key := range s.sortedKeys {
+		entries := s.index[key]
+		i := len(entries) - 1
+		for revision > 0 && i >= 0 && entries[i].rev.main > revision {
+			i--
+		}
+		if i < 0 || entries[i].rev.tombstone {
+			continue
+		}
+		kv, err := s.keyValue(entries[i].rev)
+		if err != nil {
+			return err
+		}
+		done, err := f(entries[i].rev, kv)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	return nil
+}
+
+// walk calls f with each revision of each key-value, in revision order, until f returns true.
+func (s *Store) walk(f func(r revKey, kv *mvccpb.KeyValue) (bool, error)) error {
+	b, err := bucketOrError(s.tx, keyBucket)
+	if err != nil {
+		return err
+	}
+	c := b.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		revision := bytesToRev(k)
+		kv := &mvccpb.KeyValue{}
+		err := kv.Unmarshal(v)
+		if err != nil {
+			return err
+		}
+		done, err := f(revision, kv)
+		if err != nil {
+			return fmt.Errorf("error handling key %s: %w", kv.Key, err)
+		}
+		if done {
+			break
+		}
+	}
+	return nil
+}
+
+func revToBytes(r revKey) []byte {
+	bytes := make([]byte, revBytesLen, markedRevBytesLen)
+	binary.BigEndian.PutUint64(bytes[0:8], uint64(r.main))
+	bytes[8] = '_'
+	binary.BigEndian.PutUint64(bytes[9:], uint64(r.sub))
+	if r.tombstone {
+		bytes = append(bytes, markTombstone)
+	}
+	return bytes
+}
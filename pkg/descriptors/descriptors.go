@@ -0,0 +1,258 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package descriptors decodes protobuf encoded objects of types that were removed from k8s.io/api, and
+// so are not in pkg/scheme, using the protobuf descriptors of past Kubernetes releases.
+//
+// The descriptors are generated by hack/gen_descriptors.
+package descriptors
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+//go:embed kubernetes.pb.gz
+var kubernetesDescriptors []byte
+
+const (
+	metav1Package       = "k8s.io.apimachinery.pkg.apis.meta.v1."
+	timeMessage         = metav1Package + "Time"
+	microTimeMessage    = metav1Package + "MicroTime"
+	durationMessage     = metav1Package + "Duration"
+	fieldsV1Message     = metav1Package + "FieldsV1"
+	rawExtensionMessage = "k8s.io.apimachinery.pkg.runtime.RawExtension"
+	intOrStringMessage  = "k8s.io.apimachinery.pkg.util.intstr.IntOrString"
+	quantityMessage     = "k8s.io.apimachinery.pkg.api.resource.Quantity"
+)
+
+var loadFiles = sync.OnceValues(func() (*protoregistry.Files, error) {
+	r, err := gzip.NewReader(bytes.NewReader(kubernetesDescriptors))
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, err
+	}
+	return protodesc.NewFiles(set)
+})
+
+// Has returns whether the embedded descriptors define the given kind.
+func Has(gvk schema.GroupVersionKind) bool {
+	_, err := message(gvk)
+	return err == nil
+}
+
+func message(gvk schema.GroupVersionKind) (protoreflect.MessageDescriptor, error) {
+	pkg, ok := protoPackages[gvk.GroupVersion().String()]
+	if !ok {
+		return nil, fmt.Errorf("no descriptors for %s", gvk.GroupVersion())
+	}
+	files, err := loadFiles()
+	if err != nil {
+		return nil, fmt.Errorf("error loading descriptors: %w", err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(pkg + "." + gvk.Kind))
+	if err != nil {
+		return nil, fmt.Errorf("no descriptor for %s: %w", gvk, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor of %s is not a message", gvk)
+	}
+	return md, nil
+}
+
+// ConvertToJSON decodes a protobuf encoded object of the given type using the embedded descriptors and
+// encodes it as JSON, the way the go type the protobuf was generated from would have.
+func ConvertToJSON(typeMeta runtime.TypeMeta, raw []byte) ([]byte, error) {
+	gvk := schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind)
+	md, err := message(gvk)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", gvk, err)
+	}
+	obj, err := messageToJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	obj["apiVersion"] = typeMeta.APIVersion
+	obj["kind"] = typeMeta.Kind
+	return json.Marshal(obj)
+}
+
+// messageToJSON encodes a message as an object. The descriptors don't record which fields are pointers or
+// omitempty, and non-pointer fields are always written, so fields set to the zero value of a scalar type
+// are omitted.
+func messageToJSON(msg protoreflect.Message) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isZeroScalar(fd, v) {
+			return true
+		}
+		var value interface{}
+		value, err = fieldToJSON(fd, v)
+		if err != nil {
+			return false
+		}
+		if inline, ok := value.(map[string]interface{}); ok && inlineFields[fd.FullName()] {
+			for k, v := range inline {
+				obj[k] = v
+			}
+			return true
+		}
+		// The fields of generated.proto are named after the JSON fields of the go types.
+		obj[string(fd.Name())] = value
+		return true
+	})
+	return obj, err
+}
+
+func isZeroScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	if fd.IsList() || fd.IsMap() {
+		return false
+	}
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	case protoreflect.BytesKind:
+		return len(v.Bytes()) == 0
+	default:
+		return v.Equal(fd.Default())
+	}
+}
+
+func fieldToJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		items := make([]interface{}, list.Len())
+		for i := range items {
+			item, err := singularToJSON(fd, list.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case fd.IsMap():
+		obj := map[string]interface{}{}
+		var err error
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			var value interface{}
+			value, err = singularToJSON(fd.MapValue(), v)
+			if err != nil {
+				return false
+			}
+			obj[k.String()] = value
+			return true
+		})
+		return obj, err
+	default:
+		return singularToJSON(fd, v)
+	}
+}
+
+func singularToJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return wellKnownToJSON(v.Message())
+	case protoreflect.BytesKind:
+		// Encoded as base64 by encoding/json, like []byte fields.
+		return v.Bytes(), nil
+	case protoreflect.EnumKind:
+		return int64(v.Enum()), nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// wellKnownToJSON encodes the apimachinery types with a custom JSON encoding the way they encode
+// themselves, and any other message as an object.
+func wellKnownToJSON(msg protoreflect.Message) (interface{}, error) {
+	md := msg.Descriptor()
+	field := func(name protoreflect.Name) protoreflect.Value {
+		return msg.Get(md.Fields().ByName(name))
+	}
+	switch md.FullName() {
+	case timeMessage, microTimeMessage:
+		// The zero time is written as an empty message.
+		seconds, nanos := field("seconds").Int(), field("nanos").Int()
+		if seconds == 0 && nanos == 0 {
+			return nil, nil
+		}
+		t := time.Unix(seconds, nanos).UTC()
+		if md.FullName() == microTimeMessage {
+			return t.Format("2006-01-02T15:04:05.000000Z07:00"), nil
+		}
+		return t.Format(time.RFC3339), nil
+	case durationMessage:
+		return time.Duration(field("duration").Int()).String(), nil
+	case quantityMessage:
+		return field("string").String(), nil
+	case intOrStringMessage:
+		if field("type").Int() == 1 {
+			return field("strVal").String(), nil
+		}
+		return field("intVal").Int(), nil
+	case fieldsV1Message:
+		return rawToJSON(field("Raw").Bytes()), nil
+	case rawExtensionMessage:
+		return rawToJSON(field("raw").Bytes()), nil
+	}
+
+	// Named slices, e.g. 'type ExtraValue []string', are wrapped in a message with a single repeated
+	// items field, which list kinds always have metadata next to.
+	if md.Fields().Len() == 1 && !strings.HasSuffix(string(md.Name()), "List") {
+		if items := md.Fields().ByName("items"); items != nil && items.IsList() {
+			return fieldToJSON(items, msg.Get(items))
+		}
+	}
+	return messageToJSON(msg)
+}
+
+// rawToJSON embeds raw JSON as is, and encodes anything else as base64 like []byte fields.
+func rawToJSON(raw []byte) interface{} {
+	if !json.Valid(raw) {
+		return raw
+	}
+	return json.RawMessage(raw)
+}
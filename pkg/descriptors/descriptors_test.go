@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package descriptors
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestHas(t *testing.T) {
+	cases := []struct {
+		gvk  schema.GroupVersionKind
+		want bool
+	}{
+		{schema.GroupVersionKind{Group: "batch", Version: "v2alpha1", Kind: "CronJob"}, true},
+		{schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}, true},
+		{schema.GroupVersionKind{Group: "resource.k8s.io", Version: "v1alpha2", Kind: "ResourceClass"}, true},
+		{schema.GroupVersionKind{Group: "batch", Version: "v2alpha1", Kind: "Widget"}, false},
+		{schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, false},
+	}
+	for _, tt := range cases {
+		if got := Has(tt.gvk); got != tt.want {
+			t.Errorf("Has(%s) = %t, want %t", tt.gvk, got, tt.want)
+		}
+	}
+}
+
+func TestConvertToJSON(t *testing.T) {
+	files, err := loadFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMessage := func(name protoreflect.FullName, fields map[protoreflect.Name]interface{}) *dynamicpb.Message {
+		desc, err := files.FindDescriptorByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+		for name, value := range fields {
+			fd := msg.Descriptor().Fields().ByName(name)
+			if m, ok := value.(*dynamicpb.Message); ok {
+				msg.Set(fd, protoreflect.ValueOfMessage(m))
+				continue
+			}
+			msg.Set(fd, protoreflect.ValueOf(value))
+		}
+		return msg
+	}
+
+	cases := []struct {
+		name string
+		msg  *dynamicpb.Message
+		want string
+	}{
+		{
+			name: "time",
+			msg: newMessage("k8s.io.api.policy.v1beta1.Eviction", map[protoreflect.Name]interface{}{
+				"metadata": newMessage(metav1Package+"ObjectMeta", map[protoreflect.Name]interface{}{
+					"name":              "pod",
+					"creationTimestamp": newMessage(timeMessage, map[protoreflect.Name]interface{}{"seconds": int64(1583298367)}),
+					"deletionTimestamp": newMessage(timeMessage, nil),
+				}),
+			}),
+			want: `{"metadata":{"creationTimestamp":"2020-03-04T05:06:07Z","deletionTimestamp":null,"name":"pod"}}`,
+		},
+		{
+			name: "zero scalars",
+			msg: newMessage(metav1Package+"ObjectMeta", map[protoreflect.Name]interface{}{
+				"name":         "pod",
+				"generateName": "",
+				"generation":   int64(0),
+			}),
+			want: `{"name":"pod"}`,
+		},
+		{
+			name: "micro time",
+			msg: newMessage("k8s.io.api.coordination.v1alpha2.LeaseCandidateSpec", map[protoreflect.Name]interface{}{
+				"pingTime":  newMessage(microTimeMessage, map[protoreflect.Name]interface{}{"seconds": int64(1583298367), "nanos": int32(123456000)}),
+				"leaseName": "lease",
+			}),
+			want: `{"leaseName":"lease","pingTime":"2020-03-04T05:06:07.123456Z"}`,
+		},
+		{
+			name: "int or string",
+			msg: newMessage("k8s.io.api.core.v1.HTTPGetAction", map[protoreflect.Name]interface{}{
+				"port": newMessage(intOrStringMessage, map[protoreflect.Name]interface{}{"intVal": int32(8080)}),
+				"host": "localhost",
+			}),
+			want: `{"host":"localhost","port":8080}`,
+		},
+		{
+			name: "quantity",
+			msg: newMessage("k8s.io.api.resource.v1alpha3.Counter", map[protoreflect.Name]interface{}{
+				"value": newMessage(quantityMessage, map[protoreflect.Name]interface{}{"string": "64Mi"}),
+			}),
+			want: `{"value":"64Mi"}`,
+		},
+		{
+			name: "raw extension",
+			msg: newMessage("k8s.io.api.resource.v1alpha3.OpaqueDeviceConfiguration", map[protoreflect.Name]interface{}{
+				"driver":     "gpu.example.com",
+				"parameters": newMessage(rawExtensionMessage, map[protoreflect.Name]interface{}{"raw": []byte(`{"sharing":"timeslice"}`)}),
+			}),
+			want: `{"driver":"gpu.example.com","parameters":{"sharing":"timeslice"}}`,
+		},
+		{
+			name: "inline",
+			msg: newMessage("k8s.io.api.core.v1.Probe", map[protoreflect.Name]interface{}{
+				"handler": newMessage("k8s.io.api.core.v1.ProbeHandler", map[protoreflect.Name]interface{}{
+					"httpGet": newMessage("k8s.io.api.core.v1.HTTPGetAction", map[protoreflect.Name]interface{}{
+						"port": newMessage(intOrStringMessage, map[protoreflect.Name]interface{}{"type": int64(1), "strVal": "http"}),
+					}),
+				}),
+				"periodSeconds": int32(10),
+			}),
+			want: `{"httpGet":{"port":"http"},"periodSeconds":10}`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := messageToJSON(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertToJSONTypeMeta(t *testing.T) {
+	files, err := loadFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := files.FindDescriptorByName("k8s.io.api.settings.v1alpha1.PodPreset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	metadata := dynamicpb.NewMessage(msg.Descriptor().Fields().ByName("metadata").Message())
+	metadata.Set(metadata.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("preset"))
+	msg.Set(msg.Descriptor().Fields().ByName("metadata"), protoreflect.ValueOfMessage(metadata))
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ConvertToJSON(runtime.TypeMeta{APIVersion: "settings.k8s.io/v1alpha1", Kind: "PodPreset"}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"apiVersion":"settings.k8s.io/v1alpha1","kind":"PodPreset","metadata":{"name":"preset"}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if _, err := ConvertToJSON(runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}, raw); err == nil {
+		t.Error("expected error for a kind without descriptors")
+	}
+}
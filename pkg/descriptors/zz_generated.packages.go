@@ -0,0 +1,66 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package descriptors
+
+// Don't edit this file directly. It is generated by hack/gen_descriptors.
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// protoPackages maps the group versions of the embedded descriptors to their proto package.
+var protoPackages = map[string]string{
+	"apidiscovery.k8s.io/v2":                "k8s.io.api.apidiscovery.v2",
+	"apidiscovery.k8s.io/v2beta1":           "k8s.io.api.apidiscovery.v2beta1",
+	"auditregistration.k8s.io/v1alpha1":     "k8s.io.api.auditregistration.v1alpha1",
+	"batch/v2alpha1":                        "k8s.io.api.batch.v2alpha1",
+	"coordination.k8s.io/v1alpha2":          "k8s.io.api.coordination.v1alpha2",
+	"discovery.k8s.io/v1alpha1":             "k8s.io.api.discovery.v1alpha1",
+	"extensions/v1beta1":                    "k8s.io.api.extensions.v1beta1",
+	"flowcontrol.apiserver.k8s.io/v1alpha1": "k8s.io.api.flowcontrol.v1alpha1",
+	"networking.k8s.io/v1alpha1":            "k8s.io.api.networking.v1alpha1",
+	"policy/v1beta1":                        "k8s.io.api.policy.v1beta1",
+	"resource.k8s.io/v1alpha1":              "k8s.io.api.resource.v1alpha1",
+	"resource.k8s.io/v1alpha2":              "k8s.io.api.resource.v1alpha2",
+	"resource.k8s.io/v1alpha3":              "k8s.io.api.resource.v1alpha3",
+	"resource.k8s.io/v1beta1":               "k8s.io.api.resource.v1beta1",
+	"resource.k8s.io/v1beta2":               "k8s.io.api.resource.v1beta2",
+	"settings.k8s.io/v1alpha1":              "k8s.io.api.settings.v1alpha1",
+}
+
+// inlineFields are the fields of embedded structs, whose fields are inlined in JSON.
+var inlineFields = map[protoreflect.FullName]bool{
+	"k8s.io.api.core.v1.ConfigMapEnvSource.localObjectReference":                     true,
+	"k8s.io.api.core.v1.ConfigMapKeySelector.localObjectReference":                   true,
+	"k8s.io.api.core.v1.ConfigMapProjection.localObjectReference":                    true,
+	"k8s.io.api.core.v1.ConfigMapVolumeSource.localObjectReference":                  true,
+	"k8s.io.api.core.v1.EphemeralContainer.ephemeralContainerCommon":                 true,
+	"k8s.io.api.core.v1.PersistentVolumeSpec.persistentVolumeSource":                 true,
+	"k8s.io.api.core.v1.Probe.handler":                                               true,
+	"k8s.io.api.core.v1.SecretEnvSource.localObjectReference":                        true,
+	"k8s.io.api.core.v1.SecretKeySelector.localObjectReference":                      true,
+	"k8s.io.api.core.v1.SecretProjection.localObjectReference":                       true,
+	"k8s.io.api.core.v1.Volume.volumeSource":                                         true,
+	"k8s.io.api.extensions.v1beta1.IngressRule.ingressRuleValue":                     true,
+	"k8s.io.api.resource.v1alpha3.DeviceAllocationConfiguration.deviceConfiguration": true,
+	"k8s.io.api.resource.v1alpha3.DeviceClaimConfiguration.deviceConfiguration":      true,
+	"k8s.io.api.resource.v1alpha3.DeviceClassConfiguration.deviceConfiguration":      true,
+	"k8s.io.api.resource.v1beta1.DeviceAllocationConfiguration.deviceConfiguration":  true,
+	"k8s.io.api.resource.v1beta1.DeviceClaimConfiguration.deviceConfiguration":       true,
+	"k8s.io.api.resource.v1beta1.DeviceClassConfiguration.deviceConfiguration":       true,
+	"k8s.io.api.resource.v1beta2.DeviceAllocationConfiguration.deviceConfiguration":  true,
+	"k8s.io.api.resource.v1beta2.DeviceClaimConfiguration.deviceConfiguration":       true,
+	"k8s.io.api.resource.v1beta2.DeviceClassConfiguration.deviceConfiguration":       true,
+	"k8s.io.apimachinery.pkg.runtime.Unknown.typeMeta":                               true,
+}
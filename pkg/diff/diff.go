@@ -0,0 +1,183 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diff compares kubernetes objects, decoded from JSON, field by field.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Type is how a field differs between two objects.
+type Type string
+
+const (
+	// Added fields are only in the new object.
+	Added Type = "added"
+	// Removed fields are only in the old object.
+	Removed Type = "removed"
+	// Changed fields are in both objects with different values.
+	Changed Type = "changed"
+)
+
+// Change is a field that differs between two objects.
+type Change struct {
+	// Path is the path of the field, by JSON names. Items of lists whose items all have a distinct name are
+	// identified by it, e.g. spec.containers[name=web].image, other items by their index.
+	Path string      `json:"path"`
+	Type Type        `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, toJSON(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, toJSON(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, toJSON(c.Old), toJSON(c.New))
+	}
+}
+
+func toJSON(v interface{}) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(buf)
+}
+
+// Objects compares two objects decoded from JSON, e.g. unstructured.Unstructured contents, and returns
+// the fields that differ, ordered by the keys of the objects and the items of their lists.
+func Objects(old, new map[string]interface{}) []Change {
+	var changes []Change
+	compareMaps("", old, new, &changes)
+	return changes
+}
+
+func compare(path string, old, new interface{}, changes *[]Change) {
+	switch old := old.(type) {
+	case map[string]interface{}:
+		if new, ok := new.(map[string]interface{}); ok {
+			compareMaps(path, old, new, changes)
+			return
+		}
+	case []interface{}:
+		if new, ok := new.([]interface{}); ok {
+			compareLists(path, old, new, changes)
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Type: Changed, Old: old, New: new})
+	}
+}
+
+func compareMaps(path string, old, new map[string]interface{}, changes *[]Change) {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fieldPath := k
+		if path != "" {
+			fieldPath = path + "." + k
+		}
+		oldValue, inOld := old[k]
+		newValue, inNew := new[k]
+		switch {
+		case !inNew:
+			*changes = append(*changes, Change{Path: fieldPath, Type: Removed, Old: oldValue})
+		case !inOld:
+			*changes = append(*changes, Change{Path: fieldPath, Type: Added, New: newValue})
+		default:
+			compare(fieldPath, oldValue, newValue, changes)
+		}
+	}
+}
+
+func compareLists(path string, old, new []interface{}, changes *[]Change) {
+	oldNames, newNames := itemNames(old), itemNames(new)
+	if oldNames == nil || newNames == nil {
+		for i := 0; i < len(old) || i < len(new); i++ {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(new):
+				*changes = append(*changes, Change{Path: itemPath, Type: Removed, Old: old[i]})
+			case i >= len(old):
+				*changes = append(*changes, Change{Path: itemPath, Type: Added, New: new[i]})
+			default:
+				compare(itemPath, old[i], new[i], changes)
+			}
+		}
+		return
+	}
+
+	newItems := make(map[string]interface{}, len(new))
+	for i, name := range newNames {
+		newItems[name] = new[i]
+	}
+	oldItems := make(map[string]bool, len(old))
+	for i, name := range oldNames {
+		oldItems[name] = true
+		itemPath := path + "[name=" + name + "]"
+		if newItem, ok := newItems[name]; ok {
+			compare(itemPath, old[i], newItem, changes)
+		} else {
+			*changes = append(*changes, Change{Path: itemPath, Type: Removed, Old: old[i]})
+		}
+	}
+	for i, name := range newNames {
+		if !oldItems[name] {
+			*changes = append(*changes, Change{Path: path + "[name=" + name + "]", Type: Added, New: new[i]})
+		}
+	}
+}
+
+// itemNames returns the names of the items of a list, or nil if not all items are objects with a distinct
+// name.
+func itemNames(list []interface{}) []string {
+	if len(list) == 0 {
+		return []string{}
+	}
+	names := make([]string, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		name, ok := obj["name"].(string)
+		if !ok || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
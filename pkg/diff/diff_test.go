@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestObjects(t *testing.T) {
+	cases := []struct {
+		name string
+		old  string
+		new  string
+		want []string
+	}{
+		{
+			name: "equal",
+			old:  `{"metadata":{"name":"a"},"spec":{"replicas":1}}`,
+			new:  `{"spec":{"replicas":1},"metadata":{"name":"a"}}`,
+		},
+		{
+			name: "fields",
+			old:  `{"metadata":{"name":"a","labels":{"app":"web"}},"spec":{"replicas":1}}`,
+			new:  `{"metadata":{"name":"a","annotations":{"a":"b"}},"spec":{"replicas":2}}`,
+			want: []string{
+				`+ metadata.annotations: {"a":"b"}`,
+				`- metadata.labels: {"app":"web"}`,
+				`~ spec.replicas: 1 -> 2`,
+			},
+		},
+		{
+			name: "type",
+			old:  `{"spec":{"ports":[80]}}`,
+			new:  `{"spec":{"ports":"80"}}`,
+			want: []string{`~ spec.ports: [80] -> "80"`},
+		},
+		{
+			name: "named items",
+			old:  `{"containers":[{"name":"web","image":"nginx:1"},{"name":"log","image":"fluentd"}]}`,
+			new:  `{"containers":[{"name":"sidecar","image":"envoy"},{"name":"web","image":"nginx:2"}]}`,
+			want: []string{
+				`~ containers[name=web].image: "nginx:1" -> "nginx:2"`,
+				`- containers[name=log]: {"image":"fluentd","name":"log"}`,
+				`+ containers[name=sidecar]: {"image":"envoy","name":"sidecar"}`,
+			},
+		},
+		{
+			name: "indexed items",
+			old:  `{"args":["a","b","c"],"finalizers":[{"x":1}]}`,
+			new:  `{"args":["a","d"],"finalizers":[{"x":2},{"x":3}]}`,
+			want: []string{
+				`~ args[1]: "b" -> "d"`,
+				`- args[2]: "c"`,
+				`~ finalizers[0].x: 1 -> 2`,
+				`+ finalizers[1]: {"x":3}`,
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Objects(unmarshal(t, tt.old), unmarshal(t, tt.new)) {
+				got = append(got, c.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func unmarshal(t *testing.T, s string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/etcd-io/auger/pkg/descriptors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+)
+
+// codecCache caches the codecs of a CodecFactory by APIVersion and media type. Codecs only depend on the
+// group version of the objects they encode and decode, not their kind.
+type codecCache struct {
+	codecs serializer.CodecFactory
+	cache  sync.Map // codecKey -> runtime.Codec
+}
+
+type codecKey struct {
+	apiVersion string
+	mediaType  string
+}
+
+func (c *codecCache) codec(typeMeta *runtime.TypeMeta, mediaType string) (runtime.Codec, error) {
+	key := codecKey{apiVersion: typeMeta.APIVersion, mediaType: mediaType}
+	if codec, ok := c.cache.Load(key); ok {
+		return codec.(runtime.Codec), nil
+	}
+	codec, err := newCodec(c.codecs, typeMeta, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := c.cache.LoadOrStore(key, codec)
+	return cached.(runtime.Codec), nil
+}
+
+// Decoder decodes kv store encoded data. It is constructed once and caches the codecs it needs, which
+// makes it faster than the Convert function for many objects. A Decoder is safe for concurrent use.
+type Decoder struct {
+	cache *codecCache
+}
+
+// NewDecoder returns a Decoder for the types of the given CodecFactory.
+func NewDecoder(codecs serializer.CodecFactory) *Decoder {
+	return &Decoder{cache: &codecCache{codecs: codecs}}
+}
+
+// DetectAndConvert detects the media type of kv store encoded data and converts it to the given output
+// format, like the DetectAndConvert function.
+func (d *Decoder) DetectAndConvert(outMediaType string, in []byte) ([]byte, *runtime.TypeMeta, error) {
+	inMediaType, in, err := DetectAndExtract(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.Convert(inMediaType, outMediaType, in)
+}
+
+// Decode decodes kv store encoded data to a typed object, e.g. a *corev1.Pod. Objects whose kind is not
+// registered in the scheme fail with ErrUnknownKind.
+func (d *Decoder) Decode(inMediaType string, in []byte) (runtime.Object, *runtime.TypeMeta, error) {
+	typeMeta, err := DecodeTypeMeta(inMediaType, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	codec, err := d.cache.codec(typeMeta, inMediaType)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err := runtime.Decode(codec, in)
+	if runtime.IsNotRegisteredError(err) {
+		return nil, nil, fmt.Errorf("error decoding from %s: %w: %w", inMediaType, ErrUnknownKind, err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
+	}
+	return obj, typeMeta, nil
+}
+
+// Convert converts kv store encoded data to the given output format, like the Convert function.
+func (d *Decoder) Convert(inMediaType, outMediaType string, in []byte) ([]byte, *runtime.TypeMeta, error) {
+	if inMediaType == StorageBinaryMediaType && outMediaType == ProtobufMediaType {
+		unknown, err := DecodeUnknown(in)
+		if err != nil {
+			return nil, nil, err
+		}
+		return unknown.Raw, &unknown.TypeMeta, nil
+	}
+
+	if outMediaType == ProtoRawMediaType {
+		if inMediaType != StorageBinaryMediaType {
+			return nil, nil, fmt.Errorf("unsupported conversion: %s to %s", inMediaType, outMediaType)
+		}
+		typeMeta, err := DecodeTypeMeta(inMediaType, in)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf := new(bytes.Buffer)
+		if err := DumpRaw(in, buf); err != nil {
+			return nil, nil, err
+		}
+		return buf.Bytes(), typeMeta, nil
+	}
+
+	if inMediaType == ProtobufMediaType && outMediaType == StorageBinaryMediaType {
+		return nil, nil, errors.New("unsupported conversion: protobuf to kubernetes binary storage representation")
+	}
+
+	typeMeta, err := DecodeTypeMeta(inMediaType, in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var encoded []byte
+	if inMediaType == outMediaType {
+		// Assumes that the stored version is "correct". Primarily a short cut to allow CRDs to work.
+		encoded = in
+		if outMediaType == JsonMediaType {
+			encoded = append(encoded, '\n')
+		}
+	} else if inMediaType == JsonMediaType && outMediaType == YamlMediaType {
+		encoded, err = yaml.JSONToYAML(in)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error encoding from %s: %w", outMediaType, err)
+		}
+	} else {
+		inCodec, err := d.cache.codec(typeMeta, inMediaType)
+		if err != nil {
+			return nil, nil, err
+		}
+		outCodec, err := d.cache.codec(typeMeta, outMediaType)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		obj, err := runtime.Decode(inCodec, in)
+		if runtime.IsNotRegisteredError(err) && inMediaType == StorageBinaryMediaType && descriptors.Has(typeMeta.GroupVersionKind()) {
+			// Types removed from k8s.io/api are decoded with the protobuf descriptors of past releases.
+			encoded, err = convertRemoved(outMediaType, in)
+			if err != nil {
+				return nil, nil, err
+			}
+			return encoded, typeMeta, nil
+		}
+		if runtime.IsNotRegisteredError(err) && inMediaType == StorageBinaryMediaType {
+			return nil, nil, fmt.Errorf("error decoding from %s: %w: %w", inMediaType, ErrUnknownKind, err)
+		}
+		if runtime.IsNotRegisteredError(err) && (inMediaType == CborMediaType || outMediaType == CborMediaType) {
+			// Custom resources are not in the scheme, convert them without a typed object.
+			encoded, err = convertUnstructured(inMediaType, outMediaType, in)
+			if err != nil {
+				return nil, nil, err
+			}
+			return encoded, typeMeta, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
+		}
+
+		encoded, err = runtime.Encode(outCodec, obj)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error encoding to %s: %w", outMediaType, err)
+		}
+	}
+
+	return encoded, typeMeta, nil
+}
+
+// Encoder encodes typed objects to the kv store encoding or other formats. Like a Decoder, it caches the
+// codecs it needs and is safe for concurrent use.
+type Encoder struct {
+	cache *codecCache
+}
+
+// NewEncoder returns an Encoder for the types of the given CodecFactory.
+func NewEncoder(codecs serializer.CodecFactory) *Encoder {
+	return &Encoder{cache: &codecCache{codecs: codecs}}
+}
+
+// Encode encodes an object, whose apiVersion and kind must be set, to the given media type. Objects
+// encoded to StorageBinaryMediaType are in the 'Unknown' envelope kube-apiserver writes to etcd.
+// Unstructured objects are encoded as their typed object if their kind is registered in the scheme.
+func (e *Encoder) Encode(obj runtime.Object, outMediaType string) ([]byte, error) {
+	apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	if apiVersion == "" || kind == "" {
+		return nil, errors.New("error encoding object: apiVersion and kind must be set")
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return e.encodeUnstructured(u, outMediaType)
+	}
+	codec, err := e.cache.codec(&runtime.TypeMeta{APIVersion: apiVersion, Kind: kind}, outMediaType)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := runtime.Encode(codec, obj)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding to %s: %w", outMediaType, err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/scheme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func testPod() *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "nginx", Args: []string{"-g", "daemon off;"}}},
+		},
+	}
+}
+
+func storedPod(t testing.TB) []byte {
+	stored, err := NewEncoder(scheme.Codecs).Encode(testPod(), StorageBinaryMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestDecoder(t *testing.T) {
+	stored := storedPod(t)
+	if !bytes.HasPrefix(stored, ProtoEncodingPrefix) {
+		t.Fatalf("got %q, expected the proto encoding prefix", stored)
+	}
+
+	d := NewDecoder(scheme.Codecs)
+	obj, typeMeta, err := d.Decode(StorageBinaryMediaType, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		t.Fatalf("got %T, want *corev1.Pod", obj)
+	}
+	if pod.Name != "web" || pod.Spec.Containers[0].Image != "nginx" || typeMeta.Kind != "Pod" {
+		t.Errorf("got pod %+v with %+v", pod, typeMeta)
+	}
+
+	js, _, err := d.Convert(StorageBinaryMediaType, JsonMediaType, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := Convert(scheme.Codecs, StorageBinaryMediaType, JsonMediaType, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(js, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", js, want)
+	}
+
+	rt, err := NewEncoder(scheme.Codecs).Encode(pod, StorageBinaryMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rt, stored) {
+		t.Errorf("got %q for the round trip, want %q", rt, stored)
+	}
+}
+
+func TestDecoderConcurrent(t *testing.T) {
+	stored := storedPod(t)
+	d := NewDecoder(scheme.Codecs)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, outMediaType := range []string{JsonMediaType, YamlMediaType, CborMediaType} {
+				if _, _, err := d.Convert(StorageBinaryMediaType, outMediaType, stored); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecoderUnknownKind(t *testing.T) {
+	d := NewDecoder(serializer.NewCodecFactory(runtime.NewScheme()))
+	in := storageBinary(t, runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}, appendField(nil, 1, nil))
+	if _, _, err := d.Decode(StorageBinaryMediaType, in); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownKind)
+	}
+}
+
+func TestEncoderNoKind(t *testing.T) {
+	pod := testPod()
+	pod.TypeMeta = metav1.TypeMeta{}
+	if _, err := NewEncoder(scheme.Codecs).Encode(pod, StorageBinaryMediaType); err == nil {
+		t.Error("expected an error for an object without apiVersion and kind")
+	}
+}
+
+// BenchmarkConvertUncached converts with new codecs for each object, the way objects were converted before
+// codecs were cached, for comparison with BenchmarkDecoderConvert.
+func BenchmarkConvertUncached(b *testing.B) {
+	stored := storedPod(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		typeMeta, err := DecodeTypeMeta(StorageBinaryMediaType, stored)
+		if err != nil {
+			b.Fatal(err)
+		}
+		inCodec, err := newCodec(scheme.Codecs, typeMeta, StorageBinaryMediaType)
+		if err != nil {
+			b.Fatal(err)
+		}
+		outCodec, err := newCodec(scheme.Codecs, typeMeta, JsonMediaType)
+		if err != nil {
+			b.Fatal(err)
+		}
+		obj, err := runtime.Decode(inCodec, stored)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := runtime.Encode(outCodec, obj); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoderConvert(b *testing.B) {
+	stored := storedPod(b)
+	d := NewDecoder(scheme.Codecs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := d.Convert(StorageBinaryMediaType, JsonMediaType, stored); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoderDecode(b *testing.B) {
+	stored := storedPod(b)
+	d := NewDecoder(scheme.Codecs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := d.Decode(StorageBinaryMediaType, stored); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
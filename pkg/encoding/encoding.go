@@ -23,9 +23,13 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/etcd-io/auger/pkg/descriptors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/cbor"
+	"k8s.io/apimachinery/pkg/runtime/serializer/cbor/direct"
 	"sigs.k8s.io/yaml"
 )
 
@@ -34,15 +38,33 @@ const (
 	ProtobufMediaType      = "application/vnd.kubernetes.protobuf"
 	YamlMediaType          = "application/yaml"
 	JsonMediaType          = "application/json" //nolint:staticcheck
+	CborMediaType          = "application/cbor"
+	// ProtoRawMediaType is not a real media type, it selects the schemaless dump of DumpRaw.
+	ProtoRawMediaType = "text/x-protobuf-raw"
 
 	ProtobufShortname = "proto"
 	YamlShortname     = "yaml"
 	JsonShortname     = "json" //nolint:staticcheck
+	CborShortname     = "cbor"
+	ProtoRawShortname = "proto-raw"
 )
 
 // See k8s.io/apimachinery/pkg/runtime/serializer/protobuf.go
 var ProtoEncodingPrefix = []byte{0x6b, 0x38, 0x73, 0x00}
 
+// CborEncodingPrefix is the self-described CBOR tag (55799) kubernetes writes before CBOR encoded objects.
+// See k8s.io/apimachinery/pkg/runtime/serializer/cbor/cbor.go
+var CborEncodingPrefix = []byte{0xd9, 0xd9, 0xf7}
+
+// unstructuredCbor decodes and encodes CBOR objects whose kinds are not registered in the scheme,
+// e.g. custom resources.
+var unstructuredCbor = cbor.NewSerializer(nil, nil)
+
+// ErrUnknownKind is returned by Convert for objects in the binary storage representation whose kind is
+// neither registered in the scheme nor in the embedded descriptors of removed types. They can still be
+// converted to ProtoRawMediaType.
+var ErrUnknownKind = errors.New("unknown kind")
+
 // ToMediaType maps 'out' flag values to corresponding mime types.
 func ToMediaType(out string) (string, error) {
 	switch out {
@@ -52,6 +74,10 @@ func ToMediaType(out string) (string, error) {
 		return JsonMediaType, nil
 	case ProtobufShortname:
 		return ProtobufMediaType, nil
+	case CborShortname:
+		return CborMediaType, nil
+	case ProtoRawShortname:
+		return ProtoRawMediaType, nil
 	default:
 		return "", fmt.Errorf("unrecognized 'out' flag value: %v", out)
 	}
@@ -69,66 +95,86 @@ func DetectAndConvert(codecs serializer.CodecFactory, outMediaType string, in []
 }
 
 // Convert from kv store encoded data to the given output format using kubernetes' api machinery to
-// perform the conversion.
+// perform the conversion. Use a Decoder to convert many objects.
 func Convert(codecs serializer.CodecFactory, inMediaType, outMediaType string, in []byte) ([]byte, *runtime.TypeMeta, error) {
-	if inMediaType == StorageBinaryMediaType && outMediaType == ProtobufMediaType {
-		unknown, err := DecodeUnknown(in)
-		if err != nil {
-			return nil, nil, err
-		}
-		return unknown.Raw, &unknown.TypeMeta, nil
-	}
+	return NewDecoder(codecs).Convert(inMediaType, outMediaType, in)
+}
 
-	if inMediaType == ProtobufMediaType && outMediaType == StorageBinaryMediaType {
-		return nil, nil, errors.New("unsupported conversion: protobuf to kubernetes binary storage representation")
+// convertRemoved converts an object in the kubernetes binary storage representation, of a type that is
+// not in the scheme, to json, yaml or cbor using the embedded protobuf descriptors.
+func convertRemoved(outMediaType string, in []byte) ([]byte, error) {
+	unknown, err := DecodeUnknown(in)
+	if err != nil {
+		return nil, err
 	}
+	js, err := descriptors.ConvertToJSON(unknown.TypeMeta, unknown.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding from %s: %w", StorageBinaryMediaType, err)
+	}
+	return convertUnstructured(JsonMediaType, outMediaType, js)
+}
 
-	typeMeta, err := DecodeTypeMeta(inMediaType, in)
+// convertUnstructured converts objects between json, yaml and cbor without decoding them into their
+// registered go types.
+func convertUnstructured(inMediaType, outMediaType string, in []byte) ([]byte, error) {
+	obj, err := decodeUnstructured(inMediaType, in)
+	if errors.Is(err, errUnsupportedUnstructured) {
+		return nil, fmt.Errorf("unsupported conversion: %s to %s for kinds not registered in the scheme", inMediaType, outMediaType)
+	}
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	var encoded []byte
-	if inMediaType == outMediaType {
-		// Assumes that the stored version is "correct". Primarily a short cut to allow CRDs to work.
-		encoded = in
-		if outMediaType == JsonMediaType {
-			encoded = append(encoded, '\n')
-		}
-	} else if inMediaType == JsonMediaType && outMediaType == YamlMediaType {
-		encoded, err = yaml.JSONToYAML(in)
+	switch outMediaType {
+	case CborMediaType:
+		return runtime.Encode(unstructuredCbor, obj)
+	case JsonMediaType, YamlMediaType:
+		js, err := obj.MarshalJSON()
 		if err != nil {
-			return nil, nil, fmt.Errorf("error encoding from %s: %w", outMediaType, err)
+			return nil, fmt.Errorf("error encoding to %s: %w", outMediaType, err)
 		}
-	} else {
-		inCodec, err := newCodec(codecs, typeMeta, inMediaType)
-		if err != nil {
-			return nil, nil, err
-		}
-		outCodec, err := newCodec(codecs, typeMeta, outMediaType)
-		if err != nil {
-			return nil, nil, err
+		if outMediaType == YamlMediaType {
+			return yaml.JSONToYAML(js)
 		}
+		return js, nil
+	default:
+		return nil, fmt.Errorf("unsupported conversion: %s to %s for kinds not registered in the scheme", inMediaType, outMediaType)
+	}
+}
 
-		obj, err := runtime.Decode(inCodec, in)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
-		}
+var errUnsupportedUnstructured = errors.New("unsupported media type for kinds not registered in the scheme")
 
-		encoded, err = runtime.Encode(outCodec, obj)
+// decodeUnstructured decodes json, yaml or cbor objects without decoding them into their registered go types.
+func decodeUnstructured(inMediaType string, in []byte) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	switch inMediaType {
+	case CborMediaType:
+		if _, _, err := unstructuredCbor.Decode(in, nil, obj); err != nil {
+			return nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
+		}
+	case JsonMediaType, YamlMediaType:
+		js, err := yaml.YAMLToJSON(in)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error encoding to %s: %w", outMediaType, err)
+			return nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
+		}
+		if err := obj.UnmarshalJSON(js); err != nil {
+			return nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
 		}
+	default:
+		return nil, errUnsupportedUnstructured
 	}
-
-	return encoded, typeMeta, nil
+	return obj, nil
 }
 
-// DetectAndExtract searches the the start of either json of protobuf data, and, if found, returns the mime type and data.
+// DetectAndExtract detects protobuf or cbor data by its prefix at the start of the value, or searches the
+// start of json data, and, if found, returns the mime type and data.
 func DetectAndExtract(in []byte) (string, []byte, error) {
 	if pb, ok := tryFindProto(in); ok {
 		return StorageBinaryMediaType, pb, nil
 	}
+	if cb, ok := tryFindCbor(in); ok {
+		return CborMediaType, cb, nil
+	}
 	if rawJs, ok := tryFindJSON(in); ok {
 		js, err := rawJs.MarshalJSON()
 		if err != nil {
@@ -136,14 +182,30 @@ func DetectAndExtract(in []byte) (string, []byte, error) {
 		}
 		return JsonMediaType, js, nil
 	}
-	return "", nil, errors.New("error reading input, does not appear to contain valid JSON or binary data")
+	return "", nil, errors.New("error reading input, does not appear to contain valid JSON, CBOR or binary data")
+}
+
+// tryFindCbor checks for the self-described CBOR tag at the start of the value, and, if found, returns the
+// data starting with the tag.
+func tryFindCbor(in []byte) ([]byte, bool) {
+	return valueWithPrefix(in, CborEncodingPrefix)
 }
 
-// TryFindProto searches for the 'k8s\0' prefix, and, if found, returns the data starting with the prefix.
+// tryFindProto checks for the 'k8s\0' prefix at the start of the value, and, if found, returns the data
+// starting with the prefix.
 func tryFindProto(in []byte) ([]byte, bool) {
-	i := bytes.Index(in, ProtoEncodingPrefix)
-	if i >= 0 && i < len(in) {
-		return in[i:], true
+	return valueWithPrefix(in, ProtoEncodingPrefix)
+}
+
+// valueWithPrefix returns the value of the input if it starts with the given prefix. The value is either
+// the whole input or, as printed by 'etcdctl get', the line following the key. The prefix is not searched
+// for elsewhere, since it may be part of a value encoded differently, e.g. a JSON string.
+func valueWithPrefix(in, prefix []byte) ([]byte, bool) {
+	if bytes.HasPrefix(in, prefix) {
+		return in, true
+	}
+	if i := bytes.IndexByte(in, '\n'); i >= 0 && bytes.HasPrefix(in[i+1:], prefix) {
+		return in[i+1:], true
 	}
 	return nil, false
 }
@@ -170,15 +232,13 @@ func tryFindJSON(in []byte) (*json.RawMessage, bool) {
 	return nil, false
 }
 
-// DecodeSummary decodes the TypeMeta, ContentEncoding and ContentType fields from the 'Unknown'
-// protobuf envelope of the given storage data.
+// DecodeSummary writes the Summary of the given data as text, see Summarize.
 func DecodeSummary(inMediaType string, in []byte, out io.Writer) error {
-	typeMeta, err := DecodeTypeMeta(inMediaType, in)
+	summary, err := Summarize(inMediaType, in)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(out, "TypeMeta.APIVersion: %s\n", typeMeta.APIVersion)
-	fmt.Fprintf(out, "TypeMeta.Kind: %s\n", typeMeta.Kind)
+	summary.WriteText(out)
 	return nil
 }
 
@@ -199,7 +259,7 @@ func DecodeUnknown(in []byte) (*runtime.Unknown, error) {
 	return unknown, nil
 }
 
-// NewCodec creates a new kubernetes storage codec for encoding and decoding persisted data.
+// newCodec creates a new kubernetes storage codec for encoding and decoding persisted data.
 func newCodec(codecs serializer.CodecFactory, typeMeta *runtime.TypeMeta, mediaType string) (runtime.Codec, error) {
 	// For api machinery purposes, we treat StorageBinaryMediaType as ProtobufMediaType
 	if mediaType == StorageBinaryMediaType {
@@ -225,7 +285,7 @@ func newCodec(codecs serializer.CodecFactory, typeMeta *runtime.TypeMeta, mediaT
 	return codec, nil
 }
 
-// DecodeTypeMeta gets the TypeMeta from the given data, either as JSON or Protobuf.
+// DecodeTypeMeta gets the TypeMeta from the given data, either as JSON, YAML, CBOR or Protobuf.
 func DecodeTypeMeta(inMediaType string, in []byte) (*runtime.TypeMeta, error) {
 	switch inMediaType {
 	case JsonMediaType:
@@ -234,6 +294,8 @@ func DecodeTypeMeta(inMediaType string, in []byte) (*runtime.TypeMeta, error) {
 		return typeMetaFromBinaryStorage(in)
 	case YamlMediaType:
 		return typeMetaFromYaml(in)
+	case CborMediaType:
+		return typeMetaFromCbor(in)
 	default:
 		return nil, fmt.Errorf("unsupported inMediaType %s", inMediaType)
 	}
@@ -262,3 +324,13 @@ func typeMetaFromYaml(in []byte) (*runtime.TypeMeta, error) {
 	}
 	return &meta, nil
 }
+
+func typeMetaFromCbor(in []byte) (*runtime.TypeMeta, error) {
+	var obj map[string]interface{}
+	if err := direct.Unmarshal(in, &obj); err != nil {
+		return nil, err
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	return &runtime.TypeMeta{APIVersion: apiVersion, Kind: kind}, nil
+}
@@ -27,7 +27,8 @@ var findProtoTests = []struct {
 	expected string
 }{
 	{string(ProtoEncodingPrefix), true, string(ProtoEncodingPrefix)},
-	{fmt.Sprintf("xxxxxx%s...end", ProtoEncodingPrefix), true, fmt.Sprintf("%s...end", ProtoEncodingPrefix)},
+	{fmt.Sprintf("/registry/pods/default/web\n%s...end", ProtoEncodingPrefix), true, fmt.Sprintf("%s...end", ProtoEncodingPrefix)},
+	{fmt.Sprintf("xxxxxx%s...end", ProtoEncodingPrefix), false, ""},
 	{"xxxxxx{}", false, ""},
 }
 
@@ -45,6 +46,58 @@ func TestTryFindProto(t *testing.T) {
 	}
 }
 
+var findCborTests = []struct {
+	in       string
+	ok       bool
+	expected string
+}{
+	{string(CborEncodingPrefix), true, string(CborEncodingPrefix)},
+	{fmt.Sprintf("/registry/example.com/widgets/default/w\n%s...end", CborEncodingPrefix), true, fmt.Sprintf("%s...end", CborEncodingPrefix)},
+	{fmt.Sprintf("xxxxxx%s...end", CborEncodingPrefix), false, ""},
+	{"xxxxxx{}", false, ""},
+}
+
+func TestTryFindCbor(t *testing.T) {
+	for _, test := range findCborTests {
+		out, ok := tryFindCbor([]byte(test.in))
+		if test.ok != ok {
+			t.Errorf("got ok=%t, want ok=%t for %+v", ok, test.ok, test)
+		}
+		if ok {
+			if string(out) != test.expected {
+				t.Errorf("got %s, want %s for %+v", out, test.expected, test)
+			}
+		}
+	}
+}
+
+var detectTests = []struct {
+	in        string
+	mediaType string
+}{
+	{fmt.Sprintf("%s{}", ProtoEncodingPrefix), StorageBinaryMediaType},
+	{fmt.Sprintf("/registry/pods/default/web\n%s{}", ProtoEncodingPrefix), StorageBinaryMediaType},
+	{fmt.Sprintf("%s\xa1Dkind{}", CborEncodingPrefix), CborMediaType},
+	// A CBOR value holding the protobuf prefix in a byte string.
+	{fmt.Sprintf("%s\xa1Ddata\x44%s", CborEncodingPrefix, ProtoEncodingPrefix), CborMediaType},
+	{`xx{"kind": "Pod"}`, JsonMediaType},
+	// A JSON value holding the CBOR tag in a string.
+	{fmt.Sprintf(`{"kind": "Secret", "data": "%s"}`, CborEncodingPrefix), JsonMediaType},
+}
+
+func TestDetectAndExtract(t *testing.T) {
+	for _, test := range detectTests {
+		mediaType, _, err := DetectAndExtract([]byte(test.in))
+		if err != nil {
+			t.Errorf("unexpected error %v for %+v", err, test)
+			continue
+		}
+		if mediaType != test.mediaType {
+			t.Errorf("got %s, want %s for %+v", mediaType, test.mediaType, test)
+		}
+	}
+}
+
 var findJSONTests = []struct {
 	in       string
 	ok       bool
@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/etcd-io/auger/pkg/descriptors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// DecodeObject decodes a value stored in etcd, such as the values returned by the data and client
+// packages, to an object. Values must be decrypted first. Objects whose kind is registered in the scheme
+// are typed, e.g. a *corev1.Pod, other objects are an *unstructured.Unstructured.
+func DecodeObject(codecs serializer.CodecFactory, stored []byte) (runtime.Object, error) {
+	return NewDecoder(codecs).DecodeObject(stored)
+}
+
+// EncodeObject encodes a typed or unstructured object to the given media type. Objects whose kind is
+// registered in the scheme are encoded to StorageBinaryMediaType the way kube-apiserver stores them. Other
+// objects, such as custom resources, which kube-apiserver stores as JSON or CBOR, can't be encoded to
+// StorageBinaryMediaType.
+func EncodeObject(codecs serializer.CodecFactory, obj runtime.Object, outMediaType string) ([]byte, error) {
+	return NewEncoder(codecs).Encode(obj, outMediaType)
+}
+
+// DecodeObject detects the media type of a value stored in etcd and decodes it, like the DecodeObject
+// function. Objects in the binary storage representation whose kind is neither registered in the scheme
+// nor a type removed from k8s.io/api fail with ErrUnknownKind.
+func (d *Decoder) DecodeObject(stored []byte) (runtime.Object, error) {
+	inMediaType, in, err := DetectAndExtract(stored)
+	if err != nil {
+		return nil, err
+	}
+	obj, _, err := d.Decode(inMediaType, in)
+	if !errors.Is(err, ErrUnknownKind) {
+		return obj, err
+	}
+
+	if inMediaType != StorageBinaryMediaType {
+		// Custom resources are not in the scheme.
+		return decodeUnstructured(inMediaType, in)
+	}
+	unknown, unknownErr := DecodeUnknown(in)
+	if unknownErr != nil {
+		return nil, unknownErr
+	}
+	if !descriptors.Has(unknown.GroupVersionKind()) {
+		return nil, err
+	}
+	// Types removed from k8s.io/api are decoded with the protobuf descriptors of past releases.
+	js, err := descriptors.ConvertToJSON(unknown.TypeMeta, unknown.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding from %s: %w", inMediaType, err)
+	}
+	return decodeUnstructured(JsonMediaType, js)
+}
+
+// encodeUnstructured encodes an unstructured object by converting its JSON, which decodes it to its typed
+// object if its kind is registered in the scheme.
+func (e *Encoder) encodeUnstructured(obj *unstructured.Unstructured, outMediaType string) ([]byte, error) {
+	js, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding object: %w", err)
+	}
+	d := &Decoder{cache: e.cache}
+	encoded, _, err := d.Convert(JsonMediaType, outMediaType, js)
+	return encoded, err
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/scheme"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const widgetJSON = `{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w","namespace":"default"},"spec":{"color":"blue"}}`
+
+func TestDecodeObject(t *testing.T) {
+	widgetCbor, err := EncodeObject(scheme.Codecs, testWidget(t), CborMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cronJob := storageBinary(t, runtime.TypeMeta{APIVersion: "batch/v2alpha1", Kind: "CronJob"},
+		appendField(nil, 1, appendField(nil, 1, []byte("backup"))))
+
+	cases := []struct {
+		name   string
+		stored []byte
+		check  func(t *testing.T, obj runtime.Object)
+	}{
+		{
+			name:   "typed",
+			stored: storedPod(t),
+			check: func(t *testing.T, obj runtime.Object) {
+				pod, ok := obj.(*corev1.Pod)
+				if !ok {
+					t.Fatalf("got %T, want *corev1.Pod", obj)
+				}
+				if pod.Name != "web" || pod.Spec.Containers[0].Image != "nginx" {
+					t.Errorf("got pod %+v", pod)
+				}
+			},
+		},
+		{
+			name:   "custom resource json",
+			stored: []byte(widgetJSON),
+			check:  checkWidget,
+		},
+		{
+			name:   "custom resource cbor",
+			stored: widgetCbor,
+			check:  checkWidget,
+		},
+		{
+			name:   "removed type",
+			stored: cronJob,
+			check: func(t *testing.T, obj runtime.Object) {
+				u, ok := obj.(*unstructured.Unstructured)
+				if !ok {
+					t.Fatalf("got %T, want *unstructured.Unstructured", obj)
+				}
+				if u.GetAPIVersion() != "batch/v2alpha1" || u.GetKind() != "CronJob" || u.GetName() != "backup" {
+					t.Errorf("got %v", u.Object)
+				}
+			},
+		},
+	}
+	d := NewDecoder(scheme.Codecs)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := d.DecodeObject(tt.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, obj)
+		})
+	}
+}
+
+func TestDecodeObjectUnknownKind(t *testing.T) {
+	in := storageBinary(t, runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}, appendField(nil, 1, nil))
+	if _, err := DecodeObject(scheme.Codecs, in); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownKind)
+	}
+}
+
+func TestEncodeObjectUnstructured(t *testing.T) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(testPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := EncodeObject(scheme.Codecs, &unstructured.Unstructured{Object: obj}, StorageBinaryMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := storedPod(t); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	js, err := EncodeObject(scheme.Codecs, testWidget(t), JsonMediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != widgetJSON+"\n" {
+		t.Errorf("got %s, want %s", js, widgetJSON)
+	}
+	if _, err := EncodeObject(scheme.Codecs, testWidget(t), StorageBinaryMediaType); err == nil {
+		t.Error("expected an error encoding a custom resource to the binary storage representation")
+	}
+}
+
+func testWidget(t *testing.T) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON([]byte(widgetJSON)); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func checkWidget(t *testing.T, obj runtime.Object) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		t.Fatalf("got %T, want *unstructured.Unstructured", obj)
+	}
+	if color, _, _ := unstructured.NestedString(u.Object, "spec", "color"); u.GetName() != "w" || color != "blue" {
+		t.Errorf("got %v", u.Object)
+	}
+}
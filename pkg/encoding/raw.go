@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DumpRaw writes the 'Unknown' protobuf envelope of the given storage data, followed by its payload
+// decoded without a schema as a tree of field numbers, wire types and values, like
+// 'protoc --decode_raw'.
+func DumpRaw(in []byte, out io.Writer) error {
+	unknown, err := DecodeUnknown(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "TypeMeta.APIVersion: %s\n", unknown.APIVersion)
+	fmt.Fprintf(out, "TypeMeta.Kind: %s\n", unknown.Kind)
+	fmt.Fprintf(out, "ContentType: %q\n", unknown.ContentType)
+	fmt.Fprintf(out, "ContentEncoding: %q\n", unknown.ContentEncoding)
+	fmt.Fprintf(out, "Raw.Length: %d\n", len(unknown.Raw))
+	fmt.Fprintln(out, "Raw:")
+	if !isMessage(unknown.Raw) {
+		fmt.Fprintf(out, "  # not a protobuf message\n  %q\n", unknown.Raw)
+		return nil
+	}
+	dumpFields(unknown.Raw, "  ", out)
+	return nil
+}
+
+// dumpFields writes the fields of a protobuf message, which must have been checked with isMessage.
+// Length-delimited values are written as nested messages if they parse as one and are not printable
+// text, and as quoted strings otherwise.
+func dumpFields(b []byte, indent string, out io.Writer) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			b = b[n:]
+			fmt.Fprintf(out, "%s%d (varint): %d\n", indent, num, v)
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			b = b[n:]
+			fmt.Fprintf(out, "%s%d (fixed32): 0x%08x\n", indent, num, v)
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			b = b[n:]
+			fmt.Fprintf(out, "%s%d (fixed64): 0x%016x\n", indent, num, v)
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			b = b[n:]
+			if len(v) > 0 && !isPrintable(v) && isMessage(v) {
+				fmt.Fprintf(out, "%s%d (bytes) {\n", indent, num)
+				dumpFields(v, indent+"  ", out)
+				fmt.Fprintf(out, "%s}\n", indent)
+			} else {
+				fmt.Fprintf(out, "%s%d (bytes): %q\n", indent, num, v)
+			}
+		case protowire.StartGroupType:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			// The group ends with an end group tag, which is not part of its fields.
+			v := b[:n-protowire.SizeTag(num)]
+			b = b[n:]
+			fmt.Fprintf(out, "%s%d (group) {\n", indent, num)
+			dumpFields(v, indent+"  ", out)
+			fmt.Fprintf(out, "%s}\n", indent)
+		}
+	}
+}
+
+// isMessage returns whether the given bytes parse as a sequence of protobuf fields.
+func isMessage(b []byte) bool {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ == protowire.EndGroupType {
+			return false
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false
+		}
+		if typ == protowire.StartGroupType && !isMessage(b[:n-protowire.SizeTag(num)]) {
+			return false
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	return strings.IndexFunc(string(b), func(r rune) bool {
+		return !unicode.IsPrint(r) && !unicode.IsSpace(r)
+	}) < 0
+}
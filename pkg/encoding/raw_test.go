@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func appendField(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func storageBinary(t *testing.T, typeMeta runtime.TypeMeta, raw []byte) []byte {
+	unknown := &runtime.Unknown{TypeMeta: typeMeta, Raw: raw, ContentType: "application/vnd.kubernetes.protobuf"}
+	b, err := unknown.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, ProtoEncodingPrefix...), b...)
+}
+
+var dumpFieldsTests = []struct {
+	name     string
+	in       []byte
+	expected string
+}{
+	{
+		name:     "scalars",
+		in:       protowire.AppendFixed32(protowire.AppendTag(protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 150), 2, protowire.Fixed32Type), 0x3f800000),
+		expected: "1 (varint): 150\n2 (fixed32): 0x3f800000\n",
+	},
+	{
+		name:     "nested",
+		in:       appendField(nil, 1, appendField(nil, 2, []byte{0x01, 0x02})),
+		expected: "1 (bytes) {\n  2 (bytes): \"\\x01\\x02\"\n}\n",
+	},
+	{
+		// "hi" also parses as field 13 with varint 105, but printable text is shown as a string.
+		name:     "printable",
+		in:       appendField(nil, 1, []byte("hi")),
+		expected: "1 (bytes): \"hi\"\n",
+	},
+	{
+		name:     "empty",
+		in:       appendField(nil, 1, nil),
+		expected: "1 (bytes): \"\"\n",
+	},
+	{
+		name: "group",
+		in: protowire.AppendTag(protowire.AppendVarint(protowire.AppendTag(protowire.AppendTag(nil, 1, protowire.StartGroupType),
+			2, protowire.VarintType), 7), 1, protowire.EndGroupType),
+		expected: "1 (group) {\n  2 (varint): 7\n}\n",
+	},
+}
+
+func TestDumpFields(t *testing.T) {
+	for _, test := range dumpFieldsTests {
+		t.Run(test.name, func(t *testing.T) {
+			if !isMessage(test.in) {
+				t.Fatalf("expected %q to parse as a message", test.in)
+			}
+			out := new(bytes.Buffer)
+			dumpFields(test.in, "", out)
+			if out.String() != test.expected {
+				t.Errorf("got:\n%s\nwant:\n%s", out, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsMessage(t *testing.T) {
+	for _, in := range [][]byte{
+		{0xff},                // truncated tag
+		{0x0a, 0x05, 'a'},     // truncated length-delimited value
+		{0x0c},                // unexpected end group
+		{0x0b, 0x10, 0x01},    // unterminated group
+		{0x00, 0x01, 0x02, 3}, // field number 0
+	} {
+		if isMessage(in) {
+			t.Errorf("expected %q not to parse as a message", in)
+		}
+	}
+}
+
+func TestDumpRaw(t *testing.T) {
+	typeMeta := runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}
+	in := storageBinary(t, typeMeta, appendField(nil, 1, appendField(nil, 1, []byte("widget"))))
+	out := new(bytes.Buffer)
+	if err := DumpRaw(in, out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `TypeMeta.APIVersion: example.com/v1
+TypeMeta.Kind: Widget
+ContentType: "application/vnd.kubernetes.protobuf"
+ContentEncoding: ""
+Raw.Length: 10
+Raw:
+  1 (bytes) {
+    1 (bytes): "widget"
+  }
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
+	}
+
+	out.Reset()
+	if err := DumpRaw(storageBinary(t, typeMeta, []byte{0xff}), out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(out.Bytes(), []byte("Raw:\n  # not a protobuf message\n  \"\\xff\"\n")) {
+		t.Errorf("got:\n%s\nexpected the raw bytes to be quoted", out)
+	}
+}
+
+func TestConvertUnknownKind(t *testing.T) {
+	codecs := serializer.NewCodecFactory(runtime.NewScheme())
+	in := storageBinary(t, runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}, appendField(nil, 1, nil))
+	if _, _, err := Convert(codecs, StorageBinaryMediaType, JsonMediaType, in); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownKind)
+	}
+	out, typeMeta, err := Convert(codecs, StorageBinaryMediaType, ProtoRawMediaType, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typeMeta.Kind != "Widget" || !bytes.Contains(out, []byte("1 (bytes): \"\"")) {
+		t.Errorf("got %+v:\n%s", typeMeta, out)
+	}
+	if _, _, err := Convert(codecs, JsonMediaType, ProtoRawMediaType, []byte(`{"apiVersion":"v1","kind":"Pod"}`)); err == nil {
+		t.Error("expected an error converting json to proto-raw")
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/cbor/direct"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// objectMetaField is the field number of the metadata of all kubernetes objects.
+	objectMetaField = 1
+	// managedFieldsField is the field number of managedFields in ObjectMeta.
+	managedFieldsField = 17
+)
+
+// Summary is the metadata of a stored object. Size is the size of the payload, the Raw field of the
+// envelope for the binary storage representation, whose ContentType and ContentEncoding are only set for
+// that representation.
+type Summary struct {
+	TypeMeta        runtime.TypeMeta   `json:"typeMeta"`
+	MediaType       string             `json:"mediaType"`
+	ContentType     string             `json:"contentType,omitempty"`
+	ContentEncoding string             `json:"contentEncoding,omitempty"`
+	Size            int                `json:"size"`
+	Metadata        *ObjectMetaSummary `json:"metadata,omitempty"`
+}
+
+// ObjectMetaSummary is the part of the ObjectMeta of a stored object that matters for triage.
+// ManagedFieldsSize is the size of the managedFields in the encoding of the object, JSON for YAML, and
+// AnnotationsSize is the total length of the annotation keys and values, which kube-apiserver limits.
+type ObjectMetaSummary struct {
+	Name              string                  `json:"name,omitempty"`
+	Namespace         string                  `json:"namespace,omitempty"`
+	UID               types.UID               `json:"uid,omitempty"`
+	ResourceVersion   string                  `json:"resourceVersion,omitempty"`
+	Generation        int64                   `json:"generation,omitempty"`
+	CreationTimestamp *metav1.Time            `json:"creationTimestamp,omitempty"`
+	DeletionTimestamp *metav1.Time            `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string                `json:"finalizers,omitempty"`
+	OwnerReferences   []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+	ManagedFieldsSize int                     `json:"managedFieldsSize"`
+	AnnotationsSize   int                     `json:"annotationsSize"`
+}
+
+// Summarize reads the Summary of the given data. Only the envelope and the metadata are decoded, so kinds
+// that are not registered in the scheme are summarized as well. Objects without metadata have none.
+func Summarize(inMediaType string, in []byte) (*Summary, error) {
+	switch inMediaType {
+	case StorageBinaryMediaType:
+		return summarizeBinaryStorage(in)
+	case JsonMediaType:
+		return summarizeJSON(inMediaType, in, in)
+	case YamlMediaType:
+		js, err := yaml.YAMLToJSON(in)
+		if err != nil {
+			return nil, err
+		}
+		return summarizeJSON(inMediaType, in, js)
+	case CborMediaType:
+		return summarizeCbor(in)
+	default:
+		return nil, fmt.Errorf("unsupported inMediaType %s", inMediaType)
+	}
+}
+
+func summarizeBinaryStorage(in []byte) (*Summary, error) {
+	unknown, err := DecodeUnknown(in)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{
+		TypeMeta:        unknown.TypeMeta,
+		MediaType:       StorageBinaryMediaType,
+		ContentType:     unknown.ContentType,
+		ContentEncoding: unknown.ContentEncoding,
+		Size:            len(unknown.Raw),
+	}
+	metadata, err := protoField(unknown.Raw, objectMetaField)
+	if err != nil || metadata == nil {
+		return summary, err
+	}
+
+	// The managedFields are usually the bulk of the metadata, only their size is needed.
+	var rest []byte
+	managedFieldsSize := 0
+	for b := metadata; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("error decoding metadata: %w", protowire.ParseError(n))
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, fmt.Errorf("error decoding metadata: %w", protowire.ParseError(m))
+		}
+		if num == managedFieldsField {
+			managedFieldsSize += n + m
+		} else {
+			rest = append(rest, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	meta := &metav1.ObjectMeta{}
+	if err := meta.Unmarshal(rest); err != nil {
+		return nil, fmt.Errorf("error decoding metadata: %w", err)
+	}
+	summary.Metadata = summarizeObjectMeta(meta, managedFieldsSize)
+	return summary, nil
+}
+
+// protoField returns the value of a length-delimited field of a protobuf message, or nil if it is not set.
+func protoField(b []byte, field protowire.Number) ([]byte, error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == field && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			return v, nil
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil, nil
+}
+
+func summarizeJSON(inMediaType string, in, js []byte) (*Summary, error) {
+	var obj struct {
+		runtime.TypeMeta `json:",inline"`
+		Metadata         map[string]json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, err
+	}
+	summary := &Summary{TypeMeta: obj.TypeMeta, MediaType: inMediaType, Size: len(in)}
+	if obj.Metadata == nil {
+		return summary, nil
+	}
+	managedFieldsSize := len(obj.Metadata["managedFields"])
+	delete(obj.Metadata, "managedFields")
+	metadata, err := json.Marshal(obj.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	meta := &metav1.ObjectMeta{}
+	if err := json.Unmarshal(metadata, meta); err != nil {
+		return nil, fmt.Errorf("error decoding metadata: %w", err)
+	}
+	summary.Metadata = summarizeObjectMeta(meta, managedFieldsSize)
+	return summary, nil
+}
+
+func summarizeCbor(in []byte) (*Summary, error) {
+	var obj map[string]interface{}
+	if err := direct.Unmarshal(in, &obj); err != nil {
+		return nil, err
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	summary := &Summary{TypeMeta: runtime.TypeMeta{APIVersion: apiVersion, Kind: kind}, MediaType: CborMediaType, Size: len(in)}
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return summary, nil
+	}
+	managedFieldsSize := 0
+	if managedFields, ok := metadata["managedFields"]; ok {
+		encoded, err := direct.Marshal(managedFields)
+		if err != nil {
+			return nil, err
+		}
+		managedFieldsSize = len(encoded)
+		delete(metadata, "managedFields")
+	}
+	meta := &metav1.ObjectMeta{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadata, meta); err != nil {
+		return nil, fmt.Errorf("error decoding metadata: %w", err)
+	}
+	summary.Metadata = summarizeObjectMeta(meta, managedFieldsSize)
+	return summary, nil
+}
+
+func summarizeObjectMeta(meta *metav1.ObjectMeta, managedFieldsSize int) *ObjectMetaSummary {
+	summary := &ObjectMetaSummary{
+		Name:              meta.Name,
+		Namespace:         meta.Namespace,
+		UID:               meta.UID,
+		ResourceVersion:   meta.ResourceVersion,
+		Generation:        meta.Generation,
+		DeletionTimestamp: meta.DeletionTimestamp,
+		Finalizers:        meta.Finalizers,
+		OwnerReferences:   meta.OwnerReferences,
+		ManagedFieldsSize: managedFieldsSize,
+	}
+	if !meta.CreationTimestamp.IsZero() {
+		summary.CreationTimestamp = &meta.CreationTimestamp
+	}
+	for k, v := range meta.Annotations {
+		summary.AnnotationsSize += len(k) + len(v)
+	}
+	return summary
+}
+
+// WriteText writes the summary as '<field>: <value>' lines. Metadata fields that are not set are omitted.
+func (s *Summary) WriteText(out io.Writer) {
+	fmt.Fprintf(out, "TypeMeta.APIVersion: %s\n", s.TypeMeta.APIVersion)
+	fmt.Fprintf(out, "TypeMeta.Kind: %s\n", s.TypeMeta.Kind)
+	fmt.Fprintf(out, "MediaType: %s\n", s.MediaType)
+	if s.MediaType == StorageBinaryMediaType {
+		fmt.Fprintf(out, "ContentType: %q\n", s.ContentType)
+		fmt.Fprintf(out, "ContentEncoding: %q\n", s.ContentEncoding)
+	}
+	fmt.Fprintf(out, "Size: %d\n", s.Size)
+	m := s.Metadata
+	if m == nil {
+		return
+	}
+	writeField := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(out, "Metadata.%s: %s\n", name, value)
+		}
+	}
+	writeTime := func(name string, t *metav1.Time) {
+		if t != nil {
+			writeField(name, t.UTC().Format(time.RFC3339))
+		}
+	}
+	writeField("Name", m.Name)
+	writeField("Namespace", m.Namespace)
+	writeField("UID", string(m.UID))
+	writeField("ResourceVersion", m.ResourceVersion)
+	if m.Generation != 0 {
+		writeField("Generation", fmt.Sprint(m.Generation))
+	}
+	writeTime("CreationTimestamp", m.CreationTimestamp)
+	writeTime("DeletionTimestamp", m.DeletionTimestamp)
+	writeField("Finalizers", strings.Join(m.Finalizers, ", "))
+	owners := make([]string, 0, len(m.OwnerReferences))
+	for _, ref := range m.OwnerReferences {
+		owners = append(owners, ref.Kind+"/"+ref.Name)
+	}
+	writeField("OwnerReferences", strings.Join(owners, ", "))
+	fmt.Fprintf(out, "Metadata.ManagedFieldsSize: %d\n", m.ManagedFieldsSize)
+	fmt.Fprintf(out, "Metadata.AnnotationsSize: %d\n", m.AnnotationsSize)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/etcd-io/auger/pkg/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func TestSummarize(t *testing.T) {
+	created := metav1.NewTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	deleted := metav1.NewTime(created.Add(time.Hour))
+	pod := testPod()
+	pod.UID = "0c5e1a2b-7d3f-4e6a-9b8c-1d2e3f4a5b6c"
+	pod.CreationTimestamp = created
+	pod.DeletionTimestamp = &deleted
+	pod.Finalizers = []string{"example.com/cleanup"}
+	pod.Annotations = map[string]string{"a": "bc"}
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-abc", UID: "1"}}
+	pod.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:    "kubectl",
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}}}`)},
+	}}
+	managedFields, err := json.Marshal(pod.ManagedFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ObjectMetaSummary{
+		Name:              "web",
+		Namespace:         "default",
+		UID:               pod.UID,
+		CreationTimestamp: &created,
+		DeletionTimestamp: &deleted,
+		Finalizers:        pod.Finalizers,
+		OwnerReferences:   pod.OwnerReferences,
+		AnnotationsSize:   3,
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inMediaType := range []string{StorageBinaryMediaType, JsonMediaType, YamlMediaType, CborMediaType} {
+		t.Run(inMediaType, func(t *testing.T) {
+			var in []byte
+			if inMediaType == YamlMediaType {
+				js, err := EncodeObject(scheme.Codecs, pod, JsonMediaType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				in, err = yaml.JSONToYAML(js)
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				in, err = EncodeObject(scheme.Codecs, pod, inMediaType)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			summary, err := Summarize(inMediaType, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.TypeMeta != (runtime.TypeMeta{APIVersion: "v1", Kind: "Pod"}) || summary.MediaType != inMediaType {
+				t.Errorf("got %+v", summary)
+			}
+			got := *summary.Metadata
+			if got.ManagedFieldsSize == 0 {
+				t.Error("got no managedFields size")
+			}
+			if inMediaType == JsonMediaType && got.ManagedFieldsSize != len(managedFields) {
+				t.Errorf("got managedFields size %d, want %d", got.ManagedFieldsSize, len(managedFields))
+			}
+			// Timestamps are decoded in the local time zone, compare their JSON.
+			got.ManagedFieldsSize = 0
+			gotJSON, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("got %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestSummarizeUnknownKind(t *testing.T) {
+	in := storageBinary(t, runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"},
+		appendField(nil, 1, appendField(nil, 1, []byte("w"))))
+	summary, err := Summarize(StorageBinaryMediaType, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TypeMeta.Kind != "Widget" || summary.Metadata == nil || summary.Metadata.Name != "w" {
+		t.Errorf("got %+v", summary)
+	}
+}
@@ -0,0 +1,391 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encryption decrypts and encrypts kubernetes objects the same way kube-apiserver encrypts
+// them at rest, using the providers declared in an EncryptionConfiguration file.
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// See k8s.io/apiserver/pkg/apis/apiserver/v1/types.go
+	ConfigAPIVersion = "apiserver.config.k8s.io/v1"
+	ConfigKind       = "EncryptionConfiguration"
+
+	AESCBCProvider    = "aescbc"
+	AESGCMProvider    = "aesgcm"
+	SecretboxProvider = "secretbox"
+	IdentityProvider  = "identity"
+)
+
+// EncryptedPrefix is the prefix kube-apiserver writes before every value encrypted at rest.
+var EncryptedPrefix = []byte("k8s:enc:")
+
+// EncryptionConfiguration mirrors the apiserver.config.k8s.io/v1 EncryptionConfiguration type.
+type EncryptionConfiguration struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Resources  []ResourceConfiguration `json:"resources"`
+}
+
+// ResourceConfiguration stores the providers used for a list of resources.
+type ResourceConfiguration struct {
+	Resources []string                `json:"resources"`
+	Providers []ProviderConfiguration `json:"providers"`
+}
+
+// ProviderConfiguration stores the configuration of a single encryption provider.
+// Exactly one of the fields is expected to be set.
+type ProviderConfiguration struct {
+	AESGCM    *AESConfiguration       `json:"aesgcm,omitempty"`
+	AESCBC    *AESConfiguration       `json:"aescbc,omitempty"`
+	Secretbox *SecretboxConfiguration `json:"secretbox,omitempty"`
+	Identity  *IdentityConfiguration  `json:"identity,omitempty"`
+	KMS       *KMSConfiguration       `json:"kms,omitempty"`
+}
+
+// AESConfiguration contains the keys of an aescbc or aesgcm provider.
+type AESConfiguration struct {
+	Keys []Key `json:"keys"`
+}
+
+// SecretboxConfiguration contains the keys of a secretbox provider.
+type SecretboxConfiguration struct {
+	Keys []Key `json:"keys"`
+}
+
+// IdentityConfiguration is an empty struct to allow the identity provider in the provider list.
+type IdentityConfiguration struct{}
+
+// Key contains the name and base64 encoded secret of a key.
+type Key struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// Config holds the transformers built from an EncryptionConfiguration.
+type Config struct {
+	resources []resourceTransformers
+}
+
+type resourceTransformers struct {
+	resources    []string
+	transformers []*prefixTransformer
+}
+
+// prefixTransformer binds a transformer to the prefix kube-apiserver writes before its output.
+type prefixTransformer struct {
+	provider    string
+	keyName     string
+	prefix      []byte
+	transformer transformer
+}
+
+// transformer decrypts and encrypts values stored at rest using a single provider key.
+type transformer interface {
+	transformFromStorage(data []byte, authenticatedData []byte) ([]byte, error)
+	transformToStorage(data []byte, authenticatedData []byte) ([]byte, error)
+}
+
+// LoadConfig reads and parses the EncryptionConfiguration file at the given path.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading encryption config file %s: %w", filename, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing encryption config file %s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses an EncryptionConfiguration, in either YAML or JSON, and builds the transformers
+// for all of its providers.
+func ParseConfig(data []byte) (*Config, error) {
+	ec := &EncryptionConfiguration{}
+	if err := yaml.UnmarshalStrict(data, ec); err != nil {
+		return nil, err
+	}
+	if ec.APIVersion != ConfigAPIVersion || ec.Kind != ConfigKind {
+		return nil, fmt.Errorf("unsupported encryption config %s/%s, expected %s/%s", ec.APIVersion, ec.Kind, ConfigAPIVersion, ConfigKind)
+	}
+	if len(ec.Resources) == 0 {
+		return nil, errors.New("encryption config contains no resources")
+	}
+
+	config := &Config{}
+	for i, rc := range ec.Resources {
+		if len(rc.Providers) == 0 {
+			return nil, fmt.Errorf("resources[%d] contains no providers", i)
+		}
+		rt := resourceTransformers{resources: rc.Resources}
+		for j, pc := range rc.Providers {
+			transformers, err := newPrefixTransformers(pc)
+			if err != nil {
+				return nil, fmt.Errorf("resources[%d].providers[%d]: %w", i, j, err)
+			}
+			rt.transformers = append(rt.transformers, transformers...)
+		}
+		config.resources = append(config.resources, rt)
+	}
+	return config, nil
+}
+
+func newPrefixTransformers(pc ProviderConfiguration) ([]*prefixTransformer, error) {
+	var provider string
+	var keys []Key
+	var newTransformer func(secret []byte) (transformer, error)
+	set := 0
+	if pc.AESCBC != nil {
+		provider, keys, newTransformer = AESCBCProvider, pc.AESCBC.Keys, newCBCTransformer
+		set++
+	}
+	if pc.AESGCM != nil {
+		provider, keys, newTransformer = AESGCMProvider, pc.AESGCM.Keys, newGCMTransformer
+		set++
+	}
+	if pc.Secretbox != nil {
+		provider, keys, newTransformer = SecretboxProvider, pc.Secretbox.Keys, newSecretboxTransformer
+		set++
+	}
+	if pc.Identity != nil {
+		provider = IdentityProvider
+		set++
+	}
+	if pc.KMS != nil {
+		provider = KMSProvider
+		set++
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one provider must be set, got %d", set)
+	}
+	switch provider {
+	case IdentityProvider:
+		return []*prefixTransformer{{provider: IdentityProvider}}, nil
+	case KMSProvider:
+		t, err := newKMSPrefixTransformer(pc.KMS)
+		if err != nil {
+			return nil, err
+		}
+		return []*prefixTransformer{t}, nil
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s provider contains no keys", provider)
+	}
+
+	var result []*prefixTransformer
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("%s provider contains a key without a name", provider)
+		}
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode secret of %s key %s: %w", provider, key.Name, err)
+		}
+		t, err := newTransformer(secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret for %s key %s: %w", provider, key.Name, err)
+		}
+		result = append(result, &prefixTransformer{
+			provider:    provider,
+			keyName:     key.Name,
+			prefix:      providerPrefix(provider, key.Name),
+			transformer: t,
+		})
+	}
+	return result, nil
+}
+
+// providerPrefix returns the prefix kube-apiserver writes for the given provider key, e.g. 'k8s:enc:aescbc:v1:key1:'.
+func providerPrefix(provider, keyName string) []byte {
+	return []byte(fmt.Sprintf("%s%s:v1:%s:", EncryptedPrefix, provider, keyName))
+}
+
+// IsEncrypted returns true if the given value was encrypted at rest by kube-apiserver.
+func IsEncrypted(value []byte) bool {
+	return bytes.HasPrefix(value, EncryptedPrefix)
+}
+
+// Decrypt decrypts a value read from etcd. The etcd key of the value is used as the authenticated
+// data of providers that require it, e.g. aesgcm. Values that are not encrypted are returned as is,
+// which matches the behavior of the identity provider. Decrypt may be called on a nil Config, in
+// which case only values that are not encrypted are accepted.
+func (c *Config) Decrypt(key string, value []byte) ([]byte, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("value is encrypted at rest with prefix %q, an encryption config is required to decrypt it", encryptedValuePrefix(value))
+	}
+
+	matched := false
+	var errs []error
+	for _, rt := range c.resources {
+		for _, pt := range rt.transformers {
+			if pt.transformer == nil || !bytes.HasPrefix(value, pt.prefix) {
+				continue
+			}
+			matched = true
+			out, err := pt.transformer.transformFromStorage(value[len(pt.prefix):], []byte(key))
+			if err == nil {
+				return out, nil
+			}
+			errs = append(errs, fmt.Errorf("%s key %s: %w", pt.provider, pt.keyName, err))
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("no provider in the encryption config matches prefix of value %q", encryptedValuePrefix(value))
+	}
+	return nil, fmt.Errorf("unable to decrypt value: %w", errors.Join(errs...))
+}
+
+// Encrypt encrypts a storage encoded value the way kube-apiserver writes it to etcd, using the first
+// provider of the first resources entry of the config that matches the given resource. The etcd key
+// the value is written to is used as the authenticated data of providers that require it. If the
+// first provider is identity, or no resources entry matches, the value is returned as is.
+func (c *Config) Encrypt(resource schema.GroupResource, key string, value []byte) ([]byte, error) {
+	rt := c.resourceTransformers(resource)
+	if rt == nil {
+		return value, nil
+	}
+	pt := rt.transformers[0]
+	if pt.transformer == nil {
+		return value, nil
+	}
+	out, err := pt.transformer.transformToStorage(value, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt value with %s key %s: %w", pt.provider, pt.keyName, err)
+	}
+	return append(append([]byte{}, pt.prefix...), out...), nil
+}
+
+// resourceTransformers returns the first resources entry that matches the given resource, either
+// by name, e.g. 'secrets' or 'deployments.apps', or by wildcard, e.g. '*.apps' or '*.*'.
+func (c *Config) resourceTransformers(resource schema.GroupResource) *resourceTransformers {
+	if c == nil {
+		return nil
+	}
+	for i, rt := range c.resources {
+		for _, r := range rt.resources {
+			if resourceMatches(r, resource) {
+				return &c.resources[i]
+			}
+		}
+	}
+	return nil
+}
+
+func resourceMatches(pattern string, resource schema.GroupResource) bool {
+	name, group, _ := strings.Cut(pattern, ".")
+	if group != resource.Group && group != "*" {
+		return false
+	}
+	return name == "*" || name == resource.Resource
+}
+
+// Summary is the envelope of a value encrypted at rest. The key ID, DEK source type and annotations are
+// only set for the KMS v2 provider.
+type Summary struct {
+	Provider               string            `json:"provider"`
+	Version                string            `json:"version"`
+	Name                   string            `json:"name"`
+	KeyID                  string            `json:"keyID,omitempty"`
+	EncryptedDEKSourceType string            `json:"encryptedDEKSourceType,omitempty"`
+	Annotations            map[string][]byte `json:"annotations,omitempty"`
+}
+
+// Summarize reads the provider, version and key name of a value encrypted at rest, and for the KMS v2
+// provider the key ID and annotations of the EncryptedObject envelope.
+func Summarize(value []byte) (*Summary, error) {
+	if !IsEncrypted(value) {
+		return nil, errors.New("value is not encrypted at rest")
+	}
+	prefix := encryptedValuePrefix(value)
+	fields := strings.Split(strings.TrimSuffix(string(prefix[len(EncryptedPrefix):]), ":"), ":")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid encrypted value prefix %q", prefix)
+	}
+	summary := &Summary{Provider: fields[0], Version: fields[1], Name: fields[2]}
+	if summary.Provider != KMSProvider || summary.Version != KMSAPIVersionV2 {
+		return summary, nil
+	}
+
+	obj, err := DecodeEncryptedObject(value[len(prefix):])
+	if err != nil {
+		return nil, err
+	}
+	summary.KeyID = obj.KeyID
+	summary.EncryptedDEKSourceType = obj.EncryptedDEKSourceType.String()
+	summary.Annotations = obj.Annotations
+	return summary, nil
+}
+
+// WriteText writes the summary as 'Encryption.<field>: <value>' lines.
+func (s *Summary) WriteText(out io.Writer) {
+	fmt.Fprintf(out, "Encryption.Provider: %s\n", s.Provider)
+	fmt.Fprintf(out, "Encryption.Version: %s\n", s.Version)
+	fmt.Fprintf(out, "Encryption.Name: %s\n", s.Name)
+	if s.Provider != KMSProvider || s.Version != KMSAPIVersionV2 {
+		return
+	}
+	fmt.Fprintf(out, "Encryption.KeyID: %s\n", s.KeyID)
+	fmt.Fprintf(out, "Encryption.EncryptedDEKSourceType: %s\n", s.EncryptedDEKSourceType)
+	keys := make([]string, 0, len(s.Annotations))
+	for k := range s.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "Encryption.Annotations.%s: %q\n", k, s.Annotations[k])
+	}
+}
+
+// DecodeSummary writes the summary of a value encrypted at rest, see Summarize.
+func DecodeSummary(value []byte, out io.Writer) error {
+	summary, err := Summarize(value)
+	if err != nil {
+		return err
+	}
+	summary.WriteText(out)
+	return nil
+}
+
+// encryptedValuePrefix returns the 'k8s:enc:<provider>:v1:<keyname>:' prefix of an encrypted value for error reporting.
+func encryptedValuePrefix(value []byte) []byte {
+	end := len(EncryptedPrefix)
+	// Skip the provider, version and key name fields.
+	for n := 0; n < 3; n++ {
+		i := bytes.IndexByte(value[end:], ':')
+		if i < 0 {
+			return EncryptedPrefix
+		}
+		end += i + 1
+	}
+	return value[:end]
+}
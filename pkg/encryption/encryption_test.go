@@ -0,0 +1,249 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	testKey       = "/registry/secrets/default/test"
+	testPlaintext = []byte("k8s\x00\n\x0c\n\x02v1\x12\x06Secret")
+	testSecret    = []byte("0123456789abcdef0123456789abcdef")
+	testConfig    = `
+apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+  - resources:
+    - secrets
+    providers:
+    - aescbc:
+        keys:
+        - name: cbckey
+          secret: ` + base64.StdEncoding.EncodeToString(testSecret) + `
+    - aesgcm:
+        keys:
+        - name: gcmkey
+          secret: ` + base64.StdEncoding.EncodeToString(testSecret) + `
+    - secretbox:
+        keys:
+        - name: boxkey
+          secret: ` + base64.StdEncoding.EncodeToString(testSecret) + `
+    - identity: {}
+`
+)
+
+func TestDecrypt(t *testing.T) {
+	config, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name  string
+		value []byte
+	}{
+		{name: "aescbc", value: append([]byte("k8s:enc:aescbc:v1:cbckey:"), encryptCBC(t, testPlaintext)...)},
+		{name: "aesgcm", value: append([]byte("k8s:enc:aesgcm:v1:gcmkey:"), encryptGCM(t, testPlaintext, []byte(testKey))...)},
+		{name: "secretbox", value: append([]byte("k8s:enc:secretbox:v1:boxkey:"), encryptSecretbox(testPlaintext)...)},
+		{name: "identity", value: testPlaintext},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := config.Decrypt(testKey, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, testPlaintext) {
+				t.Errorf("got %q, want %q", out, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	config, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		key      string
+		value    []byte
+		expected string
+	}{
+		{
+			name:     "unknown-key",
+			key:      testKey,
+			value:    append([]byte("k8s:enc:aescbc:v1:otherkey:"), encryptCBC(t, testPlaintext)...),
+			expected: `no provider in the encryption config matches prefix of value "k8s:enc:aescbc:v1:otherkey:"`,
+		},
+		{
+			name:     "wrong-authenticated-data",
+			key:      "/registry/secrets/default/other",
+			value:    append([]byte("k8s:enc:aesgcm:v1:gcmkey:"), encryptGCM(t, testPlaintext, []byte(testKey))...),
+			expected: "unable to decrypt value: aesgcm key gcmkey",
+		},
+		{
+			name:     "cbc-iv-only",
+			key:      testKey,
+			value:    append([]byte("k8s:enc:aescbc:v1:cbckey:"), make([]byte, 16)...),
+			expected: "no ciphertext after the IV",
+		},
+		{
+			name:     "cbc-partial-block",
+			key:      testKey,
+			value:    append([]byte("k8s:enc:aescbc:v1:cbckey:"), make([]byte, 16+5)...),
+			expected: "not a multiple of the block size",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Decrypt(tt.key, tt.value)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %q, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider string
+		resource schema.GroupResource
+		prefix   string
+	}{
+		{name: "aescbc", provider: "aescbc", resource: schema.GroupResource{Resource: "secrets"}, prefix: "k8s:enc:aescbc:v1:k1:"},
+		{name: "aesgcm", provider: "aesgcm", resource: schema.GroupResource{Resource: "secrets"}, prefix: "k8s:enc:aesgcm:v1:k1:"},
+		{name: "secretbox", provider: "secretbox", resource: schema.GroupResource{Resource: "secrets"}, prefix: "k8s:enc:secretbox:v1:k1:"},
+		{name: "group-wildcard", provider: "aesgcm", resource: schema.GroupResource{Group: "apps", Resource: "deployments"}, prefix: "k8s:enc:aesgcm:v1:k1:"},
+		{name: "no-match", provider: "aesgcm", resource: schema.GroupResource{Resource: "configmaps"}},
+		{name: "identity", provider: "identity", resource: schema.GroupResource{Resource: "secrets"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := tt.provider + ": {keys: [{name: k1, secret: " + base64.StdEncoding.EncodeToString(testSecret) + "}]}"
+			if tt.provider == IdentityProvider {
+				provider = "identity: {}"
+			}
+			config, err := ParseConfig([]byte("apiVersion: apiserver.config.k8s.io/v1\nkind: EncryptionConfiguration\nresources:\n- resources: [secrets, '*.apps']\n  providers:\n  - " + provider + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := config.Encrypt(tt.resource, testKey, testPlaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prefix == "" {
+				if !bytes.Equal(value, testPlaintext) {
+					t.Fatalf("got %q, expected the value to be left unencrypted", value)
+				}
+				return
+			}
+			if !bytes.HasPrefix(value, []byte(tt.prefix)) {
+				t.Fatalf("got value %q, expected prefix %q", value, tt.prefix)
+			}
+			out, err := config.Decrypt(testKey, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, testPlaintext) {
+				t.Errorf("got %q, want %q", out, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name:     "wrong-kind",
+			config:   "apiVersion: v1\nkind: EncryptionConfig\nresources: []\n",
+			expected: "unsupported encryption config",
+		},
+		{
+			name:     "two-providers",
+			config:   "apiVersion: apiserver.config.k8s.io/v1\nkind: EncryptionConfiguration\nresources:\n- resources: [secrets]\n  providers:\n  - identity: {}\n    aescbc: {keys: [{name: k, secret: " + base64.StdEncoding.EncodeToString(testSecret) + "}]}\n",
+			expected: "exactly one provider must be set, got 2",
+		},
+		{
+			name:     "short-secret",
+			config:   "apiVersion: apiserver.config.k8s.io/v1\nkind: EncryptionConfiguration\nresources:\n- resources: [secrets]\n  providers:\n  - secretbox: {keys: [{name: k, secret: " + base64.StdEncoding.EncodeToString(testSecret[:16]) + "}]}\n",
+			expected: "secretbox key must be 32 bytes",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.config))
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %q, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func encryptCBC(t *testing.T, plaintext []byte) []byte {
+	block, err := aes.NewCipher(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paddingSize := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(paddingSize)}, paddingSize)...)
+	iv := bytes.Repeat([]byte{1}, aes.BlockSize)
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+	return append(iv, out...)
+}
+
+func encryptGCM(t *testing.T, plaintext, authenticatedData []byte) []byte {
+	block, err := aes.NewCipher(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{2}, aead.NonceSize())
+	return aead.Seal(nonce, nonce, plaintext, authenticatedData)
+}
+
+func encryptSecretbox(plaintext []byte) []byte {
+	var key [32]byte
+	copy(key[:], testSecret)
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], bytes.Repeat([]byte{3}, secretboxNonceSize))
+	return secretbox.Seal(nonce[:], plaintext, &nonce, &key)
+}
@@ -0,0 +1,552 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+	kmsapiv1beta1 "k8s.io/kms/apis/v1beta1"
+	kmsapiv2 "k8s.io/kms/apis/v2"
+)
+
+const (
+	KMSProvider = "kms"
+
+	KMSAPIVersionV1 = "v1"
+	KMSAPIVersionV2 = "v2"
+
+	// See k8s.io/apiserver/pkg/server/options/encryptionconfig/config.go
+	defaultKMSTimeout = 3 * time.Second
+
+	// kmsv1PluginAPIVersion is the version sent in each KMS v1 plugin request.
+	kmsv1PluginAPIVersion = "v1beta1"
+)
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/envelope/kmsv2/v2/api.proto
+type EncryptedDEKSourceType int32
+
+const (
+	// AESGCMKey means that the plaintext of the encrypted DEK source is the DEK itself, with AES-GCM as the encryption algorithm.
+	AESGCMKey EncryptedDEKSourceType = 0
+	// HKDFSHA256XNonceAESGCMSeed means that the plaintext of the encrypted DEK source is the seed from which a DEK is
+	// derived per value using HKDF with SHA-256, with AES-GCM and an extended nonce as the encryption algorithm.
+	HKDFSHA256XNonceAESGCMSeed EncryptedDEKSourceType = 1
+)
+
+func (t EncryptedDEKSourceType) String() string {
+	switch t {
+	case AESGCMKey:
+		return "AES_GCM_KEY"
+	case HKDFSHA256XNonceAESGCMSeed:
+		return "HKDF_SHA256_XNONCE_AES_GCM_SEED"
+	default:
+		return fmt.Sprintf("unrecognized enum value %d", int32(t))
+	}
+}
+
+// EncryptedObject is the envelope kube-apiserver stores for values encrypted by a KMS v2 provider.
+type EncryptedObject struct {
+	// EncryptedData is the encrypted data.
+	EncryptedData []byte
+	// KeyID is the KMS key ID used for encryption operations.
+	KeyID string
+	// EncryptedDEKSource is the ciphertext of the source of the DEK used to encrypt the data.
+	EncryptedDEKSource []byte
+	// Annotations is additional metadata that was provided by the KMS plugin.
+	Annotations map[string][]byte
+	// EncryptedDEKSourceType defines the process of using the plaintext of the encrypted DEK source to determine the DEK.
+	EncryptedDEKSourceType EncryptedDEKSourceType
+}
+
+// KMSConfiguration contains the name, cache size and plugin endpoint of a KMS based envelope transformer.
+type KMSConfiguration struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Name       string `json:"name"`
+	CacheSize  *int32 `json:"cachesize,omitempty"`
+	Endpoint   string `json:"endpoint"`
+	Timeout    string `json:"timeout,omitempty"`
+}
+
+// DecodeEncryptedObject decodes the EncryptedObject protobuf stored after the KMS v2 prefix.
+func DecodeEncryptedObject(in []byte) (*EncryptedObject, error) {
+	obj := &EncryptedObject{}
+	for len(in) > 0 {
+		num, typ, n := protowire.ConsumeTag(in)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid EncryptedObject: %w", protowire.ParseError(n))
+		}
+		in = in[n:]
+		switch {
+		case num == 5 && typ == protowire.VarintType:
+			var x uint64
+			x, n = protowire.ConsumeVarint(in)
+			obj.EncryptedDEKSourceType = EncryptedDEKSourceType(x)
+		case num >= 1 && num <= 4 && typ == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(in)
+			switch num {
+			case 1:
+				obj.EncryptedData = v
+			case 2:
+				obj.KeyID = string(v)
+			case 3:
+				obj.EncryptedDEKSource = v
+			case 4:
+				key, value, err := decodeAnnotation(v)
+				if err != nil {
+					return nil, err
+				}
+				if obj.Annotations == nil {
+					obj.Annotations = map[string][]byte{}
+				}
+				obj.Annotations[key] = value
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, in)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("invalid EncryptedObject field %d: %w", num, protowire.ParseError(n))
+		}
+		in = in[n:]
+	}
+	return obj, nil
+}
+
+// EncodeEncryptedObject encodes the EncryptedObject protobuf stored after the KMS v2 prefix.
+func EncodeEncryptedObject(obj *EncryptedObject) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, obj.EncryptedData)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, obj.KeyID)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, obj.EncryptedDEKSource)
+	keys := make([]string, 0, len(obj.Annotations))
+	for k := range obj.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, obj.Annotations[k])
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	if obj.EncryptedDEKSourceType != AESGCMKey {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(obj.EncryptedDEKSourceType))
+	}
+	return b
+}
+
+// decodeAnnotation decodes a single map<string, bytes> entry.
+func decodeAnnotation(in []byte) (string, []byte, error) {
+	var key string
+	var value []byte
+	for len(in) > 0 {
+		num, typ, n := protowire.ConsumeTag(in)
+		if n < 0 {
+			return "", nil, fmt.Errorf("invalid EncryptedObject annotation: %w", protowire.ParseError(n))
+		}
+		in = in[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, in)
+		} else {
+			var v []byte
+			v, n = protowire.ConsumeBytes(in)
+			switch num {
+			case 1:
+				key = string(v)
+			case 2:
+				value = v
+			}
+		}
+		if n < 0 {
+			return "", nil, fmt.Errorf("invalid EncryptedObject annotation: %w", protowire.ParseError(n))
+		}
+		in = in[n:]
+	}
+	return key, value, nil
+}
+
+func (o *EncryptedObject) validate() error {
+	if len(o.EncryptedData) == 0 {
+		return errors.New("encrypted data is empty")
+	}
+	if len(o.KeyID) == 0 {
+		return errors.New("keyID is empty")
+	}
+	if len(o.EncryptedDEKSource) == 0 {
+		return errors.New("encrypted DEK source is empty")
+	}
+	switch o.EncryptedDEKSourceType {
+	case AESGCMKey, HKDFSHA256XNonceAESGCMSeed:
+		return nil
+	default:
+		return fmt.Errorf("unsupported encrypted DEK source type %s", o.EncryptedDEKSourceType)
+	}
+}
+
+func newKMSPrefixTransformer(config *KMSConfiguration) (*prefixTransformer, error) {
+	if config.Name == "" {
+		return nil, errors.New("kms provider name is empty")
+	}
+	if strings.Contains(config.Name, ":") {
+		return nil, fmt.Errorf("kms provider name %q must not contain ':'", config.Name)
+	}
+	timeout := defaultKMSTimeout
+	if config.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for kms provider %s: %w", config.Name, err)
+		}
+	}
+	plugin := &kmsPlugin{endpoint: config.Endpoint, timeout: timeout}
+
+	apiVersion := config.APIVersion
+	if apiVersion == "" {
+		apiVersion = KMSAPIVersionV1
+	}
+	var t transformer
+	switch apiVersion {
+	case KMSAPIVersionV1:
+		t = &kmsv1Transformer{plugin: plugin, cache: map[string]transformer{}}
+	case KMSAPIVersionV2:
+		t = &kmsv2Transformer{plugin: plugin, cache: map[string]transformer{}}
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion %q for kms provider %s, expected one of %s, %s", apiVersion, config.Name, KMSAPIVersionV1, KMSAPIVersionV2)
+	}
+	return &prefixTransformer{
+		provider:    KMSProvider,
+		keyName:     config.Name,
+		prefix:      []byte(fmt.Sprintf("%s%s:%s:%s:", EncryptedPrefix, KMSProvider, apiVersion, config.Name)),
+		transformer: t,
+	}, nil
+}
+
+// kmsPlugin lazily connects to a KMS plugin listening on a unix domain socket.
+type kmsPlugin struct {
+	endpoint string
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+}
+
+func (p *kmsPlugin) connection() (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		return p.conn, nil
+	}
+	if !strings.HasPrefix(p.endpoint, "unix://") {
+		return nil, fmt.Errorf("unsupported kms plugin endpoint %q, only unix domain sockets are supported", p.endpoint)
+	}
+	conn, err := grpc.NewClient(p.endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to kms plugin at %s: %w", p.endpoint, err)
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+func (p *kmsPlugin) decryptV1(ciphertext []byte) ([]byte, error) {
+	conn, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	resp, err := kmsapiv1beta1.NewKeyManagementServiceClient(conn).Decrypt(ctx, &kmsapiv1beta1.DecryptRequest{
+		Version: kmsv1PluginAPIVersion,
+		Cipher:  ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms plugin at %s failed to decrypt DEK: %w", p.endpoint, err)
+	}
+	return resp.Plain, nil
+}
+
+func (p *kmsPlugin) encryptV1(plaintext []byte) ([]byte, error) {
+	conn, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	resp, err := kmsapiv1beta1.NewKeyManagementServiceClient(conn).Encrypt(ctx, &kmsapiv1beta1.EncryptRequest{
+		Version: kmsv1PluginAPIVersion,
+		Plain:   plaintext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms plugin at %s failed to encrypt DEK: %w", p.endpoint, err)
+	}
+	return resp.Cipher, nil
+}
+
+func (p *kmsPlugin) encryptV2(plaintext []byte) (*kmsapiv2.EncryptResponse, error) {
+	conn, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	resp, err := kmsapiv2.NewKeyManagementServiceClient(conn).Encrypt(ctx, &kmsapiv2.EncryptRequest{
+		Plaintext: plaintext,
+		Uid:       uuid.NewString(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms plugin at %s failed to encrypt DEK: %w", p.endpoint, err)
+	}
+	return resp, nil
+}
+
+func (p *kmsPlugin) decryptV2(obj *EncryptedObject) ([]byte, error) {
+	conn, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	resp, err := kmsapiv2.NewKeyManagementServiceClient(conn).Decrypt(ctx, &kmsapiv2.DecryptRequest{
+		Ciphertext:  obj.EncryptedDEKSource,
+		Uid:         uuid.NewString(),
+		KeyId:       obj.KeyID,
+		Annotations: obj.Annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms plugin at %s failed to decrypt DEK source for key ID %s: %w", p.endpoint, obj.KeyID, err)
+	}
+	return resp.Plaintext, nil
+}
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/envelope/envelope.go
+type kmsv1Transformer struct {
+	plugin *kmsPlugin
+
+	mu sync.Mutex
+	// cache maps encrypted DEKs to their transformers, values encrypted with the same DEK
+	// only require a single call to the KMS plugin.
+	cache map[string]transformer
+}
+
+func (t *kmsv1Transformer) transformFromStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	// The length of the encrypted DEK is stored in the first 2 bytes.
+	if len(data) < 2 {
+		return nil, errors.New("the stored data was shorter than the required size")
+	}
+	keyLen := int(binary.BigEndian.Uint16(data[:2]))
+	if keyLen+2 > len(data) {
+		return nil, fmt.Errorf("invalid data encountered by kms v1 transformer, length of encrypted DEK %d exceeds the data length", keyLen)
+	}
+	encKey := data[2 : keyLen+2]
+	encData := data[2+keyLen:]
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dataTransformer, ok := t.cache[string(encKey)]
+	if !ok {
+		key, err := t.plugin.decryptV1(encKey)
+		if err != nil {
+			return nil, err
+		}
+		dataTransformer, err = newCBCTransformer(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEK returned by kms plugin: %w", err)
+		}
+		t.cache[string(encKey)] = dataTransformer
+	}
+	return dataTransformer.transformFromStorage(encData, authenticatedData)
+}
+
+func (t *kmsv1Transformer) transformToStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	key, err := generateKey(32)
+	if err != nil {
+		return nil, err
+	}
+	encKey, err := t.plugin.encryptV1(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encKey) > 1<<16-1 {
+		return nil, fmt.Errorf("encrypted DEK of length %d returned by kms plugin is too long", len(encKey))
+	}
+	dataTransformer, err := newCBCTransformer(key)
+	if err != nil {
+		return nil, err
+	}
+	encData, err := dataTransformer.transformToStorage(data, authenticatedData)
+	if err != nil {
+		return nil, err
+	}
+	out := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(encKey)+len(encData)), uint16(len(encKey)))
+	out = append(out, encKey...)
+	return append(out, encData...), nil
+}
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/envelope/kmsv2/envelope.go
+type kmsv2Transformer struct {
+	plugin *kmsPlugin
+
+	mu sync.Mutex
+	// cache maps encrypted DEK sources to their transformers.
+	cache map[string]transformer
+}
+
+func (t *kmsv2Transformer) transformFromStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	obj, err := DecodeEncryptedObject(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := obj.validate(); err != nil {
+		return nil, fmt.Errorf("invalid EncryptedObject: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cacheKey := fmt.Sprintf("%d:%s", obj.EncryptedDEKSourceType, obj.EncryptedDEKSource)
+	dataTransformer, ok := t.cache[cacheKey]
+	if !ok {
+		source, err := t.plugin.decryptV2(obj)
+		if err != nil {
+			return nil, err
+		}
+		switch obj.EncryptedDEKSourceType {
+		case HKDFSHA256XNonceAESGCMSeed:
+			dataTransformer, err = newHKDFExtendedNonceGCMTransformer(source)
+		default:
+			dataTransformer, err = newGCMTransformer(source)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEK source returned by kms plugin: %w", err)
+		}
+		t.cache[cacheKey] = dataTransformer
+	}
+	return dataTransformer.transformFromStorage(obj.EncryptedData, authenticatedData)
+}
+
+// transformToStorage encrypts the data with a new DEK that is wrapped by the KMS plugin. The DEK
+// is used directly, as AES_GCM_KEY, which kube-apiserver supports reading in all KMS v2 versions.
+func (t *kmsv2Transformer) transformToStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	key, err := generateKey(32)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.plugin.encryptV2(key)
+	if err != nil {
+		return nil, err
+	}
+	dataTransformer, err := newGCMTransformer(key)
+	if err != nil {
+		return nil, err
+	}
+	encData, err := dataTransformer.transformToStorage(data, authenticatedData)
+	if err != nil {
+		return nil, err
+	}
+	obj := &EncryptedObject{
+		EncryptedData:          encData,
+		KeyID:                  resp.KeyId,
+		EncryptedDEKSource:     resp.Ciphertext,
+		Annotations:            resp.Annotations,
+		EncryptedDEKSourceType: AESGCMKey,
+	}
+	if err := obj.validate(); err != nil {
+		return nil, fmt.Errorf("invalid response from kms plugin: %w", err)
+	}
+	return EncodeEncryptedObject(obj), nil
+}
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/aes/aes_extended_nonce.go
+const (
+	hkdfInfoSize       = 32
+	hkdfDerivedKeySize = 32
+	hkdfMinSeedSize    = 32
+)
+
+type hkdfExtendedNonceGCMTransformer struct {
+	seed []byte
+}
+
+func newHKDFExtendedNonceGCMTransformer(seed []byte) (transformer, error) {
+	if len(seed) < hkdfMinSeedSize {
+		return nil, fmt.Errorf("invalid seed length %d, must be at least %d", len(seed), hkdfMinSeedSize)
+	}
+	return &hkdfExtendedNonceGCMTransformer{seed: seed}, nil
+}
+
+func (t *hkdfExtendedNonceGCMTransformer) transformFromStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	if len(data) < hkdfInfoSize {
+		return nil, errors.New("the stored data was shorter than the required size")
+	}
+	info := data[:hkdfInfoSize]
+	key, err := hkdf.Expand(sha256.New, t.seed, string(info), hkdfDerivedKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive read key from KDF: %w", err)
+	}
+	gcm, err := newGCMTransformer(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.transformFromStorage(data[hkdfInfoSize:], authenticatedData)
+}
+
+func (t *hkdfExtendedNonceGCMTransformer) transformToStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	info, err := generateKey(hkdfInfoSize)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hkdf.Expand(sha256.New, t.seed, string(info), hkdfDerivedKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive write key from KDF: %w", err)
+	}
+	gcm, err := newGCMTransformer(key)
+	if err != nil {
+		return nil, err
+	}
+	out, err := gcm.transformToStorage(data, authenticatedData)
+	if err != nil {
+		return nil, err
+	}
+	return append(info, out...), nil
+}
+
+func generateKey(length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kmsapiv1beta1 "k8s.io/kms/apis/v1beta1"
+	kmsapiv2 "k8s.io/kms/apis/v2"
+)
+
+const fakeKeyID = "fake-key-id"
+
+// fakeKMSv2Plugin "encrypts" DEKs by reversing their bytes.
+type fakeKMSv2Plugin struct {
+	kmsapiv2.UnimplementedKeyManagementServiceServer
+	decryptCalls atomic.Int32
+}
+
+func (p *fakeKMSv2Plugin) Encrypt(_ context.Context, req *kmsapiv2.EncryptRequest) (*kmsapiv2.EncryptResponse, error) {
+	return &kmsapiv2.EncryptResponse{
+		Ciphertext:  reverse(req.Plaintext),
+		KeyId:       fakeKeyID,
+		Annotations: map[string][]byte{"kms.example.com/region": []byte("local")},
+	}, nil
+}
+
+func (p *fakeKMSv2Plugin) Decrypt(_ context.Context, req *kmsapiv2.DecryptRequest) (*kmsapiv2.DecryptResponse, error) {
+	p.decryptCalls.Add(1)
+	if req.KeyId != fakeKeyID {
+		return nil, fmt.Errorf("unknown key ID %q", req.KeyId)
+	}
+	if string(req.Annotations["kms.example.com/region"]) != "local" {
+		return nil, fmt.Errorf("missing annotations, got %v", req.Annotations)
+	}
+	return &kmsapiv2.DecryptResponse{Plaintext: reverse(req.Ciphertext)}, nil
+}
+
+// fakeKMSv1Plugin "encrypts" DEKs by reversing their bytes.
+type fakeKMSv1Plugin struct {
+	kmsapiv1beta1.UnimplementedKeyManagementServiceServer
+}
+
+func (p *fakeKMSv1Plugin) Encrypt(_ context.Context, req *kmsapiv1beta1.EncryptRequest) (*kmsapiv1beta1.EncryptResponse, error) {
+	return &kmsapiv1beta1.EncryptResponse{Cipher: reverse(req.Plain)}, nil
+}
+
+func (p *fakeKMSv1Plugin) Decrypt(_ context.Context, req *kmsapiv1beta1.DecryptRequest) (*kmsapiv1beta1.DecryptResponse, error) {
+	return &kmsapiv1beta1.DecryptResponse{Plain: reverse(req.Cipher)}, nil
+}
+
+func TestDecryptKMS(t *testing.T) {
+	dir := t.TempDir()
+	v2Plugin := &fakeKMSv2Plugin{}
+	v2Endpoint := startFakeKMSPlugin(t, filepath.Join(dir, "kmsv2.sock"), func(s *grpc.Server) {
+		kmsapiv2.RegisterKeyManagementServiceServer(s, v2Plugin)
+	})
+	v1Endpoint := startFakeKMSPlugin(t, filepath.Join(dir, "kmsv1.sock"), func(s *grpc.Server) {
+		kmsapiv1beta1.RegisterKeyManagementServiceServer(s, &fakeKMSv1Plugin{})
+	})
+	config, err := ParseConfig([]byte(`
+apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+  - resources:
+    - secrets
+    providers:
+    - kms:
+        apiVersion: v2
+        name: fake-v2
+        endpoint: ` + v2Endpoint + `
+    - kms:
+        name: fake-v1
+        endpoint: ` + v1Endpoint + `
+        cachesize: 10
+        timeout: 1s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dek := bytes.Repeat([]byte{4}, 32)
+	cases := []struct {
+		name  string
+		value []byte
+	}{
+		{
+			name: "kmsv2-aes-gcm-key",
+			value: append([]byte("k8s:enc:kms:v2:fake-v2:"), EncodeEncryptedObject(&EncryptedObject{
+				EncryptedData:          encryptGCMWithKey(t, dek, testPlaintext, []byte(testKey)),
+				KeyID:                  fakeKeyID,
+				EncryptedDEKSource:     reverse(dek),
+				Annotations:            map[string][]byte{"kms.example.com/region": []byte("local")},
+				EncryptedDEKSourceType: AESGCMKey,
+			})...),
+		},
+		{
+			name: "kmsv2-hkdf-seed",
+			value: append([]byte("k8s:enc:kms:v2:fake-v2:"), EncodeEncryptedObject(&EncryptedObject{
+				EncryptedData:          encryptHKDF(t, dek, testPlaintext, []byte(testKey)),
+				KeyID:                  fakeKeyID,
+				EncryptedDEKSource:     reverse(dek),
+				Annotations:            map[string][]byte{"kms.example.com/region": []byte("local")},
+				EncryptedDEKSourceType: HKDFSHA256XNonceAESGCMSeed,
+			})...),
+		},
+		{
+			name:  "kmsv1",
+			value: append([]byte("k8s:enc:kms:v1:fake-v1:"), encodeTestKMSv1Value(t, dek, testPlaintext)...),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := config.Decrypt(testKey, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, testPlaintext) {
+				t.Errorf("got %q, want %q", out, testPlaintext)
+			}
+		})
+	}
+
+	// Decrypting again must be served from the DEK cache.
+	calls := v2Plugin.decryptCalls.Load()
+	if _, err := config.Decrypt(testKey, cases[0].value); err != nil {
+		t.Fatal(err)
+	}
+	if got := v2Plugin.decryptCalls.Load(); got != calls {
+		t.Errorf("got %d kms plugin calls, expected the DEK to be cached after %d calls", got, calls)
+	}
+}
+
+func TestEncryptKMS(t *testing.T) {
+	dir := t.TempDir()
+	v2Endpoint := startFakeKMSPlugin(t, filepath.Join(dir, "kmsv2.sock"), func(s *grpc.Server) {
+		kmsapiv2.RegisterKeyManagementServiceServer(s, &fakeKMSv2Plugin{})
+	})
+	v1Endpoint := startFakeKMSPlugin(t, filepath.Join(dir, "kmsv1.sock"), func(s *grpc.Server) {
+		kmsapiv1beta1.RegisterKeyManagementServiceServer(s, &fakeKMSv1Plugin{})
+	})
+	cases := []struct {
+		name     string
+		provider string
+		prefix   string
+	}{
+		{name: "kmsv2", provider: "{apiVersion: v2, name: fake-v2, endpoint: \"" + v2Endpoint + "\"}", prefix: "k8s:enc:kms:v2:fake-v2:"},
+		{name: "kmsv1", provider: "{name: fake-v1, endpoint: \"" + v1Endpoint + "\"}", prefix: "k8s:enc:kms:v1:fake-v1:"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(`
+apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+  - resources: [secrets]
+    providers:
+    - kms: ` + tt.provider + `
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := config.Encrypt(schema.GroupResource{Resource: "secrets"}, testKey, testPlaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(value, []byte(tt.prefix)) {
+				t.Fatalf("got value %q, expected prefix %q", value, tt.prefix)
+			}
+			out, err := config.Decrypt(testKey, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, testPlaintext) {
+				t.Errorf("got %q, want %q", out, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestDecodeSummaryKMSv2(t *testing.T) {
+	value := append([]byte("k8s:enc:kms:v2:fake-v2:"), EncodeEncryptedObject(&EncryptedObject{
+		EncryptedData:          []byte("data"),
+		KeyID:                  fakeKeyID,
+		EncryptedDEKSource:     []byte("dek"),
+		Annotations:            map[string][]byte{"b.example.com": []byte("2"), "a.example.com": []byte("1")},
+		EncryptedDEKSourceType: HKDFSHA256XNonceAESGCMSeed,
+	})...)
+	out := new(bytes.Buffer)
+	if err := DecodeSummary(value, out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Encryption.Provider: kms
+Encryption.Version: v2
+Encryption.Name: fake-v2
+Encryption.KeyID: fake-key-id
+Encryption.EncryptedDEKSourceType: HKDF_SHA256_XNONCE_AES_GCM_SEED
+Encryption.Annotations.a.example.com: "1"
+Encryption.Annotations.b.example.com: "2"
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestDecryptKMSUnsupportedEndpoint(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+  - resources: [secrets]
+    providers:
+    - kms: {apiVersion: v2, name: remote, endpoint: "tcp://127.0.0.1:1234"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := append([]byte("k8s:enc:kms:v2:remote:"), EncodeEncryptedObject(&EncryptedObject{
+		EncryptedData:      []byte("data"),
+		KeyID:              fakeKeyID,
+		EncryptedDEKSource: []byte("dek"),
+	})...)
+	_, err = config.Decrypt(testKey, value)
+	if err == nil || !strings.Contains(err.Error(), "only unix domain sockets are supported") {
+		t.Errorf("expected unsupported endpoint error, got %v", err)
+	}
+}
+
+func startFakeKMSPlugin(t *testing.T, socket string, register func(s *grpc.Server)) string {
+	t.Helper()
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", socket, err)
+	}
+	server := grpc.NewServer()
+	register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return "unix://" + socket
+}
+
+func encodeTestKMSv1Value(t *testing.T, dek, plaintext []byte) []byte {
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paddingSize := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(paddingSize)}, paddingSize)...)
+	iv := bytes.Repeat([]byte{5}, aes.BlockSize)
+	data := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, padded)
+
+	encDEK := reverse(dek)
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(encDEK)))
+	out = append(out, encDEK...)
+	out = append(out, iv...)
+	return append(out, data...)
+}
+
+func encryptGCMWithKey(t *testing.T, key, plaintext, authenticatedData []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{6}, aead.NonceSize())
+	return aead.Seal(nonce, nonce, plaintext, authenticatedData)
+}
+
+func encryptHKDF(t *testing.T, seed, plaintext, authenticatedData []byte) []byte {
+	info := bytes.Repeat([]byte{8}, hkdfInfoSize)
+	key, err := hkdf.Expand(sha256.New, seed, string(info), hkdfDerivedKeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(info, encryptGCMWithKey(t, key, plaintext, authenticatedData)...)
+}
+
+func reverse(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[len(in)-1-i] = b
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/aes/aes.go
+type gcmTransformer struct {
+	aead cipher.AEAD
+}
+
+func newGCMTransformer(secret []byte) (transformer, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &gcmTransformer{aead: aead}, nil
+}
+
+func (t *gcmTransformer) transformFromStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	nonceSize := t.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("the stored data was shorter than the required size")
+	}
+	return t.aead.Open(nil, data[:nonceSize], data[nonceSize:], authenticatedData)
+}
+
+func (t *gcmTransformer) transformToStorage(data []byte, authenticatedData []byte) ([]byte, error) {
+	nonceSize := t.aead.NonceSize()
+	result := make([]byte, nonceSize, nonceSize+len(data)+t.aead.Overhead())
+	if _, err := rand.Read(result); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes: %w", err)
+	}
+	return t.aead.Seal(result, result, data, authenticatedData), nil
+}
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/aes/aes_cbc.go
+type cbcTransformer struct {
+	block cipher.Block
+}
+
+func newCBCTransformer(secret []byte) (transformer, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &cbcTransformer{block: block}, nil
+}
+
+func (t *cbcTransformer) transformFromStorage(data []byte, _ []byte) ([]byte, error) {
+	blockSize := aes.BlockSize
+	if len(data) < blockSize {
+		return nil, errors.New("the stored data was shorter than the required size")
+	}
+	iv := data[:blockSize]
+	data = data[blockSize:]
+
+	if len(data) == 0 {
+		return nil, errors.New("the stored data has no ciphertext after the IV")
+	}
+	if len(data)%blockSize != 0 {
+		return nil, errors.New("the stored data is not a multiple of the block size")
+	}
+
+	result := make([]byte, len(data))
+	copy(result, data)
+	mode := cipher.NewCBCDecrypter(t.block, iv)
+	mode.CryptBlocks(result, result)
+
+	// remove and verify PKCS#7 padding for CBC
+	c := result[len(result)-1]
+	paddingSize := int(c)
+	size := len(result) - paddingSize
+	if paddingSize == 0 || paddingSize > len(result) {
+		return nil, errors.New("invalid PKCS7 data (empty or not padded)")
+	}
+	for i := 0; i < paddingSize; i++ {
+		if result[size+i] != c {
+			return nil, errors.New("invalid padding on input")
+		}
+	}
+	return result[:size], nil
+}
+
+func (t *cbcTransformer) transformToStorage(data []byte, _ []byte) ([]byte, error) {
+	blockSize := aes.BlockSize
+	paddingSize := blockSize - (len(data) % blockSize)
+	result := make([]byte, blockSize+len(data)+paddingSize)
+	iv := result[:blockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes: %w", err)
+	}
+	copy(result[blockSize:], data)
+
+	// add PKCS#7 padding for CBC
+	copy(result[blockSize+len(data):], bytes.Repeat([]byte{byte(paddingSize)}, paddingSize))
+
+	mode := cipher.NewCBCEncrypter(t.block, iv)
+	mode.CryptBlocks(result[blockSize:], result[blockSize:])
+	return result, nil
+}
+
+// See k8s.io/apiserver/pkg/storage/value/encrypt/secretbox/secretbox.go
+const secretboxNonceSize = 24
+
+type secretboxTransformer struct {
+	key [32]byte
+}
+
+func newSecretboxTransformer(secret []byte) (transformer, error) {
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("secretbox key must be 32 bytes, got %d", len(secret))
+	}
+	t := &secretboxTransformer{}
+	copy(t.key[:], secret)
+	return t, nil
+}
+
+func (t *secretboxTransformer) transformFromStorage(data []byte, _ []byte) ([]byte, error) {
+	if len(data) < (secretbox.Overhead + secretboxNonceSize) {
+		return nil, errors.New("the stored data was shorter than the required size")
+	}
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], data[:secretboxNonceSize])
+	data = data[secretboxNonceSize:]
+	out := make([]byte, 0, len(data)-secretbox.Overhead)
+	result, ok := secretbox.Open(out, data, &nonce, &t.key)
+	if !ok {
+		return nil, errors.New("unable to open secretbox, message authentication failed")
+	}
+	return result, nil
+}
+
+func (t *secretboxTransformer) transformToStorage(data []byte, _ []byte) ([]byte, error) {
+	var nonce [secretboxNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("unable to read sufficient random bytes: %w", err)
+	}
+	out := make([]byte, secretboxNonceSize, secretboxNonceSize+secretbox.Overhead+len(data))
+	copy(out, nonce[:])
+	return secretbox.Seal(out, data, &nonce, &t.key), nil
+}
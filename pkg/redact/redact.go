@@ -0,0 +1,247 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact masks sensitive values of kubernetes objects, decoded from JSON, so that they can be
+// shared, e.g. in support bundles. Masked values keep their key names and sizes.
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"sigs.k8s.io/yaml"
+)
+
+// lastAppliedConfigAnnotation holds a copy of the object as last applied by kubectl, data included.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Redactor masks the data of Secrets, including service account and bootstrap tokens, kubeconfigs held by
+// ConfigMaps, and the values of the fields at the paths it is configured with.
+//
+// A nil Redactor masks nothing, so it may be used whether or not redaction is enabled.
+type Redactor struct {
+	paths [][]string
+}
+
+// New returns a Redactor that also masks the values at the given paths of all objects. Paths are JSON
+// field names separated by dots, e.g. spec.template.spec.containers.*.env.*.value, where * matches all
+// fields of an object or items of a list.
+func New(paths []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, path := range paths {
+		fields := strings.Split(strings.TrimPrefix(path, "."), ".")
+		for _, field := range fields {
+			if field == "" {
+				return nil, fmt.Errorf("invalid redaction path %q: empty field name", path)
+			}
+		}
+		r.paths = append(r.paths, fields)
+	}
+	return r, nil
+}
+
+// Object masks the sensitive values of an object decoded from JSON, e.g. unstructured.Unstructured
+// contents, in place. It returns whether any value was masked.
+func (r *Redactor) Object(obj map[string]interface{}) bool {
+	if r == nil {
+		return false
+	}
+	redacted := false
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	switch {
+	case apiVersion == "v1" && kind == "Secret":
+		redacted = redactSecret(obj)
+	case apiVersion == "v1" && kind == "ConfigMap":
+		redacted = redactConfigMap(obj)
+	}
+	for _, path := range r.paths {
+		if redactPath(obj, path) {
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+// Redact masks the sensitive values of an object encoded as JSON or YAML. Objects that have no sensitive
+// values are returned as is, others are encoded again, with the fields of JSON objects sorted by name.
+func (r *Redactor) Redact(mediaType string, in []byte) ([]byte, error) {
+	if r == nil {
+		return in, nil
+	}
+	js := in
+	switch mediaType {
+	case encoding.JsonMediaType:
+	case encoding.YamlMediaType:
+		var err error
+		if js, err = yaml.YAMLToJSON(in); err != nil {
+			return nil, fmt.Errorf("error decoding object to redact: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("redaction is not supported for %s output", mediaType)
+	}
+	// Numbers are kept as is, rather than rounded to float64.
+	obj := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("error decoding object to redact: %w", err)
+	}
+	if !r.Object(obj) {
+		return in, nil
+	}
+
+	if mediaType == encoding.YamlMediaType {
+		return yaml.Marshal(obj)
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(in, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// Supported returns whether objects of the media type can be redacted.
+func Supported(mediaType string) bool {
+	return mediaType == encoding.JsonMediaType || mediaType == encoding.YamlMediaType
+}
+
+// redactSecret masks the data and stringData values of a Secret, and the copy of them kubectl keeps in
+// its last applied configuration.
+func redactSecret(obj map[string]interface{}) bool {
+	redacted := false
+	if data, ok := obj["data"].(map[string]interface{}); ok {
+		for key, value := range data {
+			s, _ := value.(string)
+			if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+				data[key] = mask(len(decoded))
+			} else {
+				data[key] = maskValue(value)
+			}
+			redacted = true
+		}
+	}
+	if stringData, ok := obj["stringData"].(map[string]interface{}); ok {
+		for key, value := range stringData {
+			stringData[key] = maskValue(value)
+			redacted = true
+		}
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if value, ok := annotations[lastAppliedConfigAnnotation]; ok {
+				annotations[lastAppliedConfigAnnotation] = maskValue(value)
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// redactConfigMap masks the data values of a ConfigMap that hold a kubeconfig, which may carry client
+// certificates, keys and tokens.
+func redactConfigMap(obj map[string]interface{}) bool {
+	redacted := false
+	data, ok := obj["data"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, value := range data {
+		s, ok := value.(string)
+		if ok && isKubeconfig(key, s) {
+			data[key] = mask(len(s))
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+// isKubeconfig returns whether a ConfigMap value is a kubeconfig, by its key or by its kind.
+func isKubeconfig(key, value string) bool {
+	if strings.Contains(strings.ToLower(key), "kubeconfig") {
+		return true
+	}
+	var typeMeta struct {
+		Kind string `json:"kind"`
+	}
+	return yaml.Unmarshal([]byte(value), &typeMeta) == nil && typeMeta.Kind == "Config"
+}
+
+// redactPath masks the values at the path of obj, which may be an object or a list.
+func redactPath(obj interface{}, path []string) bool {
+	field, rest := path[0], path[1:]
+	redacted := false
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range obj {
+			if field != "*" && field != key {
+				continue
+			}
+			if len(rest) == 0 {
+				obj[key] = maskValue(value)
+				redacted = true
+			} else if redactPath(value, rest) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for i, value := range obj {
+			if field != "*" && field != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				obj[i] = maskValue(value)
+				redacted = true
+			} else if redactPath(value, rest) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// maskValue masks a value with its size, the length of strings or of the JSON encoding of other values.
+// Values that are already masked are kept, so that their size is the size of the original value.
+func maskValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		if strings.HasPrefix(s, maskPrefix) && strings.HasSuffix(s, maskSuffix) {
+			return s
+		}
+		return mask(len(s))
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return mask(0)
+	}
+	return mask(len(buf))
+}
+
+const (
+	maskPrefix = "[redacted: "
+	maskSuffix = " bytes]"
+)
+
+func mask(size int) string {
+	return maskPrefix + strconv.Itoa(size) + maskSuffix
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+)
+
+func TestObject(t *testing.T) {
+	cases := []struct {
+		name  string
+		paths []string
+		in    string
+		want  string
+	}{
+		{
+			name: "secret",
+			in:   `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{}}","team":"a"}},"type":"Opaque","data":{"password":"aHVudGVyMg=="},"stringData":{"user":"admin"}}`,
+			want: `{"apiVersion":"v1","data":{"password":"[redacted: 7 bytes]"},"kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"[redacted: 11 bytes]","team":"a"},"name":"s"},"stringData":{"user":"[redacted: 5 bytes]"},"type":"Opaque"}`,
+		},
+		{
+			name: "service account token",
+			in:   `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"default-token"},"type":"kubernetes.io/service-account-token","data":{"token":"ZXlKaGJH","namespace":"ZGVmYXVsdA=="}}`,
+			want: `{"apiVersion":"v1","data":{"namespace":"[redacted: 7 bytes]","token":"[redacted: 6 bytes]"},"kind":"Secret","metadata":{"name":"default-token"},"type":"kubernetes.io/service-account-token"}`,
+		},
+		{
+			name: "bootstrap token",
+			in:   `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"bootstrap-token-abcdef","namespace":"kube-system"},"type":"bootstrap.kubernetes.io/token","data":{"token-id":"YWJjZGVm","token-secret":"MDEyMzQ1Njc4OWFiY2RlZg=="}}`,
+			want: `{"apiVersion":"v1","data":{"token-id":"[redacted: 6 bytes]","token-secret":"[redacted: 16 bytes]"},"kind":"Secret","metadata":{"name":"bootstrap-token-abcdef","namespace":"kube-system"},"type":"bootstrap.kubernetes.io/token"}`,
+		},
+		{
+			name: "kubeconfig configmap",
+			in:   `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"c"},"data":{"admin.conf":"apiVersion: v1\nkind: Config\nusers: []\n","kubeconfig":"x","color":"blue"}}`,
+			want: `{"apiVersion":"v1","data":{"admin.conf":"[redacted: 38 bytes]","color":"blue","kubeconfig":"[redacted: 1 bytes]"},"kind":"ConfigMap","metadata":{"name":"c"}}`,
+		},
+		{
+			name:  "paths",
+			paths: []string{".spec.containers.*.env.*.value", "spec.token", "spec.missing.field"},
+			in:    `{"apiVersion":"v1","kind":"Pod","spec":{"token":{"a":1},"containers":[{"name":"web","env":[{"name":"PASSWORD","value":"hunter2"}]}]}}`,
+			want:  `{"apiVersion":"v1","kind":"Pod","spec":{"containers":[{"env":[{"name":"PASSWORD","value":"[redacted: 7 bytes]"}],"name":"web"}],"token":"[redacted: 7 bytes]"}}`,
+		},
+		{
+			name:  "list index",
+			paths: []string{"items.1"},
+			in:    `{"items":["a","bb","c"]}`,
+			want:  `{"items":["a","[redacted: 2 bytes]","c"]}`,
+		},
+		{
+			name:  "masked",
+			paths: []string{"data.*", "metadata.annotations.*"},
+			in:    `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}","team":"a"}},"data":{"a":"YQ==","b":"[redacted: 3 bytes]"}}`,
+			want:  `{"apiVersion":"v1","data":{"a":"[redacted: 1 bytes]","b":"[redacted: 3 bytes]"},"kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"[redacted: 2 bytes]","team":"[redacted: 1 bytes]"}}}`,
+		},
+		{
+			name: "nothing to redact",
+			in:   `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"c"},"data":{"color":"blue"}}`,
+			want: `{"apiVersion":"v1","data":{"color":"blue"},"kind":"ConfigMap","metadata":{"name":"c"}}`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(tt.in), &obj); err != nil {
+				t.Fatal(err)
+			}
+			r.Object(obj)
+			got, err := json.Marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := `{"kind":"Secret","apiVersion":"v1","metadata":{"name":"s","generation":12345678901234567},"data":{"password":"aHVudGVyMg=="}}` + "\n"
+
+	got, err := r.Redact(encoding.JsonMediaType, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"apiVersion":"v1","data":{"password":"[redacted: 7 bytes]"},"kind":"Secret","metadata":{"generation":12345678901234567,"name":"s"}}` + "\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = r.Redact(encoding.YamlMediaType, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  generation: 12345678901234567\ndata:\n  password: aHVudGVyMg==\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "apiVersion: v1\ndata:\n  password: '[redacted: 7 bytes]'\nkind: Secret\nmetadata:\n  generation: 12345678901234567\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	pod := `{"kind":"Pod","apiVersion":"v1"}` + "\n"
+	if got, err = r.Redact(encoding.JsonMediaType, []byte(pod)); err != nil || string(got) != pod {
+		t.Errorf("got %q, %v, want the object as is", got, err)
+	}
+
+	if _, err := r.Redact(encoding.CborMediaType, []byte(pod)); err == nil {
+		t.Error("expected an error for CBOR")
+	}
+
+	var none *Redactor
+	if got, err = none.Redact(encoding.JsonMediaType, []byte(secret)); err != nil || string(got) != secret {
+		t.Errorf("got %q, %v, want the object as is from a nil Redactor", got, err)
+	}
+}
+
+func TestNewInvalidPath(t *testing.T) {
+	for _, path := range []string{"", "spec..token", "spec."} {
+		if _, err := New([]string{path}); err == nil {
+			t.Errorf("expected an error for path %q", path)
+		}
+	}
+}
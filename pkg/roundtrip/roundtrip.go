@@ -0,0 +1,337 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package roundtrip verifies that objects stored in the kubernetes binary storage representation are
+// decoded and re-encoded by the scheme without losing data.
+package roundtrip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// unknownEnvelopeRawField is the field number of 'raw' in the runtime.Unknown envelope.
+const unknownEnvelopeRawField = 2
+
+var timeType = reflect.TypeOf(metav1.Time{})
+
+// Reason is why a stored field is not round-tripped.
+type Reason string
+
+const (
+	// Unknown fields are not in the type declaration and are dropped when decoding.
+	Unknown Reason = "unknown field"
+	// Dropped fields are in the type declaration, but are missing from the re-encoded object.
+	Dropped Reason = "dropped"
+	// Changed fields are in the type declaration, but have a different value in the re-encoded object.
+	Changed Reason = "changed"
+)
+
+// Finding is a stored field that does not survive the round trip.
+type Finding struct {
+	// Offset is the byte offset of the field in the stored value, including the encoding prefix.
+	Offset int
+	// Path is the path of the field, by the JSON names of the type declaration where known and by field
+	// number otherwise.
+	Path   string
+	Reason Reason
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("offset %d: %s: %s", f.Offset, f.Path, f.Reason)
+}
+
+// Result is the outcome of verifying the round trip of a stored object.
+type Result struct {
+	TypeMeta runtime.TypeMeta
+	Findings []Finding
+}
+
+// Verify decodes a value in the kubernetes binary storage representation with the given codecs,
+// re-encodes it and compares the protobuf payloads field by field. Fields only in the re-encoded object,
+// e.g. fields added since the object was stored, are not findings, and neither are the nanoseconds of
+// metav1.Time, which kube-apiserver drops when reading too. encoding.ErrUnknownKind is returned if the
+// kind of the object is not in the scheme.
+func Verify(codecs serializer.CodecFactory, value []byte) (*Result, error) {
+	stored, err := encoding.DecodeUnknown(value)
+	if err != nil {
+		return nil, err
+	}
+	obj, _, err := encoding.NewDecoder(codecs).Decode(encoding.StorageBinaryMediaType, value)
+	if err != nil {
+		return nil, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(stored.TypeMeta.GroupVersionKind())
+	encoded, err := encoding.NewEncoder(codecs).Encode(obj, encoding.StorageBinaryMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s: %w", stored.TypeMeta.GroupVersionKind(), err)
+	}
+	reencoded, err := encoding.DecodeUnknown(encoded)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := rawOffset(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{TypeMeta: stored.TypeMeta}
+	compare(stored.Raw, reencoded.Raw, reflect.TypeOf(obj), "", offset, &result.Findings)
+	return result, nil
+}
+
+// rawOffset returns the offset of the protobuf payload in a value in the binary storage representation.
+func rawOffset(value []byte) (int, error) {
+	prefix := len(encoding.ProtoEncodingPrefix)
+	fields, err := parseFields(value[prefix:], prefix)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range fields {
+		if f.num == unknownEnvelopeRawField && f.typ == protowire.BytesType {
+			return f.valueOffset, nil
+		}
+	}
+	return 0, errors.New("no raw payload in the runtime.Unknown envelope")
+}
+
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+	// value is the encoded value, length-delimited values without their length.
+	value []byte
+	// offset is the offset of the tag, valueOffset of the value.
+	offset, valueOffset int
+}
+
+// parseFields parses the fields of a protobuf message, which starts at the given offset.
+func parseFields(b []byte, offset int) ([]field, error) {
+	var fields []field
+	pos := 0
+	for pos < len(b) {
+		num, typ, n := protowire.ConsumeTag(b[pos:])
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		f := field{num: num, typ: typ, offset: offset + pos}
+		pos += n
+		n = protowire.ConsumeFieldValue(num, typ, b[pos:])
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		f.value, f.valueOffset = b[pos:pos+n], offset+pos
+		if typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(b[pos:])
+			f.value, f.valueOffset = v, offset+pos+m-len(v)
+		}
+		pos += n
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// compare appends the fields of the stored message that are not in the re-encoded message, or differ,
+// to the findings. Fields are looked up in the given go type, or only compared by number if it is nil.
+func compare(stored, reencoded []byte, t reflect.Type, path string, offset int, findings *[]Finding) {
+	storedFields, err := parseFields(stored, offset)
+	if err != nil {
+		*findings = append(*findings, Finding{Offset: offset, Path: path, Reason: Changed})
+		return
+	}
+	reencodedFields, err := parseFields(reencoded, 0)
+	if err != nil {
+		*findings = append(*findings, Finding{Offset: offset, Path: path, Reason: Changed})
+		return
+	}
+	reencodedByNum := map[protowire.Number][]field{}
+	for _, f := range reencodedFields {
+		reencodedByNum[f.num] = append(reencodedByNum[f.num], f)
+	}
+
+	occurrences := map[protowire.Number]int{}
+	for _, f := range storedFields {
+		i := occurrences[f.num]
+		occurrences[f.num]++
+		info, ok := lookupField(t, f.num)
+		if isZero(f) && !info.repeated {
+			// Older releases wrote empty fields that have since been removed, e.g. ObjectMeta.ClusterName,
+			// or are now omitted, which loses nothing.
+			continue
+		}
+		if !ok {
+			*findings = append(*findings, Finding{Offset: f.offset, Path: joinPath(path, strconv.Itoa(int(f.num))), Reason: Unknown})
+			continue
+		}
+		if info.skip {
+			continue
+		}
+		fieldPath := joinPath(path, info.name)
+		if info.repeated {
+			fieldPath = fmt.Sprintf("%s[%d]", fieldPath, i)
+		}
+		candidates := reencodedByNum[f.num]
+		if i >= len(candidates) {
+			*findings = append(*findings, Finding{Offset: f.offset, Path: fieldPath, Reason: Dropped})
+			continue
+		}
+		r := candidates[i]
+		if f.typ == r.typ && bytes.Equal(f.value, r.value) {
+			continue
+		}
+		if f.typ == protowire.BytesType && r.typ == protowire.BytesType && info.message {
+			compare(f.value, r.value, info.typ, fieldPath, f.valueOffset, findings)
+			continue
+		}
+		*findings = append(*findings, Finding{Offset: f.offset, Path: fieldPath, Reason: Changed})
+	}
+}
+
+// isZero returns whether a field has the zero value of its wire type.
+func isZero(f field) bool {
+	switch f.typ {
+	case protowire.StartGroupType:
+		return false
+	case protowire.BytesType:
+		return len(f.value) == 0
+	}
+	for _, b := range f.value {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+type fieldInfo struct {
+	name string
+	// typ is the go type of the field, or of its elements if repeated. It is nil if unknown, e.g. for
+	// types with a custom protobuf encoding.
+	typ      reflect.Type
+	repeated bool
+	// message is set if the value may be a nested message.
+	message bool
+	// skip is set for fields that are dropped on purpose.
+	skip bool
+}
+
+// structFields caches the fields of struct types by protobuf field number.
+var structFields sync.Map
+
+// lookupField returns the field with the given number of a go type, or false if the type declares
+// protobuf fields but not this one.
+func lookupField(t reflect.Type, num protowire.Number) (fieldInfo, bool) {
+	untyped := fieldInfo{name: strconv.Itoa(int(num)), message: true}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return untyped, true
+	}
+	if t == timeType {
+		// Only the seconds are decoded, as by kube-apiserver.
+		return fieldInfo{name: "seconds", skip: num != 1}, true
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		// Maps are encoded as repeated entry messages of a key and a value.
+		switch num {
+		case 1:
+			return newFieldInfo("key", t.Key()), true
+		case 2:
+			return newFieldInfo("value", t.Elem()), true
+		}
+		return fieldInfo{}, false
+	case reflect.Struct:
+		fields := structFieldsOf(t)
+		if len(fields) == 0 {
+			// Types with a custom protobuf encoding, e.g. resource.Quantity.
+			return untyped, true
+		}
+		info, ok := fields[num]
+		return info, ok
+	default:
+		return untyped, true
+	}
+}
+
+func structFieldsOf(t reflect.Type) map[protowire.Number]fieldInfo {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.(map[protowire.Number]fieldInfo)
+	}
+	fields := map[protowire.Number]fieldInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("protobuf"), ",")
+		if len(tag) < 2 {
+			continue
+		}
+		num, err := strconv.Atoi(tag[1])
+		if err != nil {
+			continue
+		}
+		name := f.Name
+		for _, opt := range tag[2:] {
+			if strings.HasPrefix(opt, "name=") {
+				name = strings.TrimPrefix(opt, "name=")
+			}
+		}
+		fields[protowire.Number(num)] = newFieldInfo(name, f.Type)
+	}
+	structFields.Store(t, fields)
+	return fields
+}
+
+func newFieldInfo(name string, t reflect.Type) fieldInfo {
+	info := fieldInfo{name: name, typ: t}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		info.repeated = true
+		info.typ = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		info.repeated = true
+	}
+	elem := info.typ
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	switch elem.Kind() {
+	case reflect.Struct, reflect.Map:
+		info.message = true
+	case reflect.Slice:
+		// Named slices, e.g. 'type ExtraValue []string', are encoded as a message of their items.
+		info.message = elem.Elem().Kind() != reflect.Uint8
+		info.typ = nil
+	}
+	return info
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package roundtrip
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/etcd-io/auger/pkg/encoding"
+	"github.com/etcd-io/auger/pkg/scheme"
+	"google.golang.org/protobuf/encoding/protowire"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func appendField(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func storageBinary(t *testing.T, typeMeta runtime.TypeMeta, raw []byte) []byte {
+	unknown := &runtime.Unknown{TypeMeta: typeMeta, Raw: raw, ContentType: "application/vnd.kubernetes.protobuf"}
+	b, err := unknown.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, encoding.ProtoEncodingPrefix...), b...)
+}
+
+func TestVerifyFixtures(t *testing.T) {
+	for _, file := range []string{"../../cmd/testdata/storage/pod.bin", "../../cmd/testdata/storage/job.bin"} {
+		in, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := Verify(scheme.Codecs, in[:len(in)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Findings) > 0 {
+			t.Errorf("%s: got findings %v", file, result.Findings)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "nginx"}}},
+	}
+	raw, err := pod.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := pod.Spec.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := pod.ObjectMeta.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeMeta := runtime.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+
+	cases := []struct {
+		name string
+		raw  []byte
+		// want are the findings with offsets relative to the payload.
+		want []Finding
+	}{
+		{
+			name: "lossless",
+			raw:  raw,
+		},
+		{
+			name: "unknown top level field",
+			raw:  protowire.AppendVarint(protowire.AppendTag(raw, 99, protowire.VarintType), 1),
+			want: []Finding{{Offset: len(raw), Path: "99", Reason: Unknown}},
+		},
+		{
+			name: "unknown nested field",
+			raw:  appendField(appendField(nil, 1, metadata), 2, appendField(spec, 99, []byte("x"))),
+			want: []Finding{{Offset: 2 + len(metadata) + 2 + len(spec), Path: "spec.99", Reason: Unknown}},
+		},
+		{
+			name: "empty unknown field",
+			raw:  appendField(raw, 99, nil),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			in := storageBinary(t, typeMeta, tt.raw)
+			result, err := Verify(scheme.Codecs, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offset := bytes.Index(in, tt.raw)
+			for i := range tt.want {
+				tt.want[i].Offset += offset
+			}
+			if result.TypeMeta != typeMeta {
+				t.Errorf("got type %v, want %v", result.TypeMeta, typeMeta)
+			}
+			if !reflect.DeepEqual(result.Findings, tt.want) {
+				t.Errorf("got findings %v, want %v", result.Findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownKind(t *testing.T) {
+	in := storageBinary(t, runtime.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"}, appendField(nil, 1, nil))
+	if _, err := Verify(scheme.Codecs, in); !errors.Is(err, encoding.ErrUnknownKind) {
+		t.Errorf("got error %v, want %v", err, encoding.ErrUnknownKind)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheme
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RESTMapper maps the kinds of objects registered in Scheme to their resources and scopes, the way
+// kube-apiserver serves them.
+var RESTMapper meta.RESTMapper
+
+// clusterScopedKinds are the kinds registered in Scheme that kube-apiserver serves and stores without a
+// namespace. The scheme doesn't hold the scope of kinds, all others are namespaced.
+var clusterScopedKinds = map[schema.GroupKind]struct{}{
+	{Group: "", Kind: "ComponentStatus"}:                                              {},
+	{Group: "", Kind: "Namespace"}:                                                    {},
+	{Group: "", Kind: "Node"}:                                                         {},
+	{Group: "", Kind: "PersistentVolume"}:                                             {},
+	{Group: "", Kind: "RangeAllocation"}:                                              {},
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingAdmissionPolicy"}:          {},
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingAdmissionPolicyBinding"}:   {},
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     {},
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        {},
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: {},
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   {},
+	{Group: "authentication.k8s.io", Kind: "SelfSubjectReview"}:                       {},
+	{Group: "authentication.k8s.io", Kind: "TokenReview"}:                             {},
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectAccessReview"}:                  {},
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectRulesReview"}:                   {},
+	{Group: "authorization.k8s.io", Kind: "SubjectAccessReview"}:                      {},
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:                 {},
+	{Group: "certificates.k8s.io", Kind: "ClusterTrustBundle"}:                        {},
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                       {},
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:       {},
+	{Group: "internal.apiserver.k8s.io", Kind: "StorageVersion"}:                      {},
+	{Group: "networking.k8s.io", Kind: "IPAddress"}:                                   {},
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                                {},
+	{Group: "networking.k8s.io", Kind: "ServiceCIDR"}:                                 {},
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                      {},
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                      {},
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                         {},
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                  {},
+	{Group: "resource.k8s.io", Kind: "DeviceClass"}:                                   {},
+	{Group: "resource.k8s.io", Kind: "DeviceTaintRule"}:                               {},
+	{Group: "resource.k8s.io", Kind: "ResourceSlice"}:                                 {},
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                               {},
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                      {},
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                        {},
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                   {},
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                               {},
+	{Group: "storage.k8s.io", Kind: "VolumeAttributesClass"}:                          {},
+	{Group: "storagemigration.k8s.io", Kind: "StorageVersionMigration"}:               {},
+}
+
+// newRESTMapper returns a RESTMapper of the kinds of objects registered in the scheme. Lists, options and
+// other kinds without object metadata are left out.
+func newRESTMapper(s *runtime.Scheme) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(s.PrioritizedVersionsAllGroups())
+	for gvk := range s.AllKnownTypes() {
+		obj, err := s.New(gvk)
+		if err != nil {
+			continue
+		}
+		if _, err := meta.Accessor(obj); err != nil || meta.IsListType(obj) {
+			continue
+		}
+		scope := meta.RESTScopeNamespace
+		if _, ok := clusterScopedKinds[gvk.GroupKind()]; ok {
+			scope = meta.RESTScopeRoot
+		}
+		mapper.Add(gvk, scope)
+	}
+	return mapper
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheme
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// namespacedKinds are the kinds registered in Scheme that kube-apiserver serves in namespaces. Together with
+// clusterScopedKinds, they make sure that the scope of each kind registered in the future is looked up.
+var namespacedKinds = map[schema.GroupKind]struct{}{
+	{Group: "", Kind: "Binding"}:                                      {},
+	{Group: "", Kind: "ConfigMap"}:                                    {},
+	{Group: "", Kind: "Endpoints"}:                                    {},
+	{Group: "", Kind: "Event"}:                                        {},
+	{Group: "", Kind: "LimitRange"}:                                   {},
+	{Group: "", Kind: "PersistentVolumeClaim"}:                        {},
+	{Group: "", Kind: "Pod"}:                                          {},
+	{Group: "", Kind: "PodStatusResult"}:                              {},
+	{Group: "", Kind: "PodTemplate"}:                                  {},
+	{Group: "", Kind: "ReplicationController"}:                        {},
+	{Group: "", Kind: "ResourceQuota"}:                                {},
+	{Group: "", Kind: "Secret"}:                                       {},
+	{Group: "", Kind: "Service"}:                                      {},
+	{Group: "", Kind: "ServiceAccount"}:                               {},
+	{Group: "apps", Kind: "ControllerRevision"}:                       {},
+	{Group: "apps", Kind: "DaemonSet"}:                                {},
+	{Group: "apps", Kind: "Deployment"}:                               {},
+	{Group: "apps", Kind: "ReplicaSet"}:                               {},
+	{Group: "apps", Kind: "Scale"}:                                    {},
+	{Group: "apps", Kind: "StatefulSet"}:                              {},
+	{Group: "authentication.k8s.io", Kind: "TokenRequest"}:            {},
+	{Group: "authorization.k8s.io", Kind: "LocalSubjectAccessReview"}: {},
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}:           {},
+	{Group: "autoscaling", Kind: "Scale"}:                             {},
+	{Group: "batch", Kind: "CronJob"}:                                 {},
+	{Group: "batch", Kind: "Job"}:                                     {},
+	{Group: "certificates.k8s.io", Kind: "PodCertificateRequest"}:     {},
+	{Group: "coordination.k8s.io", Kind: "Lease"}:                     {},
+	{Group: "coordination.k8s.io", Kind: "LeaseCandidate"}:            {},
+	{Group: "discovery.k8s.io", Kind: "EndpointSlice"}:                {},
+	{Group: "events.k8s.io", Kind: "Event"}:                           {},
+	{Group: "extensions", Kind: "DaemonSet"}:                          {},
+	{Group: "extensions", Kind: "Deployment"}:                         {},
+	{Group: "extensions", Kind: "Ingress"}:                            {},
+	{Group: "extensions", Kind: "NetworkPolicy"}:                      {},
+	{Group: "extensions", Kind: "ReplicaSet"}:                         {},
+	{Group: "extensions", Kind: "Scale"}:                              {},
+	{Group: "networking.k8s.io", Kind: "Ingress"}:                     {},
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}:               {},
+	{Group: "policy", Kind: "Eviction"}:                               {},
+	{Group: "policy", Kind: "PodDisruptionBudget"}:                    {},
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:                {},
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:         {},
+	{Group: "storage.k8s.io", Kind: "CSIStorageCapacity"}:             {},
+}
+
+func TestRESTMapperScopes(t *testing.T) {
+	for gvk := range Scheme.AllKnownTypes() {
+		obj, err := Scheme.New(gvk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := meta.Accessor(obj); err != nil || meta.IsListType(obj) {
+			continue
+		}
+		_, clusterScoped := clusterScopedKinds[gvk.GroupKind()]
+		_, namespaced := namespacedKinds[gvk.GroupKind()]
+		if clusterScoped == namespaced {
+			t.Errorf("%s must be listed either as a cluster-scoped or as a namespaced kind", gvk.GroupKind())
+			continue
+		}
+		mapping, err := RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			t.Errorf("%s: %v", gvk, err)
+			continue
+		}
+		want := meta.RESTScopeNameNamespace
+		if clusterScoped {
+			want = meta.RESTScopeNameRoot
+		}
+		if got := mapping.Scope.Name(); got != want {
+			t.Errorf("got scope %s of %s, want %s", got, gvk, want)
+		}
+	}
+}